@@ -0,0 +1,82 @@
+// Package api содержит спецификацию OpenAPI 3 эмулятора, страницу Swagger UI с ее скриптами и
+// маршрутизатор со всеми маршрутами приложения.
+package api
+
+import (
+	"embed"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/gorilla/mux"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/apierror"
+)
+
+// Спецификация OpenAPI 3 всех маршрутов приложения.
+//
+//go:embed openapi.json
+var spec []byte
+
+// Страница Swagger UI, которая загружает спецификацию с OpenAPI.
+//
+//go:embed docs.html
+var docs []byte
+
+// Скрипты и стили Swagger UI из пакета swagger-ui-dist версии из swagger-ui/VERSION. Они копируются в
+// каталог командой `make swagger-ui`, поэтому страница документации не обращается к внешним CDN.
+//
+//go:embed swagger-ui
+var assets embed.FS
+
+// Это константа, определяющая маршрут.
+const (
+	OpenAPI = "/openapi.json"
+	Docs    = "/docs"
+	Asset   = "/docs/{file}"
+)
+
+// Эта функция регистрирует обработчики спецификации, страницы документации и ее файлов.
+func Register(router *mux.Router) *mux.Router {
+	router.HandleFunc(OpenAPI, GetSpec).Methods(http.MethodGet).Name("OpenAPI")
+	router.HandleFunc(Docs, GetDocs).Methods(http.MethodGet).Name("Docs")
+	router.HandleFunc(Asset, GetAsset).Methods(http.MethodGet).Name("Asset")
+	return router
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/openapi.json` методом `GET`.
+func GetSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(spec)
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/docs` методом `GET`.
+func GetDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(docs)
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/docs/{file}` методом `GET`.
+func GetAsset(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["file"]
+
+	data, err := assets.ReadFile(path.Join("swagger-ui", name))
+	if err != nil {
+		apierror.Write(w, r, http.StatusNotFound, apierror.Body{Code: CodeAssetNotFound, Message: AssetNotFound})
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "text/plain; charset=utf-8"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/onlycodergod/payment-api-emulator/internal/currency"
+	"github.com/onlycodergod/payment-api-emulator/internal/payment"
+	"github.com/onlycodergod/payment-api-emulator/internal/webhook"
+	"github.com/onlycodergod/payment-api-emulator/pkg/health"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/apierror"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/fault"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/loglevels"
+	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
+	"github.com/onlycodergod/payment-api-emulator/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+// document — это часть спецификации OpenAPI, которую проверяют тесты.
+type document struct {
+	OpenAPI string                                `json:"openapi"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+// Он разбирает встроенную спецификацию и падает, если она некорректна.
+func mustDocument(t *testing.T) document {
+	var output document
+	if err := json.Unmarshal(spec, &output); err != nil {
+		t.Fatalf("an error '%s' was not expected when parsing openapi.json", err)
+	}
+
+	return output
+}
+
+// Он собирает маршрутизатор со всеми обработчиками приложения через NewRouter, как main.
+func newRouter(t *testing.T) *mux.Router {
+	rates, err := payment.NewRateTable(payment.RateOptions{})
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when creating a rate table", err)
+	}
+
+	levels, err := loggin.NewLevels(loggin.LevelSettings{})
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when creating log levels", err)
+	}
+
+	return NewRouter(Handlers{
+		Faults:     fault.NewInjector(1),
+		Webhooks:   webhook.NewWebhookController(nil, nil),
+		Currencies: currency.NewCurrencyController(nil, nil),
+		Rates:      rates,
+		Metrics:    metrics.NewRegistry(),
+		Health:     health.NewChecker(0),
+		LogLevels:  loglevels.NewLevelsController(levels),
+		Payments:   payment.NewPaymentController(nil, nil, nil, nil),
+	})
+}
+
+// Он проверяет, что каждый зарегистрированный маршрут с каждым методом описан в спецификации, а в
+// спецификации нет операций без маршрута.
+func TestSpecCoversRoutes(t *testing.T) {
+	t.Parallel()
+
+	doc := mustDocument(t)
+	assert.True(t, strings.HasPrefix(doc.OpenAPI, "3."))
+
+	registered := make(map[string]map[string]bool)
+
+	err := newRouter(t).Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+
+		for _, method := range methods {
+			method = strings.ToLower(method)
+
+			if registered[path] == nil {
+				registered[path] = make(map[string]bool)
+			}
+			registered[path][method] = true
+
+			_, ok := doc.Paths[path][method]
+			assert.True(t, ok, "route %s %s is missing from openapi.json", strings.ToUpper(method), path)
+		}
+
+		return nil
+	})
+	assert.NoError(t, err)
+
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			if method == "parameters" {
+				continue
+			}
+
+			assert.True(t, registered[path][method], "operation %s %s has no registered route", strings.ToUpper(method), path)
+		}
+	}
+}
+
+// Он проверяет, что спецификация и страница документации отдаются с нужным типом содержимого.
+func TestRegister(t *testing.T) {
+	t.Parallel()
+
+	router := Register(mux.NewRouter())
+
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		contains    string
+	}{
+		{name: "Spec", target: OpenAPI, contentType: "application/json", contains: `"openapi"`},
+		{name: "Docs", target: Docs, contentType: "text/html; charset=utf-8", contains: OpenAPI},
+		{name: "Version", target: "/docs/VERSION", contentType: "text/plain; charset=utf-8", contains: "4."},
+		{name: "Bundle", target: "/docs/swagger-ui-bundle.js", contentType: mime.TypeByExtension(".js"), contains: "SwaggerUIBundle"},
+		{name: "Styles", target: "/docs/swagger-ui.css", contentType: mime.TypeByExtension(".css"), contains: ".swagger-ui"},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+		assert.Equal(t, http.StatusOK, w.Code, tt.name)
+		assert.Equal(t, tt.contentType, w.Header().Get("Content-Type"), tt.name)
+		assert.Contains(t, w.Body.String(), tt.contains, tt.name)
+	}
+
+	assert.NotContains(t, string(docs), "https://")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/unknown.js", nil))
+
+	var got apierror.Body
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, CodeAssetNotFound, got.Code)
+}
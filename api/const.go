@@ -0,0 +1,8 @@
+package api
+
+const AssetNotFound = "asset not found"
+
+// Машиночитаемые коды ошибок в поле «code» ответа.
+const (
+	CodeAssetNotFound = "asset_not_found"
+)
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/fault"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/route"
+)
+
+// Registrar — это обработчики, которые регистрируют свои маршруты в маршрутизаторе.
+type Registrar interface {
+	Register(router *mux.Router) *mux.Router
+}
+
+// Handlers — это обработчики всех маршрутов приложения.
+// @property {*fault.Injector} Faults - Внедрение сбоев. Подключается всегда: без правил ответы не
+// меняются, правила задаются через /admin/faults.
+// @property {Registrar} Webhooks - Эндпоинты webhook и журнал доставок.
+// @property {Registrar} Currencies - Реестр валют.
+// @property {Registrar} Rates - Таблица курсов обмена, /admin/fx/rates.
+// @property {Registrar} Metrics - Метрики Prometheus, /metrics.
+// @property {Registrar} Health - Пробы живости и готовности, /healthz и /readyz.
+// @property {Registrar} LogLevels - Уровни журнала, /admin/log/levels.
+// @property {Registrar} Payments - Платежи.
+type Handlers struct {
+	Faults     *fault.Injector
+	Webhooks   Registrar
+	Currencies Registrar
+	Rates      Registrar
+	Metrics    Registrar
+	Health     Registrar
+	LogLevels  Registrar
+	Payments   Registrar
+}
+
+// > Эта функция создает маршрутизатор со всеми маршрутами приложения. Ее вызывают main и тест
+// спецификации, поэтому каждый маршрут, добавленный здесь, должен быть описан в openapi.json.
+func NewRouter(handlers Handlers) *mux.Router {
+	router := mux.NewRouter()
+
+	// Маршрут, найденный маршрутизатором, сохраняется в записи запроса для метрик и журнала доступа.
+	router.Use(route.Match)
+
+	// Внедрение сбоев: задержки, ошибки и разрывы соединения по имени маршрута, настраиваются через
+	// /admin/faults.
+	router.Use(handlers.Faults.Middleware)
+	handlers.Faults.Register(router)
+
+	handlers.Webhooks.Register(router)
+	handlers.Currencies.Register(router)
+	handlers.Rates.Register(router)
+
+	// Документация: спецификация OpenAPI 3 и Swagger UI.
+	Register(router)
+	handlers.Metrics.Register(router)
+	handlers.Health.Register(router)
+	handlers.LogLevels.Register(router)
+
+	return handlers.Payments.Register(router)
+}
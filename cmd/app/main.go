@@ -1,18 +1,27 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
-	"github.com/gorilla/mux"
+	"github.com/onlycodergod/payment-api-emulator/api"
 	"github.com/onlycodergod/payment-api-emulator/config"
+	"github.com/onlycodergod/payment-api-emulator/internal/currency"
+	"github.com/onlycodergod/payment-api-emulator/internal/outbox"
 	"github.com/onlycodergod/payment-api-emulator/internal/payment"
+	"github.com/onlycodergod/payment-api-emulator/internal/webhook"
 	"github.com/onlycodergod/payment-api-emulator/pkg/db/postgres"
+	"github.com/onlycodergod/payment-api-emulator/pkg/health"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/fault"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/loglevels"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/requestid"
 	"github.com/onlycodergod/payment-api-emulator/pkg/http/server"
 	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
+	"github.com/onlycodergod/payment-api-emulator/pkg/metrics"
 )
 
 // Он создает новый объект конфигурации, и в случае сбоя он регистрирует ошибку и выходит из программы.
@@ -22,50 +31,293 @@ func main() {
 		log.Fatalf("config initialization error: %s", err.Error())
 	}
 
-	// Logger
-	logger := loggin.NewLogger(cfg.Logger.Debug)
+	// Logger: уровни журнала по умолчанию и по имени регистратора меняются через /admin/log/levels.
+	level := cfg.Logger.Level
+	if level == "" && cfg.Logger.Debug {
+		level = "debug"
+	}
 
-	// Database
-	dbOptions := postgres.DBOptions{
-		User:     cfg.Postgres.User,
-		Password: cfg.Postgres.Password,
-		Host:     cfg.Postgres.Host,
-		Port:     cfg.Postgres.Port,
-		DB:       cfg.Postgres.DB,
-		SSLmode:  cfg.Postgres.SSLMode,
+	levels, err := loggin.NewLevels(loggin.LevelSettings{
+		Level:    level,
+		Packages: cfg.Logger.Packages,
+	})
+	if err != nil {
+		log.Fatalf("logger initialization error: %s", err.Error())
 	}
 
-	pg, err := postgres.NewPostgres(dbOptions).Connect()
+	logger, err := loggin.New(
+		loggin.Options{
+			Encoding: cfg.Logger.Encoding,
+			Outputs:  cfg.Logger.Outputs,
+			File:     cfg.Logger.File,
+			MaxSize:  cfg.Logger.MaxSize << 20,
+			MaxAge:   time.Duration(cfg.Logger.MaxAge) * 24 * time.Hour,
+		},
+		levels,
+	)
 	if err != nil {
-		logger.Fatalf("postgres connection failed, %s", err.Error())
+		log.Fatalf("logger initialization error: %s", err.Error())
+	}
+
+	// Метрики Prometheus, отдаются по /metrics.
+	registry := metrics.NewRegistry()
+
+	// Пробы живости и готовности, отдаются по /healthz и /readyz.
+	checker := health.NewChecker(health.DefaultTimeout)
+
+	// Контекст фоновых процессов, отменяется при завершении работы.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Хранилища платежей, webhook и реестра валют.
+	var rep payment.PaymentRepository
+	var webhookRep webhook.WebhookRepository
+	var currencyRep currency.CurrencyRepository
+
+	switch cfg.Storage.Driver {
+	case config.StorageMemory:
+		rep = payment.NewMemoryPaymentRepository()
+		webhookRep = webhook.NewMemoryWebhookRepository()
+		currencyRep = currency.NewMemoryCurrencyRepository()
+	case config.StoragePostgres:
+		dbOptions := postgres.DBOptions{
+			User:     cfg.Postgres.User,
+			Password: cfg.Postgres.Password,
+			Host:     cfg.Postgres.Host,
+			Port:     cfg.Postgres.Port,
+			DB:       cfg.Postgres.DB,
+			SSLmode:  cfg.Postgres.SSLMode,
+		}
+
+		pg, err := postgres.NewPostgres(dbOptions).Connect()
+		if err != nil {
+			logger.Fatalf("postgres connection failed, %s", err.Error())
+		}
+		defer pg.Close()
+
+		// Метрики пула соединений с базой данных.
+		metrics.NewDBStats(registry, pg)
+
+		// Готовность: база данных отвечает, и миграции применены до последней версии.
+		migrationVersion, err := postgres.LatestMigration(postgres.MigrationsPath)
+		if err != nil {
+			logger.Fatalf("migrations lookup failed, %s", err.Error())
+		}
+
+		checker.Add("database", pg.PingContext)
+		checker.Add("migrations", postgres.CheckMigrations(pg, migrationVersion))
+
+		// Миграция базы данных.
+		postgres.InitMigrate(
+			logger.Named("postgres"),
+			dbOptions,
+		)
+
+		rep = payment.NewPaymentRepository(pg)
+		webhookRep = webhook.NewWebhookRepository(pg)
+		currencyRep = currency.NewCurrencyRepository(pg)
+	default:
+		logger.Fatalf("unknown storage driver %q", cfg.Storage.Driver)
 	}
-	defer pg.Close()
 
-	// Миграция базы данных.
-	postgres.InitMigrate(
-		logger,
-		dbOptions,
+	// Реестр валют: валюты ISO 4217 добавляются в хранилище при запуске, прием платежей в валюте
+	// включается и отключается через /currencies/{code}.
+	currencyUsc := currency.NewCurrencyUseCase(currencyRep)
+	if err := currencyUsc.Load(ctx); err != nil {
+		logger.Fatalf("currency registry initialization error: %s", err.Error())
+	}
+
+	currencyCon := currency.NewCurrencyController(
+		logger.Named("currency"),
+		currencyUsc,
+	)
+
+	// Создание нового варианта использования и контроллера.
+	usc := payment.NewPaymentUseCase(
+		rep,
+		payment.Options{
+			IdempotencyTTL: time.Duration(cfg.Idempotency.TTL) * time.Second,
+		},
 	)
+	// Таблица курсов: пересчет платежей в валюту расчетов, курсы меняются через /admin/fx/rates.
+	rates, err := payment.NewRateTable(
+		payment.RateOptions{
+			Seed:  cfg.FX.Seed,
+			Drift: cfg.FX.Drift,
+		},
+	)
+	if err != nil {
+		logger.Fatalf("fx rates initialization error: %s", err.Error())
+	}
+
+	if cfg.FX.Path != "" {
+		values, err := payment.LoadRates(cfg.FX.Path)
+		if err != nil {
+			logger.Fatalf("fx rates initialization error: %s", err.Error())
+		}
+
+		if err := rates.SetRates(values, payment.RateSourceFile); err != nil {
+			logger.Fatalf("fx rates initialization error: %s", err.Error())
+		}
+	}
 
-	// Создание нового репозитория платежей, варианта использования и контроллера.
-	rep := payment.NewPaymentRepository(pg)
-	usc := payment.NewPaymentUseCase(rep)
 	con := payment.NewPaymentController(
-		logger,
+		logger.Named("payment"),
 		usc,
+		currencyUsc,
+		rates,
 	)
 
-	// Создание нового маршрутизатора и http-сервера.
-	router := mux.NewRouter()
+	// Webhook: эндпоинты мерчантов получают события о каждом изменении статуса платежа.
+	webhookUsc := webhook.NewWebhookUseCase(
+		webhookRep,
+	)
+	webhookCon := webhook.NewWebhookController(
+		logger.Named("webhook"),
+		webhookUsc,
+	)
+	// Метрики платежей: созданные платежи и смены статусов.
+	usc.Subscribe(payment.NewMetrics(registry))
+
+	dispatcher, err := webhook.NewDispatcher(
+		webhookRep,
+		logger.Named("webhook"),
+		webhook.DispatcherOptions{
+			Interval:    time.Duration(cfg.Webhooks.Interval) * time.Second,
+			Timeout:     time.Duration(cfg.Webhooks.Timeout) * time.Second,
+			BaseBackoff: time.Duration(cfg.Webhooks.BaseBackoff) * time.Second,
+			MaxBackoff:  time.Duration(cfg.Webhooks.MaxBackoff) * time.Second,
+			MaxAttempts: cfg.Webhooks.MaxAttempts,
+			BatchSize:   cfg.Webhooks.BatchSize,
+		},
+	)
+	if err != nil {
+		logger.Fatalf("webhook dispatcher initialization error: %s", err.Error())
+	}
+
+	go dispatcher.Run(ctx)
+
+	// Outbox: события изменения статусов переносятся в доставки webhook и в sink для внешних
+	// потребителей.
+	sink, err := outbox.NewSink(
+		cfg.Outbox.Sink,
+		cfg.Outbox.File,
+		cfg.Outbox.URL,
+		time.Duration(cfg.Outbox.Timeout)*time.Second,
+	)
+	if err != nil {
+		logger.Fatalf("outbox sink initialization error: %s", err.Error())
+	}
+
+	relay, err := outbox.NewRelay(
+		rep,
+		outbox.NewMultiSink(webhookUsc, sink),
+		logger.Named("outbox"),
+		outbox.RelayOptions{
+			Interval:  time.Duration(cfg.Outbox.Interval) * time.Second,
+			BatchSize: cfg.Outbox.BatchSize,
+		},
+	)
+	if err != nil {
+		logger.Fatalf("outbox relay initialization error: %s", err.Error())
+	}
+
+	go relay.Run(ctx)
+
+	// Сценарий эмулятора: новые платежи автоматически переводятся в итоговый статус по правилам.
+	if cfg.Scenarios.Enabled {
+		scenarios, err := payment.LoadScenarios(cfg.Scenarios.Path)
+		if err != nil {
+			logger.Fatalf("scenarios initialization error: %s", err.Error())
+		}
+
+		scenarioWorker, err := payment.NewScenarioWorker(
+			rep,
+			usc,
+			logger.Named("payment"),
+			scenarios,
+			payment.ScenarioOptions{
+				Interval:  time.Duration(cfg.Scenarios.Interval) * time.Second,
+				BatchSize: cfg.Scenarios.BatchSize,
+			},
+		)
+		if err != nil {
+			logger.Fatalf("scenarios initialization error: %s", err.Error())
+		}
+
+		go scenarioWorker.Run(ctx)
+	}
+
+	// Авторизации: платежи в статусе authorized отменяются, если их не списали за срок удержания.
+	expiryWorker, err := payment.NewExpiryWorker(
+		rep,
+		usc,
+		logger.Named("payment"),
+		payment.AuthorizationOptions{
+			Hold:      time.Duration(cfg.Authorizations.Hold) * time.Second,
+			Interval:  time.Duration(cfg.Authorizations.Interval) * time.Second,
+			BatchSize: cfg.Authorizations.BatchSize,
+		},
+	)
+	if err != nil {
+		logger.Fatalf("authorizations initialization error: %s", err.Error())
+	}
+
+	go expiryWorker.Run(ctx)
+
+	// Создание нового маршрутизатора со всеми маршрутами приложения и http-сервера.
+	router := api.NewRouter(api.Handlers{
+		Faults:     fault.NewInjector(cfg.Faults.Seed),
+		Webhooks:   webhookCon,
+		Currencies: currencyCon,
+		Rates:      rates,
+		Metrics:    registry,
+		Health:     checker,
+		LogLevels:  loglevels.NewLevelsController(levels),
+		Payments:   con,
+	})
+
+	// Идентификатор запроса X-Request-ID попадает в каждую запись журнала о запросе и возвращается в
+	// ответе. В контексте запроса хранится корневой регистратор: имя записи задает слой, который ее
+	// пишет. Метрики HTTP-сервера: количество и длительность запросов по имени маршрута. Метрики и
+	// журнал доступа делят одну запись запроса с маршрутом, который нашел маршрутизатор.
+	httpMetrics := metrics.NewHTTPMetrics(registry)
+	middlewares := []server.Middleware{
+		requestid.Middleware(logger),
+		httpMetrics.Middleware,
+	}
+
+	// Журнал доступа: метод, шаблон пути, код и размер ответа, время обработки, адрес клиента и
+	// идентификатор запроса. Строки пишутся в те же выводы, что и журнал приложения.
+	if cfg.Logger.Access {
+		accessLog, err := server.NewAccessLog(
+			logger.Writer(),
+			server.AccessLogOptions{
+				Format:     cfg.Logger.AccessFormat,
+				SampleRate: cfg.Logger.AccessSampleRate,
+				Exclude:    cfg.Logger.AccessExclude,
+			},
+		)
+		if err != nil {
+			logger.Fatalf("access log initialization error: %s", err.Error())
+		}
+
+		middlewares = append(middlewares, accessLog.Middleware)
+	}
 
 	httpServer := server.NewHttpServer(
-		con.Register(router),
+		router,
 		cfg.HTTP.Port,
 		time.Duration(cfg.HTTP.ReadTimeout),
 		time.Duration(cfg.HTTP.WriteTimeout),
 		time.Duration(cfg.HTTP.ShutdownTimeout),
+		middlewares...,
 	)
 
+	// При завершении работы проба готовности отвечает 503, пока сервер еще принимает запросы.
+	httpServer.OnShutdown(checker.Drain)
+	httpServer.SetDrainDelay(time.Duration(cfg.HTTP.DrainDelay))
+
 	logger.Infof("http server created and started at http://localhost:%s", cfg.HTTP.Port)
 
 	// Обработчик сигнала.
@@ -79,6 +331,9 @@ func main() {
 		logger.Errorf("app - run - httpServer.Notify: %s", err.Error())
 	}
 
+	// Остановка фоновых процессов.
+	cancel()
+
 	// Грамотное завершение работы сервера. (Shutdown)
 	err = httpServer.Shutdown()
 	if err != nil {
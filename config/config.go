@@ -4,10 +4,34 @@ import (
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
-// «Logger» — это структура с одним полем «Debug», которое является логическим значением.
-// @property {bool} Debug - Если true, регистратор будет печатать отладочные сообщения.
+// «Logger» — это настройки регистратора и журнала доступа.
+// @property {bool} Debug - Если true и уровень не задан, регистратор будет печатать отладочные сообщения.
+// @property {string} Level - Уровень журнала по умолчанию: «debug», «info», «warn» или «error».
+// @property {map[string]string} Packages - Уровни журнала по имени регистратора, например «payment».
+// @property {string} Encoding - Кодировщик записей: «console» или «json».
+// @property {[]string} Outputs - Выводы журнала: «stdout», «stderr» и «file».
+// @property {string} File - Путь к файлу журнала для вывода «file».
+// @property {int64} MaxSize - Размер файла журнала в мегабайтах, после которого он переименовывается в
+// архивный, 0 - без ограничения.
+// @property {int64} MaxAge - Сколько дней хранятся архивные файлы журнала, 0 - без ограничения.
+// @property {bool} Access - Если true, каждый запрос записывается в журнал доступа.
+// @property {string} AccessFormat - Формат журнала доступа: «json», «logfmt» или «combined».
+// @property {float64} AccessSampleRate - Доля записываемых запросов от 0 до 1. Ответы с кодом 5xx
+// записываются всегда.
+// @property {[]string} AccessExclude - Пути запросов, которые не записываются в журнал доступа.
 type Logger struct {
-	Debug bool `yaml:"debug"`
+	Debug            bool              `yaml:"debug"`
+	Level            string            `yaml:"level" env:"LOGGER_LEVEL"`
+	Packages         map[string]string `yaml:"packages" env:"LOGGER_PACKAGES"`
+	Encoding         string            `yaml:"encoding" env:"LOGGER_ENCODING" env-default:"console"`
+	Outputs          []string          `yaml:"outputs" env:"LOGGER_OUTPUTS" env-default:"stdout"`
+	File             string            `yaml:"file" env:"LOGGER_FILE" env-default:"logs/app.log"`
+	MaxSize          int64             `yaml:"maxSize" env:"LOGGER_MAX_SIZE" env-default:"100"`
+	MaxAge           int64             `yaml:"maxAge" env:"LOGGER_MAX_AGE" env-default:"7"`
+	Access           bool              `yaml:"access" env:"LOGGER_ACCESS" env-default:"true"`
+	AccessFormat     string            `yaml:"accessFormat" env:"LOGGER_ACCESS_FORMAT" env-default:"json"`
+	AccessSampleRate float64           `yaml:"accessSampleRate" env:"LOGGER_ACCESS_SAMPLE_RATE" env-default:"1"`
+	AccessExclude    []string          `yaml:"accessExclude" env:"LOGGER_ACCESS_EXCLUDE" env-default:"/healthz,/readyz,/metrics"`
 }
 
 // HTTP — это структура, содержащая строку, int64, int64 и int64.
@@ -16,11 +40,106 @@ type Logger struct {
 // ответа.
 // @property {int64} ReadTimeout - Максимальная продолжительность чтения всего запроса, включая тело.
 // @property {int64} ShutdownTimeout - Время ожидания выключения сервера перед его уничтожением.
+// @property {int64} DrainDelay - Сколько секунд сервер продолжает принимать запросы после начала
+// завершения работы, отвечая 503 на пробу готовности.
 type HTTP struct {
 	Port            string `yaml:"port" env:"HTTP_PORT" env-required:"true"`
 	WriteTimeout    int64  `yaml:"writeTimeout" env:"HTTP_WRITE_TIMEOUT" env-required:"true"`
 	ReadTimeout     int64  `yaml:"readTimeout" env:"HTTP_READ_TIMEOUT" env-required:"true"`
 	ShutdownTimeout int64  `yaml:"shutdownTimeout" env:"HTTP_SHUT_DOWN_TIMEOUT" env-required:"true"`
+	DrainDelay      int64  `yaml:"drainDelay" env:"HTTP_DRAIN_DELAY" env-default:"0"`
+}
+
+// Драйверы хранилища платежей.
+const (
+	StorageMemory   = "memory"
+	StoragePostgres = "postgres"
+)
+
+// Storage — это настройки хранилища платежей.
+// @property {string} Driver - Драйвер хранилища: «memory» или «postgres».
+type Storage struct {
+	Driver string `yaml:"driver" env:"STORAGE_DRIVER" env-default:"postgres"`
+}
+
+// Idempotency — это настройки заголовка Idempotency-Key.
+// @property {int64} TTL - Время жизни сохраненного ответа в секундах.
+type Idempotency struct {
+	TTL int64 `yaml:"ttl" env:"IDEMPOTENCY_TTL" env-default:"86400"`
+}
+
+// Webhooks — это настройки фоновой отправки событий на webhook-эндпоинты.
+// @property {int64} Interval - Период опроса очереди доставок в секундах.
+// @property {int64} Timeout - Тайм-аут запроса к эндпоинту в секундах.
+// @property {int64} BaseBackoff - Задержка перед второй попыткой в секундах.
+// @property {int64} MaxBackoff - Максимальная задержка между попытками в секундах.
+// @property {int} MaxAttempts - Количество попыток до признания доставки неудачной.
+// @property {int} BatchSize - Сколько доставок отправляется за один опрос.
+type Webhooks struct {
+	Interval    int64 `yaml:"interval" env:"WEBHOOKS_INTERVAL" env-default:"1"`
+	Timeout     int64 `yaml:"timeout" env:"WEBHOOKS_TIMEOUT" env-default:"5"`
+	BaseBackoff int64 `yaml:"baseBackoff" env:"WEBHOOKS_BASE_BACKOFF" env-default:"1"`
+	MaxBackoff  int64 `yaml:"maxBackoff" env:"WEBHOOKS_MAX_BACKOFF" env-default:"3600"`
+	MaxAttempts int   `yaml:"maxAttempts" env:"WEBHOOKS_MAX_ATTEMPTS" env-default:"8"`
+	BatchSize   int   `yaml:"batchSize" env:"WEBHOOKS_BATCH_SIZE" env-default:"50"`
+}
+
+// Outbox — это настройки relay, который переносит события платежей из outbox в доставки webhook и в sink.
+// @property {string} Sink - Вид sink: «none» (только webhook), «stdout», «file» или «http».
+// @property {string} File - Путь к файлу для sink «file».
+// @property {string} URL - Адрес получателя для sink «http».
+// @property {int64} Timeout - Тайм-аут запроса sink «http» в секундах.
+// @property {int64} Interval - Период опроса outbox в секундах.
+// @property {int} BatchSize - Сколько событий отправляется в sink за раз.
+type Outbox struct {
+	Sink      string `yaml:"sink" env:"OUTBOX_SINK" env-default:"none"`
+	File      string `yaml:"file" env:"OUTBOX_FILE" env-default:"logs/outbox.jsonl"`
+	URL       string `yaml:"url" env:"OUTBOX_URL"`
+	Timeout   int64  `yaml:"timeout" env:"OUTBOX_TIMEOUT" env-default:"5"`
+	Interval  int64  `yaml:"interval" env:"OUTBOX_INTERVAL" env-default:"1"`
+	BatchSize int    `yaml:"batchSize" env:"OUTBOX_BATCH_SIZE" env-default:"100"`
+}
+
+// Scenarios — это настройки сценария эмулятора, который автоматически переводит новые платежи в
+// итоговый статус.
+// @property {bool} Enabled - Если true, фоновый процесс применяет правила сценария.
+// @property {string} Path - Путь к YAML-файлу с правилами.
+// @property {int64} Interval - Период опроса новых платежей в секундах.
+// @property {int} BatchSize - Сколько новых платежей читается за один запрос.
+type Scenarios struct {
+	Enabled   bool   `yaml:"enabled" env:"SCENARIOS_ENABLED" env-default:"false"`
+	Path      string `yaml:"path" env:"SCENARIOS_PATH" env-default:"config/scenarios.yml"`
+	Interval  int64  `yaml:"interval" env:"SCENARIOS_INTERVAL" env-default:"1"`
+	BatchSize int    `yaml:"batchSize" env:"SCENARIOS_BATCH_SIZE" env-default:"100"`
+}
+
+// Faults — это настройки внедрения задержек и ошибок в ответы API. Правила задаются через
+// /admin/faults, без правил ответы не меняются.
+// @property {int64} Seed - Начальное значение генератора случайных чисел, 0 - случайный seed.
+type Faults struct {
+	Seed int64 `yaml:"seed" env:"FAULTS_SEED" env-default:"0"`
+}
+
+// FX — это настройки таблицы курсов обмена для пересчета платежей в валюту расчетов.
+// @property {string} Path - Путь к YAML-файлу с курсами. Пустой путь - курсы задаются только через
+// /admin/fx/rates.
+// @property {int64} Seed - Начальное значение генератора отклонения курса, 0 - случайный seed.
+// @property {float64} Drift - Наибольшее относительное отклонение курса при каждом расчете, 0 - без
+// отклонения.
+type FX struct {
+	Path  string  `yaml:"path" env:"FX_PATH" env-default:"config/rates.yml"`
+	Seed  int64   `yaml:"seed" env:"FX_SEED" env-default:"0"`
+	Drift float64 `yaml:"drift" env:"FX_DRIFT" env-default:"0"`
+}
+
+// Authorizations — это настройки авторизаций, которые списываются или отменяются отдельным запросом.
+// @property {int64} Hold - Срок удержания авторизации в секундах, после которого она отменяется.
+// @property {int64} Interval - Период поиска истекших авторизаций в секундах.
+// @property {int} BatchSize - Сколько авторизаций читается за один запрос.
+type Authorizations struct {
+	Hold      int64 `yaml:"hold" env:"AUTHORIZATIONS_HOLD" env-default:"604800"`
+	Interval  int64 `yaml:"interval" env:"AUTHORIZATIONS_INTERVAL" env-default:"60"`
+	BatchSize int   `yaml:"batchSize" env:"AUTHORIZATIONS_BATCH_SIZE" env-default:"100"`
 }
 
 // Это структура с полями, которые являются строками, и каждое поле имеет тег, который сообщает пакету
@@ -53,9 +172,25 @@ type Postgres struct {
 // @property {Logger}  - Регистратор: это конфигурация регистратора.
 // @property {HTTP}  - Регистратор: это конфигурация регистратора.
 // @property {Postgres}  - Регистратор: это конфигурация регистратора.
+// @property {Storage}  - Это настройки хранилища платежей.
+// @property {Idempotency}  - Это настройки заголовка Idempotency-Key.
+// @property {Webhooks}  - Это настройки отправки событий на webhook-эндпоинты.
+// @property {Outbox}  - Это настройки relay событий outbox.
+// @property {Scenarios}  - Это настройки сценария эмулятора.
+// @property {Faults}  - Это настройки внедрения сбоев.
+// @property {FX}  - Это настройки таблицы курсов обмена.
+// @property {Authorizations}  - Это настройки срока удержания авторизаций.
 type Config struct {
-	Logger `yaml:"logger"`
-	HTTP   `yaml:"http"`
+	Logger         `yaml:"logger"`
+	HTTP           `yaml:"http"`
+	Storage        `yaml:"storage"`
+	Idempotency    `yaml:"idempotency"`
+	Webhooks       `yaml:"webhooks"`
+	Outbox         `yaml:"outbox"`
+	Scenarios      `yaml:"scenarios"`
+	Faults         `yaml:"faults"`
+	FX             `yaml:"fx"`
+	Authorizations `yaml:"authorizations"`
 	Postgres
 }
 
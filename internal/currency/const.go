@@ -0,0 +1,17 @@
+package currency
+
+const InternalServerError = "internal server error"
+
+const (
+	InvalidBodyData    = "invalid body data"
+	InvalidBodyEnabled = "invalid body enabled"
+	CurrencyNotFound   = "currency not found"
+)
+
+// Машиночитаемые коды ошибок в поле «code» ответа.
+const (
+	CodeInternalError    = "internal_error"
+	CodeInvalidBody      = "invalid_body"
+	CodeInvalidField     = "invalid_field"
+	CodeCurrencyNotFound = "currency_not_found"
+)
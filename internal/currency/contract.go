@@ -0,0 +1,25 @@
+package currency
+
+import "context"
+
+// CurrencyRepository — это интерфейс хранилища реестра валют.
+// @property SyncCurrencies - Добавляет отсутствующие валюты, не меняя уже сохраненные.
+// @property GetCurrencies - Возвращает все валюты по возрастанию кода.
+// @property SetEnabled - Включает или отключает валюту и возвращает количество измененных строк.
+type CurrencyRepository interface {
+	SyncCurrencies(ctx context.Context, input []Currency) error
+	GetCurrencies(ctx context.Context) ([]Currency, error)
+	SetEnabled(ctx context.Context, code string, enabled bool) (int64, error)
+}
+
+// CurrencyUseCase — это интерфейс реестра валют.
+// @property Lookup - Возвращает включенную валюту по коду.
+// @property GetCurrencies - Возвращает все валюты реестра.
+// @property GetCurrency - Возвращает валюту по коду, включенную или нет.
+// @property SetEnabled - Включает или отключает валюту.
+type CurrencyUseCase interface {
+	Lookup(code string) (Currency, error)
+	GetCurrencies() []Currency
+	GetCurrency(code string) (Currency, error)
+	SetEnabled(ctx context.Context, code string, enabled bool) (Currency, error)
+}
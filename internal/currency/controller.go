@@ -0,0 +1,123 @@
+package currency
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/apierror"
+	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
+)
+
+// > Тип контроллера — это структура с интерфейсом CurrencyUseCase и интерфейсом регистратора.
+// @property {CurrencyUseCase} UseCase - Это интерфейс реестра валют.
+// @property logger - Это регистратор, который будет использоваться для регистрации ошибок.
+type controller struct {
+	UseCase CurrencyUseCase
+	logger  loggin.ILogger
+}
+
+// > Эта функция создает новый экземпляр структуры контроллера и возвращает указатель на него
+func NewCurrencyController(l loggin.ILogger, u CurrencyUseCase) *controller {
+	return &controller{
+		logger:  l,
+		UseCase: u,
+	}
+}
+
+// Это константа, определяющая маршрут.
+const (
+	GetCurrencies        = "/currencies"
+	GetCurrencyByCode    = "/currencies/{code}"
+	UpdateCurrencyByCode = "/currencies/{code}"
+)
+
+// Эта функция регистрирует обработчики маршрутов реестра валют.
+func (c *controller) Register(router *mux.Router) *mux.Router {
+	router.HandleFunc(GetCurrencies, c.GetCurrencies).Methods(http.MethodGet).Name("GetCurrencies")
+	router.HandleFunc(GetCurrencyByCode, c.GetCurrency).Methods(http.MethodGet).Name("GetCurrencyByCode")
+	router.HandleFunc(UpdateCurrencyByCode, c.UpdateCurrency).Methods(http.MethodPut).Name("UpdateCurrencyByCode")
+	return router
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/currencies` методом `GET`.
+func (c *controller) GetCurrencies(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(
+		CurrenciesData{
+			Data: c.UseCase.GetCurrencies(),
+		},
+	)
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/currencies/{code}` методом `GET`.
+func (c *controller) GetCurrency(w http.ResponseWriter, r *http.Request) {
+	data, err := c.UseCase.GetCurrency(mux.Vars(r)["code"])
+	if err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/currencies/{code}` методом `PUT`. Включает или отключает прием платежей в валюте.
+func (c *controller) UpdateCurrency(w http.ResponseWriter, r *http.Request) {
+	var input CurrencyState
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.Body{
+			Code:    CodeInvalidBody,
+			Message: InvalidBodyData,
+		})
+		return
+	}
+
+	if input.Enabled == nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.Body{
+			Code:    CodeInvalidField,
+			Message: InvalidBodyEnabled,
+			Details: map[string]interface{}{
+				"field": "enabled",
+			},
+		})
+		return
+	}
+
+	data, err := c.UseCase.SetEnabled(
+		r.Context(),
+		mux.Vars(r)["code"],
+		*input.Enabled,
+	)
+	if err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+// Отвечает клиенту на ошибку реестра валют.
+func (c *controller) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrCurrencyNotFound):
+		apierror.Write(w, r, http.StatusNotFound, apierror.Body{
+			Code:    CodeCurrencyNotFound,
+			Message: CurrencyNotFound,
+		})
+	default:
+		c.logger.WithContext(r.Context()).Error(err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Body{
+			Code:    CodeInternalError,
+			Message: InternalServerError,
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package currency
+
+// Currency — это валюта реестра по ISO 4217.
+// @property {string} Code - Буквенный код валюты в нижнем регистре, например «usd».
+// @property {string} Numeric - Цифровой код валюты, например «840».
+// @property {int} MinorUnits - Количество знаков после точки (экспонента), например 2 для центов.
+// @property {bool} Enabled - Принимаются ли новые платежи в этой валюте.
+type Currency struct {
+	Code       string `json:"code" db:"code"`
+	Numeric    string `json:"numeric_code" db:"numeric_code"`
+	MinorUnits int    `json:"minor_units" db:"minor_units"`
+	Enabled    bool   `json:"enabled" db:"enabled"`
+}
+
+// CurrenciesData — это структура, содержащая фрагмент структур валют.
+// @property {[]Currency} Data - Это массив валют.
+type CurrenciesData struct {
+	Data []Currency `json:"data"`
+}
+
+// CurrencyState — это структура с данными для включения или отключения валюты.
+// @property {*bool} Enabled - Принимаются ли новые платежи в валюте. Обязательное поле.
+type CurrencyState struct {
+	Enabled *bool `json:"enabled"`
+}
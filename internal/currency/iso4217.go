@@ -0,0 +1,70 @@
+package currency
+
+import "strings"
+
+// ISO4217 — это валюты, которыми реестр дополняет хранилище при запуске. Включены только валюты, которые
+// эмулятор принимал до появления реестра, остальные включаются через PUT /currencies/{code}.
+var ISO4217 = []Currency{
+	{Code: "aed", Numeric: "784", MinorUnits: 2},
+	{Code: "amd", Numeric: "051", MinorUnits: 2},
+	{Code: "ars", Numeric: "032", MinorUnits: 2},
+	{Code: "aud", Numeric: "036", MinorUnits: 2},
+	{Code: "azn", Numeric: "944", MinorUnits: 2},
+	{Code: "bhd", Numeric: "048", MinorUnits: 3},
+	{Code: "brl", Numeric: "986", MinorUnits: 2},
+	{Code: "byn", Numeric: "933", MinorUnits: 2},
+	{Code: "cad", Numeric: "124", MinorUnits: 2},
+	{Code: "chf", Numeric: "756", MinorUnits: 2},
+	{Code: "clp", Numeric: "152", MinorUnits: 0},
+	{Code: "cny", Numeric: "156", MinorUnits: 2},
+	{Code: "czk", Numeric: "203", MinorUnits: 2},
+	{Code: "dkk", Numeric: "208", MinorUnits: 2},
+	{Code: "eur", Numeric: "978", MinorUnits: 2, Enabled: true},
+	{Code: "gbp", Numeric: "826", MinorUnits: 2},
+	{Code: "gel", Numeric: "981", MinorUnits: 2},
+	{Code: "hkd", Numeric: "344", MinorUnits: 2},
+	{Code: "huf", Numeric: "348", MinorUnits: 2},
+	{Code: "idr", Numeric: "360", MinorUnits: 2},
+	{Code: "ils", Numeric: "376", MinorUnits: 2},
+	{Code: "inr", Numeric: "356", MinorUnits: 2},
+	{Code: "iqd", Numeric: "368", MinorUnits: 3},
+	{Code: "isk", Numeric: "352", MinorUnits: 0},
+	{Code: "jod", Numeric: "400", MinorUnits: 3},
+	{Code: "jpy", Numeric: "392", MinorUnits: 0},
+	{Code: "kgs", Numeric: "417", MinorUnits: 2},
+	{Code: "krw", Numeric: "410", MinorUnits: 0},
+	{Code: "kwd", Numeric: "414", MinorUnits: 3},
+	{Code: "kzt", Numeric: "398", MinorUnits: 2},
+	{Code: "lyd", Numeric: "434", MinorUnits: 3},
+	{Code: "mxn", Numeric: "484", MinorUnits: 2},
+	{Code: "nok", Numeric: "578", MinorUnits: 2},
+	{Code: "nzd", Numeric: "554", MinorUnits: 2},
+	{Code: "omr", Numeric: "512", MinorUnits: 3},
+	{Code: "pln", Numeric: "985", MinorUnits: 2},
+	{Code: "rub", Numeric: "643", MinorUnits: 2, Enabled: true},
+	{Code: "sar", Numeric: "682", MinorUnits: 2},
+	{Code: "sek", Numeric: "752", MinorUnits: 2},
+	{Code: "sgd", Numeric: "702", MinorUnits: 2},
+	{Code: "thb", Numeric: "764", MinorUnits: 2},
+	{Code: "tjs", Numeric: "972", MinorUnits: 2},
+	{Code: "tnd", Numeric: "788", MinorUnits: 3},
+	{Code: "try", Numeric: "949", MinorUnits: 2},
+	{Code: "uah", Numeric: "980", MinorUnits: 2},
+	{Code: "usd", Numeric: "840", MinorUnits: 2, Enabled: true},
+	{Code: "uzs", Numeric: "860", MinorUnits: 2},
+	{Code: "vnd", Numeric: "704", MinorUnits: 0},
+	{Code: "zar", Numeric: "710", MinorUnits: 2},
+}
+
+// Возвращает валюту из списка ISO4217 по коду без учета регистра.
+func Find(code string) (Currency, bool) {
+	code = strings.ToLower(code)
+
+	for _, value := range ISO4217 {
+		if value.Code == code {
+			return value, true
+		}
+	}
+
+	return Currency{}, false
+}
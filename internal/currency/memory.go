@@ -0,0 +1,69 @@
+package currency
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// memoryRepository — это потокобезопасная реализация CurrencyRepository в памяти.
+// @property mu - Мьютекс, который защищает валюты.
+// @property currencies - Валюты по коду.
+type memoryRepository struct {
+	mu         sync.RWMutex
+	currencies map[string]Currency
+}
+
+// Он создает новый пустой репозиторий в памяти и возвращает указатель на него.
+func NewMemoryCurrencyRepository() *memoryRepository {
+	return &memoryRepository{
+		currencies: make(map[string]Currency),
+	}
+}
+
+// Добавление валют. Уже сохраненные валюты не меняются.
+func (r *memoryRepository) SyncCurrencies(ctx context.Context, input []Currency) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, value := range input {
+		if _, ok := r.currencies[value.Code]; !ok {
+			r.currencies[value.Code] = value
+		}
+	}
+
+	return nil
+}
+
+// Функция, которая возвращает срез всех валют по возрастанию кода.
+func (r *memoryRepository) GetCurrencies(ctx context.Context) ([]Currency, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	output := make([]Currency, 0, len(r.currencies))
+	for _, value := range r.currencies {
+		output = append(output, value)
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].Code < output[j].Code
+	})
+
+	return output, nil
+}
+
+// Включение или отключение валюты.
+func (r *memoryRepository) SetEnabled(ctx context.Context, code string, enabled bool) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	value, ok := r.currencies[code]
+	if !ok {
+		return 0, nil
+	}
+
+	value.Enabled = enabled
+	r.currencies[code] = value
+
+	return 1, nil
+}
@@ -0,0 +1,127 @@
+package currency
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+const currencies = "currencies"
+
+// Репозиторий — это структура с полем db, которое является указателем на sql.DB.
+// @property db - Это указатель на объект sql.DB, который будет использоваться для подключения к базе
+// данных.
+type repository struct {
+	db *sql.DB
+}
+
+// Он создает новый экземпляр структуры репозитория и возвращает указатель на него.
+func NewCurrencyRepository(db *sql.DB) *repository {
+	return &repository{
+		db: db,
+	}
+}
+
+// Добавление валют одним запросом. Уже сохраненные валюты, включая их признак enabled, не меняются.
+func (r *repository) SyncCurrencies(ctx context.Context, input []Currency) error {
+	const format = `INSERT INTO %s (code, numeric_code, minor_units, enabled)
+						VALUES %s
+					ON CONFLICT (code) DO NOTHING`
+
+	if len(input) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(input))
+	args := make([]interface{}, 0, len(input)*4)
+	for _, value := range input {
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4))
+		args = append(args, value.Code, value.Numeric, value.MinorUnits, value.Enabled)
+	}
+
+	query := fmt.Sprintf(
+		format,
+		currencies,
+		strings.Join(values, ", "),
+	)
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("currency-repository-SyncCurrencies, %s", err.Error())
+	}
+
+	return nil
+}
+
+// Функция, которая возвращает срез всех валют.
+func (r *repository) GetCurrencies(ctx context.Context) ([]Currency, error) {
+	const format = `SELECT code, numeric_code, minor_units, enabled from %s
+					ORDER BY code`
+
+	query := fmt.Sprintf(
+		format,
+		currencies,
+	)
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+	)
+	if err != nil {
+		return []Currency{}, fmt.Errorf("currency-repository-GetCurrencies, %s", err.Error())
+	}
+
+	defer rows.Close()
+
+	output := make([]Currency, 0)
+	for rows.Next() {
+		value := Currency{}
+
+		err := rows.Scan(
+			&value.Code,
+			&value.Numeric,
+			&value.MinorUnits,
+			&value.Enabled,
+		)
+		if err != nil {
+			return []Currency{}, fmt.Errorf("currency-repository-GetCurrencies, %s", err.Error())
+		}
+
+		output = append(output, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []Currency{}, fmt.Errorf("currency-repository-GetCurrencies, %s", err.Error())
+	}
+
+	return output, nil
+}
+
+// Включение или отключение валюты.
+func (r *repository) SetEnabled(ctx context.Context, code string, enabled bool) (int64, error) {
+	const format = `UPDATE %s SET enabled = $1
+						WHERE code = $2`
+
+	query := fmt.Sprintf(
+		format,
+		currencies,
+	)
+
+	rows, err := r.db.ExecContext(
+		ctx,
+		query,
+		enabled,
+		code,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("currency-repository-SetEnabled, %s", err.Error())
+	}
+
+	return rows.RowsAffected()
+}
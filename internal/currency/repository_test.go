@@ -0,0 +1,125 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет, что валюты добавляются одним запросом без изменения сохраненных.
+func TestSyncCurrencies(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	r := NewCurrencyRepository(db)
+
+	dbMock.ExpectExec("INSERT INTO currencies (.+) ON CONFLICT \\(code\\) DO NOTHING").
+		WithArgs("usd", "840", 2, true, "jpy", "392", 0, false).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err = r.SyncCurrencies(
+		context.TODO(),
+		[]Currency{
+			{Code: "usd", Numeric: "840", MinorUnits: 2, Enabled: true},
+			{Code: "jpy", Numeric: "392", MinorUnits: 0},
+		},
+	)
+
+	assert.NoError(t, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+// Он проверяет получение всех валют.
+func TestGetCurrencies(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	r := NewCurrencyRepository(db)
+
+	tests := []struct {
+		name   string
+		mock   func()
+		expect []Currency
+		err    error
+	}{
+		{
+			name: "Get currencies",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"code", "numeric_code", "minor_units", "enabled"}).
+					AddRow("jpy", "392", 0, false).
+					AddRow("usd", "840", 2, true)
+
+				dbMock.ExpectQuery("SELECT (.+) from currencies").
+					WillReturnRows(rows)
+			},
+			expect: []Currency{
+				{Code: "jpy", Numeric: "392", MinorUnits: 0, Enabled: false},
+				{Code: "usd", Numeric: "840", MinorUnits: 2, Enabled: true},
+			},
+		},
+		{
+			name: "Fail",
+			mock: func() {
+				dbMock.ExpectQuery("SELECT (.+) from currencies").
+					WillReturnError(errors.New("select error"))
+			},
+			err: errors.New("select error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.GetCurrencies(context.TODO())
+
+			if tt.err != nil {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expect, got)
+			}
+
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+// Он проверяет включение и отключение валюты.
+func TestSetEnabled(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	r := NewCurrencyRepository(db)
+
+	dbMock.ExpectExec("UPDATE currencies SET enabled").
+		WithArgs(false, "usd").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	got, err := r.SetEnabled(context.TODO(), "usd", false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), got)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
@@ -0,0 +1,146 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Ошибки, которые возвращает реестр валют.
+var (
+	ErrCurrencyNotFound = errors.New("currency not found")
+	ErrCurrencyDisabled = errors.New("currency is disabled")
+)
+
+// UseCase — это реестр валют. Валюты читаются из хранилища при запуске и держатся в памяти, поэтому
+// проверка валюты платежа не обращается к базе данных. Включение и отключение сначала сохраняется в
+// хранилище, затем применяется к реестру.
+// @property mu - Мьютекс, который защищает валюты.
+// @property {CurrencyRepository} repo - Это хранилище реестра валют.
+// @property currencies - Валюты по коду.
+type UseCase struct {
+	mu         sync.RWMutex
+	repo       CurrencyRepository
+	currencies map[string]Currency
+}
+
+// > Эта функция создает новый экземпляр структуры UseCase с пустым реестром и возвращает указатель на
+// нее. Реестр заполняется методом Load.
+func NewCurrencyUseCase(repo CurrencyRepository) *UseCase {
+	return &UseCase{
+		repo:       repo,
+		currencies: make(map[string]Currency),
+	}
+}
+
+// Дополняет хранилище валютами ISO4217, которых в нем нет, и загружает все валюты в реестр.
+func (u *UseCase) Load(ctx context.Context) error {
+	if err := u.repo.SyncCurrencies(ctx, ISO4217); err != nil {
+		return err
+	}
+
+	values, err := u.repo.GetCurrencies(ctx)
+	if err != nil {
+		return err
+	}
+
+	currencies := make(map[string]Currency, len(values))
+	for _, value := range values {
+		currencies[value.Code] = value
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.currencies = currencies
+
+	return nil
+}
+
+// Эта функция используется для получения валюты, в которой принимаются платежи. Возвращает
+// ErrCurrencyNotFound для валюты вне реестра и ErrCurrencyDisabled для отключенной валюты.
+func (u *UseCase) Lookup(code string) (Currency, error) {
+	value, err := u.GetCurrency(code)
+	if err != nil {
+		return Currency{}, err
+	}
+
+	if !value.Enabled {
+		return Currency{}, fmt.Errorf("currency-UseCase-Lookup, %w: %q", ErrCurrencyDisabled, value.Code)
+	}
+
+	return value, nil
+}
+
+// Эта функция используется для получения валюты по коду без учета регистра.
+func (u *UseCase) GetCurrency(code string) (Currency, error) {
+	code = strings.ToLower(code)
+
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	value, ok := u.currencies[code]
+	if !ok {
+		return Currency{}, fmt.Errorf("currency-UseCase-GetCurrency, %w: %q", ErrCurrencyNotFound, code)
+	}
+
+	return value, nil
+}
+
+// Эта функция используется для получения всех валют реестра по возрастанию кода.
+func (u *UseCase) GetCurrencies() []Currency {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	output := make([]Currency, 0, len(u.currencies))
+	for _, value := range u.currencies {
+		output = append(output, value)
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].Code < output[j].Code
+	})
+
+	return output
+}
+
+// Эта функция используется для включения или отключения валюты. Платежи, уже созданные в отключенной
+// валюте, не меняются.
+func (u *UseCase) SetEnabled(ctx context.Context, code string, enabled bool) (Currency, error) {
+	code = strings.ToLower(code)
+
+	rows, err := u.repo.SetEnabled(
+		ctx,
+		code,
+		enabled,
+	)
+	if err != nil {
+		return Currency{}, err
+	}
+
+	if rows == 0 {
+		return Currency{}, fmt.Errorf("currency-UseCase-SetEnabled, %w: %q", ErrCurrencyNotFound, code)
+	}
+
+	u.mu.Lock()
+	value, ok := u.currencies[code]
+	if ok {
+		value.Enabled = enabled
+		u.currencies[code] = value
+	}
+	u.mu.Unlock()
+
+	// Валюту добавили в хранилище после запуска, реестр перечитывается целиком.
+	if !ok {
+		if err := u.Load(ctx); err != nil {
+			return Currency{}, err
+		}
+
+		return u.GetCurrency(code)
+	}
+
+	return value, nil
+}
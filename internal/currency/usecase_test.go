@@ -0,0 +1,87 @@
+package currency
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет поиск валюты по реестру.
+func TestUseCaseLookup(t *testing.T) {
+	t.Parallel()
+
+	u := NewCurrencyUseCase(NewMemoryCurrencyRepository())
+	assert.NoError(t, u.Load(context.TODO()))
+
+	tests := []struct {
+		name   string
+		input  string
+		expect Currency
+		err    error
+	}{
+		{
+			name:   "Enabled currency",
+			input:  "USD",
+			expect: Currency{Code: "usd", Numeric: "840", MinorUnits: 2, Enabled: true},
+		},
+		{
+			name:  "Disabled currency",
+			input: "gbp",
+			err:   ErrCurrencyDisabled,
+		},
+		{
+			name:  "Unknown currency",
+			input: "xxx",
+			err:   ErrCurrencyNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := u.Lookup(tt.input)
+
+		if tt.err != nil {
+			assert.ErrorIs(t, err, tt.err, tt.name)
+		} else {
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, tt.expect, got, tt.name)
+		}
+	}
+}
+
+// Он проверяет, что включение валюты сохраняется в хранилище и сразу применяется к реестру.
+func TestUseCaseSetEnabled(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	repo := NewMemoryCurrencyRepository()
+
+	u := NewCurrencyUseCase(repo)
+	assert.NoError(t, u.Load(ctx))
+
+	got, err := u.SetEnabled(ctx, "GBP", true)
+	assert.NoError(t, err)
+	assert.Equal(t, Currency{Code: "gbp", Numeric: "826", MinorUnits: 2, Enabled: true}, got)
+
+	_, err = u.Lookup("gbp")
+	assert.NoError(t, err)
+
+	_, err = u.SetEnabled(ctx, "usd", false)
+	assert.NoError(t, err)
+
+	_, err = u.Lookup("usd")
+	assert.ErrorIs(t, err, ErrCurrencyDisabled)
+
+	_, err = u.SetEnabled(ctx, "xxx", true)
+	assert.ErrorIs(t, err, ErrCurrencyNotFound)
+
+	// Повторная загрузка не сбрасывает сохраненные признаки.
+	reloaded := NewCurrencyUseCase(repo)
+	assert.NoError(t, reloaded.Load(ctx))
+
+	_, err = reloaded.Lookup("gbp")
+	assert.NoError(t, err)
+
+	_, err = reloaded.Lookup("usd")
+	assert.ErrorIs(t, err, ErrCurrencyDisabled)
+}
@@ -0,0 +1,18 @@
+package outbox
+
+import "context"
+
+// Store — это интерфейс хранилища outbox.
+// @property FetchOutbox - Возвращает не опубликованные события в порядке их записи.
+// @property MarkOutboxPublished - Отмечает события опубликованными.
+type Store interface {
+	FetchOutbox(ctx context.Context, limit int) ([]Event, error)
+	MarkOutboxPublished(ctx context.Context, ids []int64) error
+}
+
+// Sink — это интерфейс получателя событий outbox. Publish должен вернуть ошибку, если события не
+// приняты, тогда они будут отправлены повторно.
+// @property Publish - Отправляет пачку событий.
+type Sink interface {
+	Publish(ctx context.Context, events []Event) error
+}
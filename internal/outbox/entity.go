@@ -0,0 +1,21 @@
+package outbox
+
+import "time"
+
+// Event — это запись outbox об изменении статуса платежа.
+// @property {int64} ID - Уникальный возрастающий идентификатор события.
+// @property {int64} PaymentID - ID платежа.
+// @property {string} OldStatus - Статус до изменения. Пустой для созданного платежа.
+// @property {string} NewStatus - Статус после изменения.
+// @property {string} Reason - Причина изменения, если она известна.
+// @property {string} Actor - Кто изменил статус.
+// @property {time.Time} CreatedAt - Момент изменения.
+type Event struct {
+	ID        int64     `json:"id"`
+	PaymentID int64     `json:"payment_id"`
+	OldStatus string    `json:"old_status,omitempty"`
+	NewStatus string    `json:"new_status"`
+	Reason    string    `json:"reason,omitempty"`
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,100 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
+)
+
+// RelayOptions — это структура с настройками relay.
+// @property {time.Duration} Interval - Период опроса outbox.
+// @property {int} BatchSize - Сколько событий отправляется в sink за раз.
+type RelayOptions struct {
+	Interval  time.Duration
+	BatchSize int
+}
+
+// Relay — это фоновый процесс, который переносит события из outbox в sink. Событие отмечается
+// опубликованным только после того, как sink его принял, поэтому доставка «как минимум один раз».
+// @property {Store} store - Это хранилище outbox.
+// @property {Sink} sink - Это получатель событий.
+// @property logger - Это регистратор ошибок отправки.
+// @property {RelayOptions} options - Это настройки relay.
+type Relay struct {
+	store   Store
+	sink    Sink
+	logger  loggin.ILogger
+	options RelayOptions
+}
+
+// > Эта функция создает новый экземпляр структуры Relay и возвращает указатель на нее. Возвращает
+// ошибку, если период опроса или размер пачки не больше нуля.
+func NewRelay(store Store, sink Sink, logger loggin.ILogger, options RelayOptions) (*Relay, error) {
+	if options.Interval <= 0 {
+		return nil, fmt.Errorf("outbox-NewRelay, interval %s must be positive", options.Interval)
+	}
+
+	if options.BatchSize <= 0 {
+		return nil, fmt.Errorf("outbox-NewRelay, batch size %d must be positive", options.BatchSize)
+	}
+
+	return &Relay{
+		store:   store,
+		sink:    sink,
+		logger:  logger,
+		options: options,
+	}, nil
+}
+
+// Запускает опрос outbox и блокируется до отмены контекста.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Drain(ctx); err != nil {
+				r.logger.Error(err)
+			}
+		}
+	}
+}
+
+// Отправляет в sink все накопившиеся события пачками по BatchSize.
+func (r *Relay) Drain(ctx context.Context) error {
+	for {
+		events, err := r.store.FetchOutbox(
+			ctx,
+			r.options.BatchSize,
+		)
+		if err != nil {
+			return err
+		}
+
+		if len(events) == 0 {
+			return nil
+		}
+
+		if err := r.sink.Publish(ctx, events); err != nil {
+			return err
+		}
+
+		ids := make([]int64, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+
+		if err := r.store.MarkOutboxPublished(ctx, ids); err != nil {
+			return err
+		}
+
+		if len(events) < r.options.BatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Это хранилище outbox для тестов.
+type fakeStore struct {
+	events    []Event
+	published map[int64]bool
+}
+
+// Возвращает не опубликованные события.
+func (s *fakeStore) FetchOutbox(ctx context.Context, limit int) ([]Event, error) {
+	output := make([]Event, 0)
+	for _, event := range s.events {
+		if len(output) < limit && !s.published[event.ID] {
+			output = append(output, event)
+		}
+	}
+
+	return output, nil
+}
+
+// Отмечает события опубликованными.
+func (s *fakeStore) MarkOutboxPublished(ctx context.Context, ids []int64) error {
+	for _, id := range ids {
+		s.published[id] = true
+	}
+
+	return nil
+}
+
+// Это sink для тестов, который не принимает события.
+type failingSink struct{}
+
+// Возвращает ошибку.
+func (failingSink) Publish(ctx context.Context, events []Event) error {
+	return errors.New("sink is unavailable")
+}
+
+// Он переносит события пачками и проверяет, что каждое событие записано в sink ровно один раз.
+func TestRelayDrain(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeStore{published: make(map[int64]bool)}
+	for i := int64(1); i <= 5; i++ {
+		store.events = append(store.events, Event{ID: i, PaymentID: i, NewStatus: "new", Actor: "api"})
+	}
+
+	buffer := &bytes.Buffer{}
+	relay, err := NewRelay(store, NewWriterSink(buffer), nil, RelayOptions{Interval: time.Second, BatchSize: 2})
+	assert.NoError(t, err)
+
+	assert.NoError(t, relay.Drain(context.TODO()))
+	assert.NoError(t, relay.Drain(context.TODO()))
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Len(t, lines, 5)
+
+	for i, line := range lines {
+		var event Event
+		assert.NoError(t, json.Unmarshal([]byte(line), &event))
+		assert.Equal(t, int64(i+1), event.ID)
+	}
+}
+
+// Он проверяет, что события не отмечаются опубликованными, если sink их не принял.
+func TestRelayDrainSinkError(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeStore{
+		events:    []Event{{ID: 1, PaymentID: 1, NewStatus: "new", Actor: "api"}},
+		published: make(map[int64]bool),
+	}
+
+	relay, err := NewRelay(store, failingSink{}, nil, RelayOptions{Interval: time.Second, BatchSize: 10})
+	assert.NoError(t, err)
+
+	assert.Error(t, relay.Drain(context.TODO()))
+	assert.False(t, store.published[1])
+}
+
+// Он проверяет, что составной sink передает события всем sink, пропуская пустые, и не отмечает их
+// опубликованными, если хотя бы один sink их не принял.
+func TestRelayDrainMultiSink(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeStore{
+		events:    []Event{{ID: 1, PaymentID: 1, NewStatus: "new", Actor: "api"}},
+		published: make(map[int64]bool),
+	}
+
+	first, second := &bytes.Buffer{}, &bytes.Buffer{}
+	relay, err := NewRelay(store, NewMultiSink(NewWriterSink(first), nil, NewWriterSink(second), failingSink{}), nil, RelayOptions{Interval: time.Second, BatchSize: 10})
+	assert.NoError(t, err)
+
+	assert.Error(t, relay.Drain(context.TODO()))
+	assert.False(t, store.published[1])
+
+	relay, err = NewRelay(store, NewMultiSink(NewWriterSink(first), nil, NewWriterSink(second)), nil, RelayOptions{Interval: time.Second, BatchSize: 10})
+	assert.NoError(t, err)
+
+	assert.NoError(t, relay.Drain(context.TODO()))
+	assert.True(t, store.published[1])
+	assert.Equal(t, 2, strings.Count(first.String(), "\n"))
+	assert.Equal(t, 2, strings.Count(second.String(), "\n"))
+}
+
+// Он проверяет, что relay не создается с нулевым периодом опроса или размером пачки.
+func TestNewRelayOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options RelayOptions
+	}{
+		{name: "Zero interval", options: RelayOptions{BatchSize: 100}},
+		{name: "Zero batch size", options: RelayOptions{Interval: time.Second}},
+	}
+
+	for _, tt := range tests {
+		_, err := NewRelay(nil, nil, nil, tt.options)
+		assert.Error(t, err, tt.name)
+	}
+}
@@ -0,0 +1,188 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Это sink, который пишет каждое событие отдельной строкой JSON в io.Writer.
+// @property mu - Мьютекс, который защищает запись.
+// @property writer - Куда пишутся события.
+type writerSink struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+// Он создает sink, который пишет события в writer.
+func NewWriterSink(writer io.Writer) *writerSink {
+	return &writerSink{
+		writer: writer,
+	}
+}
+
+// Он создает sink, который пишет события в стандартный вывод.
+func NewStdoutSink() *writerSink {
+	return NewWriterSink(os.Stdout)
+}
+
+// Пишет события строками JSON.
+func (s *writerSink) Publish(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	encoder := json.NewEncoder(s.writer)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return fmt.Errorf("outbox-writerSink-Publish, %s", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Это sink, который дописывает события строками JSON в файл.
+// @property {writerSink} - Запись событий.
+// @property file - Открытый файл.
+type fileSink struct {
+	*writerSink
+	file *os.File
+}
+
+// Он открывает файл на дозапись и создает sink, который пишет в него события.
+func NewFileSink(path string) (*fileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("outbox-NewFileSink, %s", err.Error())
+	}
+
+	return &fileSink{
+		writerSink: NewWriterSink(file),
+		file:       file,
+	}, nil
+}
+
+// Пишет события в файл и сбрасывает их на диск.
+func (s *fileSink) Publish(ctx context.Context, events []Event) error {
+	if err := s.writerSink.Publish(ctx, events); err != nil {
+		return err
+	}
+
+	return s.file.Sync()
+}
+
+// Закрывает файл.
+func (s *fileSink) Close() error {
+	return s.file.Close()
+}
+
+// Это sink, который отправляет пачку событий массивом JSON POST-запросом.
+// @property {string} url - Адрес получателя.
+// @property client - HTTP-клиент.
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+// Он создает sink, который отправляет события на url.
+func NewHTTPSink(url string, timeout time.Duration) *httpSink {
+	return &httpSink{
+		url: url,
+		client: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// Отправляет события. Ответ с кодом не из диапазона 2xx считается ошибкой.
+func (s *httpSink) Publish(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("outbox-httpSink-Publish, %s", err.Error())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("outbox-httpSink-Publish, %s", err.Error())
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("outbox-httpSink-Publish, %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("outbox-httpSink-Publish, unexpected response status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Это sink, который по очереди передает пачку событий нескольким sink. Если один из них вернул ошибку,
+// пачка отправляется повторно во все sink, поэтому каждый из них должен выдерживать повторы.
+// @property sinks - Получатели событий.
+type multiSink struct {
+	sinks []Sink
+}
+
+// Он создает sink, который передает события всем sinks. Пустые sink пропускаются.
+func NewMultiSink(sinks ...Sink) *multiSink {
+	s := &multiSink{}
+	for _, sink := range sinks {
+		if sink != nil {
+			s.sinks = append(s.sinks, sink)
+		}
+	}
+
+	return s
+}
+
+// Передает события каждому sink и возвращает первую ошибку.
+func (s *multiSink) Publish(ctx context.Context, events []Event) error {
+	for _, sink := range s.sinks {
+		if err := sink.Publish(ctx, events); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Виды sink, которые можно выбрать в конфигурации.
+const (
+	SinkNone   = "none"
+	SinkStdout = "stdout"
+	SinkFile   = "file"
+	SinkHTTP   = "http"
+)
+
+// Он создает sink по его виду. Для SinkNone возвращается nil: события получают только webhook.
+func NewSink(kind, path, url string, timeout time.Duration) (Sink, error) {
+	switch kind {
+	case SinkNone, "":
+		return nil, nil
+	case SinkStdout:
+		return NewStdoutSink(), nil
+	case SinkFile:
+		return NewFileSink(path)
+	case SinkHTTP:
+		if url == "" {
+			return nil, fmt.Errorf("outbox-NewSink, %s", "http sink requires url")
+		}
+
+		return NewHTTPSink(url, timeout), nil
+	default:
+		return nil, fmt.Errorf("outbox-NewSink, unknown sink %q", kind)
+	}
+}
@@ -0,0 +1,119 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
+)
+
+// AuthorizationOptions — это структура с настройками фоновой отмены истекших авторизаций.
+// @property {time.Duration} Hold - Срок удержания авторизации, после которого она отменяется.
+// @property {time.Duration} Interval - Период поиска истекших авторизаций.
+// @property {int} BatchSize - Сколько авторизаций читается за один запрос.
+type AuthorizationOptions struct {
+	Hold      time.Duration
+	Interval  time.Duration
+	BatchSize int
+}
+
+// ExpiryWorker — это фоновый процесс, который отменяет авторизации старше срока удержания. Отмена
+// выполняется через UseCase.VoidPayment от имени «expiry» с причиной «authorization expired», поэтому
+// она попадает в историю, outbox и webhook как обычная.
+// @property {PaymentRepository} repo - Это хранилище, из которого читаются истекшие авторизации.
+// @property {PaymentUseCase} UseCase - Это вариант использования, через который отменяется авторизация.
+// @property logger - Это регистратор ошибок отмены.
+// @property {AuthorizationOptions} options - Это срок удержания и настройки опроса.
+// @property now - Источник текущего времени.
+type ExpiryWorker struct {
+	repo    PaymentRepository
+	UseCase PaymentUseCase
+	logger  loggin.ILogger
+	options AuthorizationOptions
+	now     func() time.Time
+}
+
+// > Эта функция создает новый экземпляр структуры ExpiryWorker и возвращает указатель на нее.
+// Возвращает ошибку, если период опроса или размер пачки не больше нуля.
+func NewExpiryWorker(repo PaymentRepository, u PaymentUseCase, logger loggin.ILogger, options AuthorizationOptions) (*ExpiryWorker, error) {
+	if options.Interval <= 0 {
+		return nil, fmt.Errorf("payment-NewExpiryWorker, interval %s must be positive", options.Interval)
+	}
+
+	if options.BatchSize <= 0 {
+		return nil, fmt.Errorf("payment-NewExpiryWorker, batch size %d must be positive", options.BatchSize)
+	}
+
+	return &ExpiryWorker{
+		repo:    repo,
+		UseCase: u,
+		logger:  logger,
+		options: options,
+		now:     time.Now,
+	}, nil
+}
+
+// Запускает поиск истекших авторизаций и блокируется до отмены контекста.
+func (w *ExpiryWorker) Run(ctx context.Context) {
+	// Варианты использования и репозиторий пишут в журнал через регистратор из контекста.
+	ctx = loggin.NewContext(ctx, w.logger)
+
+	ticker := time.NewTicker(w.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Expire(ctx); err != nil {
+				w.logger.Error(err)
+			}
+		}
+	}
+}
+
+// Отменяет все авторизации, срок удержания которых истек. Авторизация, которую успели списать или
+// отменить, пропускается. При другой ошибке опрос прекращается до следующего запуска.
+func (w *ExpiryWorker) Expire(ctx context.Context) error {
+	ctx = WithActor(ctx, ActorExpiry)
+	before := w.now().Add(-w.options.Hold)
+
+	for {
+		values, err := w.repo.GetExpiredAuthorizations(
+			ctx,
+			before,
+			w.options.BatchSize,
+		)
+		if err != nil {
+			return err
+		}
+
+		if len(values) == 0 {
+			return nil
+		}
+
+		for _, value := range values {
+			err := w.UseCase.VoidPayment(
+				ctx,
+				PaymentStatus{
+					ID:     value.ID,
+					Reason: ReasonAuthorizationExpired,
+				},
+			)
+
+			var transitionErr *TransitionError
+			switch {
+			case err == nil, errors.As(err, &transitionErr), errors.Is(err, ErrNotAuthorized), errors.Is(err, ErrPaymentNotFound):
+			default:
+				return err
+			}
+		}
+
+		if len(values) < w.options.BatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package payment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет частичное списание авторизации, ограничение возвратов списанной суммой и отмену
+// только авторизованного платежа.
+func TestUseCaseCaptureAndVoid(t *testing.T) {
+	t.Parallel()
+
+	repo := NewMemoryPaymentRepository()
+	usc := NewPaymentUseCase(repo, Options{})
+	ctx := context.TODO()
+
+	captured, err := repo.CreatePayment(ctx, PaymentInput{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("10.5"), Currency: "usd"})
+	assert.NoError(t, err)
+
+	voided, err := repo.CreatePayment(ctx, PaymentInput{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("3"), Currency: "usd"})
+	assert.NoError(t, err)
+
+	_, err = usc.CapturePayment(ctx, CaptureInput{PaymentID: captured})
+	assert.ErrorIs(t, err, ErrNotAuthorized)
+
+	assert.ErrorIs(t, usc.VoidPayment(ctx, PaymentStatus{ID: voided}), ErrNotAuthorized)
+
+	for _, id := range []int64{captured, voided} {
+		assert.NoError(t, usc.UpdateStatus(ctx, PaymentStatus{ID: id, Status: StatusAuthorized}))
+	}
+
+	_, err = usc.CapturePayment(ctx, CaptureInput{PaymentID: captured, Amount: mustDecimal("11")})
+	assert.ErrorIs(t, err, ErrCaptureExceedsAmount)
+
+	_, err = usc.CapturePayment(ctx, CaptureInput{PaymentID: captured, Amount: mustDecimal("4.555")})
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+
+	_, err = usc.CapturePayment(ctx, CaptureInput{PaymentID: captured, Amount: mustDecimal("4.5"), Version: 1})
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+
+	data, err := usc.CapturePayment(ctx, CaptureInput{PaymentID: captured, Amount: mustDecimal("4.5"), Reason: "partial shipment", Version: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, CaptureData{ID: captured, Status: StatusSuccess, CapturedAmount: mustDecimal("4.5")}, data)
+
+	history, err := usc.GetHistory(ctx, captured)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusAuthorized, history[len(history)-1].FromStatus)
+	assert.Equal(t, StatusSuccess, history[len(history)-1].ToStatus)
+	assert.Equal(t, "partial shipment", history[len(history)-1].Reason)
+
+	_, err = usc.CreateRefund(ctx, RefundInput{PaymentID: captured, Amount: mustDecimal("5")})
+	assert.ErrorIs(t, err, ErrRefundExceedsAmount)
+
+	value, err := usc.CreateRefund(ctx, RefundInput{PaymentID: captured})
+	assert.NoError(t, err)
+	assert.Equal(t, mustDecimal("4.5"), value.Amount)
+
+	assert.NoError(t, usc.VoidPayment(ctx, PaymentStatus{ID: voided, Reason: "customer request"}))
+
+	status, err := usc.GetStatus(ctx, voided)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCanceled, status)
+
+	assert.ErrorIs(t, usc.VoidPayment(ctx, PaymentStatus{ID: voided}), ErrNotAuthorized)
+	assert.ErrorIs(t, usc.VoidPayment(ctx, PaymentStatus{ID: 100}), ErrPaymentNotFound)
+}
+
+// Он проверяет, что фоновый процесс отменяет только авторизации старше срока удержания и записывает
+// отмену от имени «expiry».
+func TestExpiryWorkerExpire(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, 7, 30, 12, 0, 0, 0, time.UTC)
+
+	repo := NewMemoryPaymentRepository()
+	repo.now = func() time.Time { return now }
+
+	usc := NewPaymentUseCase(repo, Options{})
+
+	worker, err := NewExpiryWorker(
+		repo,
+		usc,
+		nil,
+		AuthorizationOptions{
+			Hold:      time.Hour,
+			Interval:  time.Minute,
+			BatchSize: 1,
+		},
+	)
+	assert.NoError(t, err)
+	worker.now = func() time.Time { return now }
+
+	ctx := context.TODO()
+
+	for i := 0; i < 3; i++ {
+		id, err := repo.CreatePayment(ctx, PaymentInput{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("10"), Currency: "usd"})
+		assert.NoError(t, err)
+
+		if i < 2 {
+			assert.NoError(t, usc.UpdateStatus(ctx, PaymentStatus{ID: id, Status: StatusAuthorized}))
+		}
+	}
+
+	now = now.Add(30 * time.Minute)
+	assert.NoError(t, worker.Expire(ctx))
+
+	status, err := repo.GetStatus(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusAuthorized, status)
+
+	now = now.Add(time.Hour)
+	assert.NoError(t, worker.Expire(ctx))
+
+	expect := []string{StatusCanceled, StatusCanceled, StatusNew}
+	for i, status := range expect {
+		got, err := repo.GetStatus(ctx, int64(i+1))
+		assert.NoError(t, err)
+		assert.Equal(t, status, got)
+	}
+
+	history, err := repo.GetHistory(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, ReasonAuthorizationExpired, history[2].Reason)
+	assert.Equal(t, ActorExpiry, history[2].Actor)
+}
+
+// Он проверяет, что процесс не создается с нулевым периодом опроса или размером пачки.
+func TestNewExpiryWorkerOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options AuthorizationOptions
+	}{
+		{name: "Zero interval", options: AuthorizationOptions{Hold: time.Hour, BatchSize: 100}},
+		{name: "Zero batch size", options: AuthorizationOptions{Hold: time.Hour, Interval: time.Minute}},
+	}
+
+	for _, tt := range tests {
+		_, err := NewExpiryWorker(nil, nil, nil, tt.options)
+		assert.Error(t, err, tt.name)
+	}
+}
@@ -1,18 +1,105 @@
 package payment
 
 const (
-	StatusNew      = "new"
-	StatusError    = "error"
-	StatusSuccess  = "success"
-	StatusFailure  = "failure"
-	StatusCanceled = "canceled"
+	StatusNew        = "new"
+	StatusAuthorized = "authorized"
+	StatusError      = "error"
+	StatusSuccess    = "success"
+	StatusFailure    = "failure"
+	StatusCanceled   = "canceled"
+)
+
+const (
+	RefundStatusPending   = "pending"
+	RefundStatusSucceeded = "succeeded"
+	RefundStatusFailed    = "failed"
+)
+
+const (
+	RefundStatePartiallyRefunded = "partially_refunded"
+	RefundStateRefunded          = "refunded"
 )
 
 const InternalServerError = "internal server error"
 
 const (
-	InvalidBodyData   = "invalid body data"
-	InvalidQueryID    = "invalid query id"
-	InvalidQueryEmail = "invalid query email"
-	InvalidBodyEmail  = "invalid body email"
+	InvalidBodyData     = "invalid body data"
+	InvalidQueryID      = "invalid query id"
+	InvalidQueryEmail   = "invalid query email"
+	InvalidBodyEmail    = "invalid body email"
+	InvalidBodyStatus   = "invalid body status"
+	InvalidBodyAmount   = "invalid body amount"
+	InvalidBodyCurrency = "invalid body currency"
+	CurrencyDisabled    = "currency is disabled"
+)
+
+const (
+	InvalidBodySettlementCurrency = "invalid body settlement_currency"
+	RateNotFound                  = "exchange rate not found"
+)
+
+// Описания ошибок проверки полей запроса, которые дополняют правила пакета validator.
+const (
+	InvalidValue          = "invalid value"
+	UnknownValue          = "unknown value"
+	MustBeInteger         = "must be an integer"
+	MustBeDecimal         = "must be a decimal number"
+	MustBeTime            = "must be an RFC 3339 time"
+	MustBeAtMostMaxAmount = "must be <= max_amount"
+	MustBeBeforeCreatedTo = "must be before created_to"
+)
+
+const (
+	UnknownField = "unknown field"
+	BodyTooLarge = "request body too large"
+)
+
+const (
+	PaymentNotFound     = "payment not found"
+	RefundNotAllowed    = "refund is allowed only for a successful payment"
+	RefundExceedsAmount = "refund exceeds the remaining amount of the payment"
+)
+
+const (
+	InvalidIdempotencyKey    = "invalid idempotency key"
+	IdempotencyKeyReused     = "idempotency key reused with a different request"
+	IdempotencyKeyInProgress = "a request with this idempotency key is in progress"
+)
+
+const InvalidStatusTransition = "invalid status transition"
+
+const (
+	PaymentNotAuthorized = "payment is not authorized"
+	CaptureExceedsAmount = "capture exceeds the authorized amount"
+)
+
+const VersionMismatch = "payment version does not match If-Match"
+
+// Причина отмены авторизации, срок удержания которой истек.
+const ReasonAuthorizationExpired = "authorization expired"
+
+// Машиночитаемые коды ошибок в поле «code» ответа.
+const (
+	CodeInternalError            = "internal_error"
+	CodeInvalidBody              = "invalid_body"
+	CodeInvalidID                = "invalid_id"
+	CodeInvalidField             = "invalid_field"
+	CodeCurrencyDisabled         = "currency_disabled"
+	CodeUnknownStatus            = "unknown_status"
+	CodeInvalidStatusTransition  = "invalid_status_transition"
+	CodeTerminalStatus           = "terminal_status"
+	CodePaymentNotFound          = "payment_not_found"
+	CodeRefundNotAllowed         = "refund_not_allowed"
+	CodeRefundExceedsAmount      = "refund_exceeds_amount"
+	CodeInvalidAmount            = "invalid_amount"
+	CodeRateNotFound             = "rate_not_found"
+	CodePaymentNotAuthorized     = "payment_not_authorized"
+	CodeCaptureExceedsAmount     = "capture_exceeds_amount"
+	CodeInvalidIdempotencyKey    = "invalid_idempotency_key"
+	CodeIdempotencyKeyReused     = "idempotency_key_reused"
+	CodeIdempotencyKeyInProgress = "idempotency_key_in_progress"
+	CodeVersionMismatch          = "version_mismatch"
+	CodeUnknownField             = "unknown_field"
+	CodeBodyTooLarge             = "body_too_large"
+	CodeInvalidRates             = "invalid_rates"
 )
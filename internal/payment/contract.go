@@ -2,35 +2,112 @@ package payment
 
 import (
 	"context"
+	"time"
+
+	"github.com/onlycodergod/payment-api-emulator/internal/currency"
+	"github.com/onlycodergod/payment-api-emulator/internal/outbox"
 )
 
-// PaymentRepository — это интерфейс с 5 методами: CreatePayment, UpdateStatus, GetStatus, GetPayments
-// и CancelPayment.
+// PaymentRepository — это интерфейс с методами: WithinTransaction, LockPayment, CreatePayment,
+// UpdateStatus, GetStatus, GetPayment, GetPayments, CancelPayment, CapturePayment, VoidPayment,
+// GetIdempotencyKey, ReserveIdempotencyKey, SaveIdempotencyKey, ReleaseIdempotencyKey, CreateRefund,
+// GetRefunds и GetHistory, а также методы outbox.Store. Каждое изменение статуса записывает событие
+// outbox и историю в той же транзакции.
+// @property WithinTransaction - Выполняет функцию в одной транзакции, которая передается через
+// контекст во все методы репозитория.
+// @property LockPayment - Возвращает платеж и блокирует его до конца транзакции.
 // @property CreatePayment - Этот метод используется для создания нового платежа.
 // @property UpdateStatus - Это используется для обновления статуса платежа.
 // @property GetStatus - Этот метод используется для получения статуса платежа.
-// @property GetPayments - Этот метод используется для получения всех платежей, сделанных
-// пользователем.
+// @property GetPayment - Возвращает платеж по ID.
+// @property GetPayments - Этот метод используется для получения страницы платежей, сделанных
+// пользователем. Возвращает до Limit+1 строк, лишняя строка означает следующую страницу.
 // @property CancelPayment - Используется для отмены платежа.
+// @property CapturePayment - Списывает авторизованный платеж полностью или частично и возвращает
+// списанную сумму.
+// @property VoidPayment - Отменяет платеж, только если он авторизован.
+// @property GetIdempotencyKey - Возвращает не истекший сохраненный ответ по Idempotency-Key.
+// @property ReserveIdempotencyKey - Резервирует Idempotency-Key за выполняющимся запросом, если ключ
+// свободен или истек.
+// @property SaveIdempotencyKey - Сохраняет ответ по зарезервированному Idempotency-Key.
+// @property ReleaseIdempotencyKey - Снимает резерв Idempotency-Key без сохранения ответа.
+// @property CreateRefund - Создает возврат, не допуская превышения суммы платежа.
+// @property GetRefunds - Возвращает все возвраты платежа.
+// @property GetHistory - Возвращает историю переходов статуса платежа.
+// @property GetPaymentsByStatus - Возвращает платежи в статусе с ID больше заданного по возрастанию ID.
+// @property GetExpiredAuthorizations - Возвращает авторизованные платежи, авторизация которых старше
+// заданного момента.
 type PaymentRepository interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	LockPayment(ctx context.Context, PaymentID int64) (payment, error)
 	CreatePayment(ctx context.Context, input PaymentInput) (int64, error)
 	UpdateStatus(ctx context.Context, input PaymentStatus) (int64, error)
 	GetStatus(ctx context.Context, PaymentID int64) (string, error)
+	GetPayment(ctx context.Context, PaymentID int64) (payment, error)
 	GetPayments(ctx context.Context, input PaymentUser) ([]payment, error)
-	CancelPayment(ctx context.Context, PaymentID int64) (int64, error)
+	CancelPayment(ctx context.Context, input PaymentStatus) (int64, error)
+	CapturePayment(ctx context.Context, input CaptureInput) (Decimal, error)
+	VoidPayment(ctx context.Context, input PaymentStatus) (int64, error)
+	GetIdempotencyKey(ctx context.Context, key string) (IdempotencyKey, bool, error)
+	ReserveIdempotencyKey(ctx context.Context, input IdempotencyKey) (bool, error)
+	SaveIdempotencyKey(ctx context.Context, input IdempotencyKey) error
+	ReleaseIdempotencyKey(ctx context.Context, input IdempotencyKey) error
+	CreateRefund(ctx context.Context, input RefundInput) (refund, error)
+	GetRefunds(ctx context.Context, PaymentID int64) ([]refund, error)
+	GetHistory(ctx context.Context, PaymentID int64) ([]statusChange, error)
+	GetPaymentsByStatus(ctx context.Context, status string, AfterID int64, limit int) ([]payment, error)
+	GetExpiredAuthorizations(ctx context.Context, before time.Time, limit int) ([]payment, error)
+	outbox.Store
 }
 
-// PaymentUseCase — это интерфейс с 5 методами: CreatePayment, UpdateStatus, GetStatus, GetPayments и
-// CancelPayment.
+// PaymentUseCase — это интерфейс с методами: CreatePayment, UpdateStatus, GetStatus, GetPayment, GetPayments,
+// CancelPayment, CapturePayment, VoidPayment, GetIdempotencyKey, ReserveIdempotencyKey,
+// SaveIdempotencyKey, ReleaseIdempotencyKey, CreateRefund, GetRefunds и GetHistory.
 // @property CreatePayment - Эта функция используется для создания платежа.
 // @property {error} UpdateStatus - Это используется для обновления статуса платежа.
 // @property GetStatus - Используется для получения статуса платежа.
-// @property GetPayments - Это используется для получения всех платежей пользователя.
+// @property GetPayment - Возвращает платеж по ID со всеми полями.
+// @property GetPayments - Это используется для получения страницы платежей пользователя с курсором
+// следующей страницы.
 // @property {error} CancelPayment - Это функция, которая будет использоваться для отмены платежа.
+// @property CapturePayment - Списывает авторизованный платеж полностью или частично.
+// @property {error} VoidPayment - Отменяет авторизацию платежа.
+// @property GetIdempotencyKey - Возвращает сохраненный ответ по Idempotency-Key.
+// @property ReserveIdempotencyKey - Резервирует Idempotency-Key за выполняющимся запросом на время
+// IdempotencyLockTimeout.
+// @property {error} SaveIdempotencyKey - Сохраняет ответ по Idempotency-Key на время жизни ключа.
+// @property {error} ReleaseIdempotencyKey - Снимает резерв Idempotency-Key без сохранения ответа.
+// @property CreateRefund - Создает полный или частичный возврат платежа.
+// @property GetRefunds - Возвращает все возвраты платежа.
+// @property GetHistory - Возвращает историю переходов статуса платежа.
 type PaymentUseCase interface {
 	CreatePayment(ctx context.Context, input PaymentInput) (int64, error)
 	UpdateStatus(ctx context.Context, input PaymentStatus) error
 	GetStatus(ctx context.Context, PaymentID int64) (string, error)
-	GetPayments(ctx context.Context, input PaymentUser) ([]payment, error)
-	CancelPayment(ctx context.Context, PaymentID int64) error
+	GetPayment(ctx context.Context, PaymentID int64) (payment, error)
+	GetPayments(ctx context.Context, input PaymentUser) (PaymentsData, error)
+	CancelPayment(ctx context.Context, input PaymentStatus) error
+	CapturePayment(ctx context.Context, input CaptureInput) (CaptureData, error)
+	VoidPayment(ctx context.Context, input PaymentStatus) error
+	GetIdempotencyKey(ctx context.Context, key string) (IdempotencyKey, bool, error)
+	ReserveIdempotencyKey(ctx context.Context, input IdempotencyKey) (bool, error)
+	SaveIdempotencyKey(ctx context.Context, input IdempotencyKey) error
+	ReleaseIdempotencyKey(ctx context.Context, input IdempotencyKey) error
+	CreateRefund(ctx context.Context, input RefundInput) (refund, error)
+	GetRefunds(ctx context.Context, PaymentID int64) ([]refund, error)
+	GetHistory(ctx context.Context, PaymentID int64) ([]statusChange, error)
+}
+
+// CurrencyRegistry — это интерфейс реестра валют, по которому контроллер проверяет валюту платежа.
+// @property Lookup - Возвращает включенную валюту по коду или ошибку, если валюта неизвестна или
+// отключена.
+type CurrencyRegistry interface {
+	Lookup(code string) (currency.Currency, error)
+}
+
+// RateConverter — это интерфейс таблицы курсов, по которой контроллер пересчитывает сумму платежа в
+// валюту расчетов.
+// @property Convert - Возвращает расчет суммы в валюте to или ErrRateNotFound, если курса нет.
+type RateConverter interface {
+	Convert(amount Money, to currency.Currency) (Settlement, error)
 }
@@ -2,9 +2,12 @@ package payment
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/onlycodergod/payment-api-emulator/internal/currency"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/apierror"
 	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
 )
 
@@ -13,16 +16,22 @@ import (
 // будет использовать для взаимодействия с вариантом использования.
 // @property logger - Это регистратор, который будет использоваться для регистрации ошибок и другой
 // информации.
+// @property {CurrencyRegistry} currencies - Это реестр валют, в которых принимаются платежи.
+// @property {RateConverter} rates - Это таблица курсов для пересчета в валюту расчетов.
 type controller struct {
-	UseCase PaymentUseCase
-	logger  loggin.ILogger
+	UseCase    PaymentUseCase
+	logger     loggin.ILogger
+	currencies CurrencyRegistry
+	rates      RateConverter
 }
 
 // > Эта функция создает новый экземпляр структуры контроллера и возвращает указатель на него
-func NewPaymentController(l loggin.ILogger, u PaymentUseCase) *controller {
+func NewPaymentController(l loggin.ILogger, u PaymentUseCase, currencies CurrencyRegistry, rates RateConverter) *controller {
 	return &controller{
-		logger:  l,
-		UseCase: u,
+		logger:     l,
+		UseCase:    u,
+		currencies: currencies,
+		rates:      rates,
 	}
 }
 
@@ -31,20 +40,33 @@ const (
 	CreatePayment          = "/payment"
 	UpdateStatusByID       = "/payments/{id}/status"
 	GetStatusByID          = "/payments/{id}/status"
+	GetPaymentByID         = "/payments/{id}"
 	GetPaymentsByUserEmail = "/payments/user" // query /payments/user?email=email
 	GetPaymentsByUserID    = "/payments/user/{id}"
 	CancelPaymentByID      = "/payments/{id}"
+	CreateRefundByID       = "/payments/{id}/refunds"
+	GetRefundsByID         = "/payments/{id}/refunds"
+	GetHistoryByID         = "/payments/{id}/history"
+	CapturePaymentByID     = "/payments/{id}/capture"
+	VoidPaymentByID        = "/payments/{id}/void"
 )
 
 // Эта функция представляет собой обработчик, который будет вызываться при запросе маршрута.
 // // метод `/платежа` `POST`.
 func (c *controller) Register(router *mux.Router) *mux.Router {
-	router.HandleFunc(CreatePayment, c.CreatePayment).Methods(http.MethodPost)
-	router.HandleFunc(UpdateStatusByID, c.UpdateStatus).Methods(http.MethodPut)
-	router.HandleFunc(GetStatusByID, c.GetStatus).Methods(http.MethodGet)
-	router.HandleFunc(GetPaymentsByUserEmail, c.GetPaymentsByUserEmail).Methods(http.MethodGet)
-	router.HandleFunc(GetPaymentsByUserID, c.GetPaymentsByUserID).Methods(http.MethodGet)
-	router.HandleFunc(CancelPaymentByID, c.CancelPayment).Methods(http.MethodPut)
+	router.Use(actor)
+	router.HandleFunc(CreatePayment, c.idempotent(c.CreatePayment)).Methods(http.MethodPost).Name("CreatePayment")
+	router.HandleFunc(UpdateStatusByID, c.UpdateStatus).Methods(http.MethodPut).Name("UpdateStatusByID")
+	router.HandleFunc(GetStatusByID, c.GetStatus).Methods(http.MethodGet).Name("GetStatusByID")
+	router.HandleFunc(GetPaymentsByUserEmail, c.GetPaymentsByUserEmail).Methods(http.MethodGet).Name("GetPaymentsByUserEmail")
+	router.HandleFunc(GetPaymentsByUserID, c.GetPaymentsByUserID).Methods(http.MethodGet).Name("GetPaymentsByUserID")
+	router.HandleFunc(GetPaymentByID, c.GetPayment).Methods(http.MethodGet).Name("GetPaymentByID")
+	router.HandleFunc(CancelPaymentByID, c.CancelPayment).Methods(http.MethodPut).Name("CancelPaymentByID")
+	router.HandleFunc(CreateRefundByID, c.CreateRefund).Methods(http.MethodPost).Name("CreateRefundByID")
+	router.HandleFunc(GetRefundsByID, c.GetRefunds).Methods(http.MethodGet).Name("GetRefundsByID")
+	router.HandleFunc(GetHistoryByID, c.GetHistory).Methods(http.MethodGet).Name("GetHistoryByID")
+	router.HandleFunc(CapturePaymentByID, c.idempotent(c.CapturePayment)).Methods(http.MethodPost).Name("CapturePaymentByID")
+	router.HandleFunc(VoidPaymentByID, c.VoidPayment).Methods(http.MethodPost).Name("VoidPaymentByID")
 	return router
 }
 
@@ -55,14 +77,57 @@ func (c *controller) CreatePayment(w http.ResponseWriter, r *http.Request) {
 	var input PaymentInput
 
 	// Это проверка правильности данных в теле запроса.
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, InvalidBodyData, http.StatusBadRequest)
+	if err := decodeJSON(w, r, &input, false); err != nil {
+		c.writeError(w, r, err)
 		return
 	}
 
-	// Это проверка правильности адреса электронной почты в теле запроса.
-	if ok := isEmail(input.UserEmail); !ok {
-		http.Error(w, InvalidBodyEmail, http.StatusBadRequest)
+	// Это проверка полей тела запроса по тегам validate: пользователь, сумма, адрес электронной почты и
+	// валюта.
+	if err := validateStruct(input); err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	// Это проверка валюты по реестру: валюта известна и включена.
+	cur, err := c.currencies.Lookup(input.Currency)
+	if errors.Is(err, currency.ErrCurrencyDisabled) {
+		c.writeError(w, r, errCurrencyDisabled)
+		return
+	}
+
+	if err != nil {
+		c.writeError(w, r, invalidField("currency", InvalidBodyCurrency))
+		return
+	}
+
+	// Это проверка суммы: знаков после точки не больше, чем у валюты.
+	money, err := NewMoney(input.Amount, cur)
+	if err != nil {
+		c.writeError(w, r, invalidField("amount", InvalidBodyAmount))
+		return
+	}
+
+	input.Currency = money.Currency.Code
+
+	// Это пересчет суммы в валюту расчетов, по умолчанию - в валюту платежа.
+	settlementCurrency := cur
+	if input.SettlementCurrency != "" {
+		settlementCurrency, err = c.currencies.Lookup(input.SettlementCurrency)
+		if err != nil {
+			c.writeError(w, r, invalidField("settlement_currency", InvalidBodySettlementCurrency))
+			return
+		}
+	}
+
+	input.Settlement, err = c.rates.Convert(money, settlementCurrency)
+	if errors.Is(err, ErrRateNotFound) {
+		c.writeError(w, r, err)
+		return
+	}
+
+	if err != nil {
+		c.writeError(w, r, invalidField("amount", InvalidBodyAmount))
 		return
 	}
 
@@ -72,8 +137,7 @@ func (c *controller) CreatePayment(w http.ResponseWriter, r *http.Request) {
 		input,
 	)
 	if err != nil {
-		c.logger.Error(err)
-		http.Error(w, InternalServerError, http.StatusInternalServerError)
+		c.writeError(w, r, err)
 		return
 	}
 
@@ -89,21 +153,33 @@ func (c *controller) CreatePayment(w http.ResponseWriter, r *http.Request) {
 
 // Эта функция представляет собой обработчик, который будет вызываться при выполнении запроса к
 // маршруту.
-// // `/payments/{id}/status` методом `PUT`.
+// // `/payments/{id}/status` методом `PUT`. Заголовок If-Match с устаревшей версией платежа дает 412.
 func (c *controller) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 	PaymentID, err := GetQueryId(r)
 	if err != nil {
-		http.Error(w, InvalidQueryID, http.StatusBadRequest)
+		c.writeError(w, r, errInvalidID)
 		return
 	}
 
 	var input PaymentStatus
-	if err = json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, InvalidBodyData, http.StatusBadRequest)
+	if err = decodeJSON(w, r, &input, false); err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	if err = validateStruct(input); err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	version, err := parseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		c.writeError(w, r, err)
 		return
 	}
 
 	input.ID = PaymentID
+	input.Version = version
 
 	err = c.UseCase.UpdateStatus(
 		r.Context(),
@@ -111,21 +187,54 @@ func (c *controller) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 	)
 
 	if err != nil {
-		c.logger.Error(err)
-		http.Error(w, InternalServerError, http.StatusInternalServerError)
+		c.writeError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
+// Эта функция представляет собой обработчик, который будет вызываться при выполнении запроса к
+// маршруту.
+// // `/payments/{id}` методом `GET`. Ответ содержит ETag с версией платежа, а запрос с совпадающим If-None-Match получает
+// 304 без тела.
+func (c *controller) GetPayment(w http.ResponseWriter, r *http.Request) {
+	PaymentID, err := GetQueryId(r)
+	if err != nil {
+		c.writeError(w, r, errInvalidID)
+		return
+	}
+
+	value, err := c.UseCase.GetPayment(
+		r.Context(),
+		PaymentID,
+	)
+	if err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	etag := paymentETag(value)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if match := r.Header.Get("If-None-Match"); match != "" && matchETag(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(value)
+}
+
 // Эта функция представляет собой обработчик, который будет вызываться при выполнении запроса к
 // маршруту.
 // // `/payments/{id}/status` методом `GET`.
 func (c *controller) GetStatus(w http.ResponseWriter, r *http.Request) {
 	PaymentID, err := GetQueryId(r)
 	if err != nil {
-		http.Error(w, InvalidQueryID, http.StatusBadRequest)
+		c.writeError(w, r, errInvalidID)
 		return
 	}
 
@@ -134,8 +243,7 @@ func (c *controller) GetStatus(w http.ResponseWriter, r *http.Request) {
 		PaymentID,
 	)
 	if err != nil {
-		c.logger.Error(err)
-		http.Error(w, InternalServerError, http.StatusInternalServerError)
+		c.writeError(w, r, err)
 		return
 	}
 
@@ -153,30 +261,34 @@ func (c *controller) GetStatus(w http.ResponseWriter, r *http.Request) {
 // // `/payments/user` методом `GET`.
 func (c *controller) GetPaymentsByUserEmail(w http.ResponseWriter, r *http.Request) {
 	UserEmail := r.URL.Query().Get("email")
-	if ok := isEmail(UserEmail); !ok {
-		http.Error(w, InvalidQueryEmail, http.StatusBadRequest)
+	if err := validate.Var("email", UserEmail, "required,email"); err != nil {
+		c.writeError(w, r, validationError(err))
 		return
 	}
 
-	data, err := c.UseCase.GetPayments(
-		r.Context(),
+	input, err := parsePaymentUser(
+		r.URL.Query(),
 		PaymentUser{
 			UserEmail: UserEmail,
 		},
 	)
 	if err != nil {
-		c.logger.Error(err)
-		http.Error(w, InternalServerError, http.StatusInternalServerError)
+		c.writeError(w, r, err)
+		return
+	}
+
+	data, err := c.UseCase.GetPayments(
+		r.Context(),
+		input,
+	)
+	if err != nil {
+		c.writeError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(
-		PaymentsData{
-			Data: data,
-		},
-	)
+	json.NewEncoder(w).Encode(data)
 }
 
 // Эта функция представляет собой обработчик, который будет вызываться при выполнении запроса к
@@ -185,51 +297,316 @@ func (c *controller) GetPaymentsByUserEmail(w http.ResponseWriter, r *http.Reque
 func (c *controller) GetPaymentsByUserID(w http.ResponseWriter, r *http.Request) {
 	userID, err := GetQueryId(r)
 	if err != nil {
-		http.Error(w, InvalidQueryID, http.StatusBadRequest)
+		c.writeError(w, r, errInvalidID)
 		return
 	}
 
-	data, err := c.UseCase.GetPayments(
-		r.Context(),
+	input, err := parsePaymentUser(
+		r.URL.Query(),
 		PaymentUser{
 			UserID: userID,
 		},
 	)
 	if err != nil {
-		c.logger.Error(err)
-		http.Error(w, InternalServerError, http.StatusInternalServerError)
+		c.writeError(w, r, err)
+		return
+	}
+
+	data, err := c.UseCase.GetPayments(
+		r.Context(),
+		input,
+	)
+	if err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/payments/{id}` методом `PUT`. Заголовок If-Match с устаревшей версией платежа дает 412.
+func (c *controller) CancelPayment(w http.ResponseWriter, r *http.Request) {
+	PaymentID, err := GetQueryId(r)
+	if err != nil {
+		c.writeError(w, r, errInvalidID)
+		return
+	}
+
+	// Тело запроса необязательно: в нем можно передать причину отмены.
+	var input PaymentStatus
+	if err = decodeJSON(w, r, &input, true); err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	version, err := parseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	err = c.UseCase.CancelPayment(
+		r.Context(),
+		PaymentStatus{
+			ID:      PaymentID,
+			Reason:  input.Reason,
+			Version: version,
+		},
+	)
+
+	if err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/payments/{id}/capture` методом `POST`. Заголовок If-Match с устаревшей версией платежа дает 412.
+func (c *controller) CapturePayment(w http.ResponseWriter, r *http.Request) {
+	PaymentID, err := GetQueryId(r)
+	if err != nil {
+		c.writeError(w, r, errInvalidID)
+		return
+	}
+
+	// Тело запроса необязательно: без суммы списывается вся авторизованная сумма.
+	var input CaptureInput
+	if err = decodeJSON(w, r, &input, true); err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	if err = validateStruct(input); err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	version, err := parseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	input.PaymentID = PaymentID
+	input.Version = version
+
+	data, err := c.UseCase.CapturePayment(
+		r.Context(),
+		input,
+	)
+	if err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/payments/{id}/void` методом `POST`. Заголовок If-Match с устаревшей версией платежа дает 412.
+func (c *controller) VoidPayment(w http.ResponseWriter, r *http.Request) {
+	PaymentID, err := GetQueryId(r)
+	if err != nil {
+		c.writeError(w, r, errInvalidID)
+		return
+	}
+
+	// Тело запроса необязательно: в нем можно передать причину отмены авторизации.
+	var input PaymentStatus
+	if err = decodeJSON(w, r, &input, true); err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	version, err := parseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	err = c.UseCase.VoidPayment(
+		r.Context(),
+		PaymentStatus{
+			ID:      PaymentID,
+			Reason:  input.Reason,
+			Version: version,
+		},
+	)
+	if err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/payments/{id}/refunds` методом `POST`.
+func (c *controller) CreateRefund(w http.ResponseWriter, r *http.Request) {
+	PaymentID, err := GetQueryId(r)
+	if err != nil {
+		c.writeError(w, r, errInvalidID)
+		return
+	}
+
+	// Тело запроса необязательно: без суммы возвращается весь остаток платежа.
+	var input RefundInput
+	if err = decodeJSON(w, r, &input, true); err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	if err = validateStruct(input); err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	input.PaymentID = PaymentID
+
+	data, err := c.UseCase.CreateRefund(
+		r.Context(),
+		input,
+	)
+	if err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(data)
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/payments/{id}/refunds` методом `GET`.
+func (c *controller) GetRefunds(w http.ResponseWriter, r *http.Request) {
+	PaymentID, err := GetQueryId(r)
+	if err != nil {
+		c.writeError(w, r, errInvalidID)
+		return
+	}
+
+	data, err := c.UseCase.GetRefunds(
+		r.Context(),
+		PaymentID,
+	)
+	if err != nil {
+		c.writeError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(
-		PaymentsData{
+		RefundsData{
 			Data: data,
 		},
 	)
 }
 
 // Обработчик, который будет вызываться при запросе маршрута
-// // `/payments/{id}` методом `PUT`.
-func (c *controller) CancelPayment(w http.ResponseWriter, r *http.Request) {
+// // `/payments/{id}/history` методом `GET`.
+func (c *controller) GetHistory(w http.ResponseWriter, r *http.Request) {
 	PaymentID, err := GetQueryId(r)
 	if err != nil {
-		http.Error(w, InvalidQueryID, http.StatusBadRequest)
+		c.writeError(w, r, errInvalidID)
 		return
 	}
 
-	err = c.UseCase.CancelPayment(
+	data, err := c.UseCase.GetHistory(
 		r.Context(),
 		PaymentID,
 	)
-
 	if err != nil {
-		c.logger.Error(err)
-		http.Error(w, InternalServerError, http.StatusInternalServerError)
+		c.writeError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(
+		HistoryData{
+			Data: data,
+		},
+	)
+}
+
+// Отвечает клиенту на ошибку в формате JSON с кодом, описанием, подробностями и идентификатором
+// запроса. HTTP-код выбирается по виду ошибки, запрещенный переход между статусами возвращается с
+// кодом 409 и текущим и запрошенным статусом, а неизвестная ошибка записывается в журнал и
+// возвращается как 500.
+func (c *controller) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var transitionErr *TransitionError
+	var paymentErr *Error
+
+	switch {
+	case errors.As(err, &transitionErr):
+		code := CodeInvalidStatusTransition
+		if errors.Is(transitionErr, ErrTerminalStatus) {
+			code = CodeTerminalStatus
+		}
+
+		apierror.Write(w, r, http.StatusConflict, apierror.Body{
+			Code:    code,
+			Message: InvalidStatusTransition,
+			Details: map[string]interface{}{
+				"current_status":   transitionErr.Current,
+				"requested_status": transitionErr.Requested,
+			},
+		})
+	case errors.As(err, &paymentErr):
+		apierror.Write(w, r, statusCode(paymentErr.Kind), apierror.Body{
+			Code:    paymentErr.Code,
+			Message: paymentErr.Message,
+			Details: paymentErr.Details,
+		})
+	default:
+		c.logger.WithContext(r.Context()).Error(err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Body{
+			Code:    CodeInternalError,
+			Message: InternalServerError,
+		})
+	}
+}
+
+// Возвращает HTTP-код ответа для вида ошибки.
+func statusCode(kind error) int {
+	switch kind {
+	case ErrNotFound:
+		return http.StatusNotFound
+	case ErrTerminalStatus, ErrConflict:
+		return http.StatusConflict
+	case ErrValidation:
+		return http.StatusUnprocessableEntity
+	case ErrBadRequest:
+		return http.StatusBadRequest
+	case ErrPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case ErrPayloadTooLarge:
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Промежуточный обработчик, который записывает в контекст запроса исполнителя изменений платежа из
+// заголовка X-Actor или ActorAPI, если заголовок не передан.
+func actor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.Header.Get(ActorHeader)
+		if name == "" {
+			name = ActorAPI
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithActor(r.Context(), name)))
+	})
 }
@@ -0,0 +1,416 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/onlycodergod/payment-api-emulator/internal/currency"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/apierror"
+	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет, что платеж отдается целиком с ETag, повторный запрос с тем же тегом получает 304, а
+// после изменения платежа тег меняется.
+func TestControllerGetPayment(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	repo := NewMemoryPaymentRepository()
+	repo.now = func() time.Time { return now }
+
+	usc := NewPaymentUseCase(repo, Options{})
+	router := NewPaymentController(loggin.NewLogger(false), usc, nil, nil).Register(mux.NewRouter())
+
+	ctx := context.TODO()
+
+	id, err := repo.CreatePayment(ctx, PaymentInput{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("10.5"), Currency: "usd"})
+	assert.NoError(t, err)
+
+	get := func(match string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/payments/1", nil)
+		if match != "" {
+			r.Header.Set("If-None-Match", match)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		return w
+	}
+
+	w := get("")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var got payment
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, id, got.ID)
+	assert.Equal(t, mustDecimal("10.5"), got.Amount)
+	assert.Equal(t, "user@mail.ru", got.UserEmail)
+	assert.Equal(t, StatusNew, got.Status)
+
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	w = get(etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	w = get(`"other", W/` + etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	assert.NoError(t, usc.UpdateStatus(ctx, PaymentStatus{ID: id, Status: StatusSuccess}))
+
+	w = get(etag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+
+	r := httptest.NewRequest(http.MethodGet, "/payments/100", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// Он проверяет, что изменение статуса с устаревшим If-Match получает 412, а из двух операторов с
+// одной версией платежа статус меняет только один.
+func TestControllerIfMatch(t *testing.T) {
+	t.Parallel()
+
+	repo := NewMemoryPaymentRepository()
+	usc := NewPaymentUseCase(repo, Options{})
+	router := NewPaymentController(loggin.NewLogger(false), usc, nil, nil).Register(mux.NewRouter())
+
+	ctx := context.TODO()
+
+	for i := 0; i < 2; i++ {
+		_, err := repo.CreatePayment(ctx, PaymentInput{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("10.5"), Currency: "usd"})
+		assert.NoError(t, err)
+	}
+
+	send := func(method, target, body, match string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		if match != "" {
+			r.Header.Set("If-Match", match)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		return w
+	}
+
+	w := send(http.MethodGet, "/payments/1", "", "")
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, `"1"`, etag)
+
+	w = send(http.MethodPut, "/payments/1/status", `{"status": "authorized"}`, etag)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		match  string
+		status int
+	}{
+		{name: "Stale capture", method: http.MethodPost, target: "/payments/1/capture", match: etag, status: http.StatusPreconditionFailed},
+		{name: "Stale void", method: http.MethodPost, target: "/payments/1/void", match: etag, status: http.StatusPreconditionFailed},
+		{name: "Stale status update", method: http.MethodPut, target: "/payments/1/status", body: `{"status": "success"}`, match: etag, status: http.StatusPreconditionFailed},
+		{name: "Stale cancel", method: http.MethodPut, target: "/payments/1", match: etag, status: http.StatusPreconditionFailed},
+		{name: "Weak tag", method: http.MethodPut, target: "/payments/1/status", body: `{"status": "success"}`, match: `W/"2"`, status: http.StatusPreconditionFailed},
+		{name: "Malformed tag", method: http.MethodPut, target: "/payments/1", match: "2", status: http.StatusPreconditionFailed},
+		{name: "Any version", method: http.MethodPut, target: "/payments/1", match: "*", status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		w := send(tt.method, tt.target, tt.body, tt.match)
+		assert.Equal(t, tt.status, w.Code, tt.name)
+
+		if tt.status == http.StatusPreconditionFailed {
+			assert.Contains(t, w.Body.String(), CodeVersionMismatch, tt.name)
+		}
+	}
+
+	status, err := usc.GetStatus(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCanceled, status)
+
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		got []int
+	)
+
+	for _, to := range []string{StatusSuccess, StatusFailure} {
+		wg.Add(1)
+		go func(to string) {
+			defer wg.Done()
+
+			w := send(http.MethodPut, "/payments/2/status", `{"status": "`+to+`"}`, `"1"`)
+
+			mu.Lock()
+			got = append(got, w.Code)
+			mu.Unlock()
+		}(to)
+	}
+	wg.Wait()
+
+	assert.ElementsMatch(t, []int{http.StatusOK, http.StatusPreconditionFailed}, got)
+
+	history, err := usc.GetHistory(ctx, 2)
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+}
+
+// Он проверяет, что некорректное тело и параметры запроса получают ошибку с полями, а неизвестные поля
+// и слишком большое тело отклоняются до обращения к платежам.
+func TestControllerValidation(t *testing.T) {
+	t.Parallel()
+
+	repo := NewMemoryPaymentRepository()
+	usc := NewPaymentUseCase(repo, Options{})
+	router := NewPaymentController(loggin.NewLogger(false), usc, nil, nil).Register(mux.NewRouter())
+
+	_, err := repo.CreatePayment(context.TODO(), PaymentInput{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("10.5"), Currency: "usd"})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		status  int
+		code    string
+		message string
+		fields  []string
+	}{
+		{
+			name:    "Payment fields",
+			method:  http.MethodPost,
+			target:  "/payment",
+			body:    `{"user_id": 0, "amount": "-1", "user_email": "user", "currency": ""}`,
+			status:  http.StatusUnprocessableEntity,
+			code:    CodeInvalidField,
+			message: "user_id: must be > 0; amount: must be > 0; user_email: must be a valid email; currency: is required",
+			fields:  []string{"user_id", "amount", "user_email", "currency"},
+		},
+		{
+			name:    "Field of a wrong type",
+			method:  http.MethodPost,
+			target:  "/payment",
+			body:    `{"user_id": "1", "amount": 10, "user_email": "user@mail.ru", "currency": "usd"}`,
+			status:  http.StatusUnprocessableEntity,
+			code:    CodeInvalidField,
+			message: "user_id: must be an integer",
+			fields:  []string{"user_id"},
+		},
+		{
+			name:    "Unknown field",
+			method:  http.MethodPost,
+			target:  "/payment",
+			body:    `{"user_id": 1, "amount": 10, "user_email": "user@mail.ru", "currency": "usd", "amout": 10}`,
+			status:  http.StatusBadRequest,
+			code:    CodeUnknownField,
+			message: "unknown field amout",
+		},
+		{
+			name:   "Trailing data",
+			method: http.MethodPost,
+			target: "/payment",
+			body:   `{"user_id": 1} {}`,
+			status: http.StatusBadRequest,
+			code:   CodeInvalidBody,
+		},
+		{
+			name:   "Body too large",
+			method: http.MethodPost,
+			target: "/payment",
+			body:   `{"user_email": "` + strings.Repeat("a", MaxBodySize) + `"}`,
+			status: http.StatusRequestEntityTooLarge,
+			code:   CodeBodyTooLarge,
+		},
+		{
+			name:    "Unknown status",
+			method:  http.MethodPut,
+			target:  "/payments/1/status",
+			body:    `{"status": "done"}`,
+			status:  http.StatusUnprocessableEntity,
+			code:    CodeInvalidField,
+			message: "status: unknown value",
+			fields:  []string{"status"},
+		},
+		{
+			name:    "Negative refund",
+			method:  http.MethodPost,
+			target:  "/payments/1/refunds",
+			body:    `{"amount": "-1"}`,
+			status:  http.StatusUnprocessableEntity,
+			code:    CodeInvalidField,
+			message: "amount: must be >= 0",
+			fields:  []string{"amount"},
+		},
+		{
+			name:    "Query parameters",
+			method:  http.MethodGet,
+			target:  "/payments/user?email=user&limit=0",
+			status:  http.StatusUnprocessableEntity,
+			code:    CodeInvalidField,
+			message: "email: must be a valid email",
+			fields:  []string{"email"},
+		},
+		{
+			name:    "Several query parameters",
+			method:  http.MethodGet,
+			target:  "/payments/user/1?limit=0&sort=amount",
+			status:  http.StatusUnprocessableEntity,
+			code:    CodeInvalidField,
+			message: "limit: must be at least 1; sort: must be one of: asc, desc",
+			fields:  []string{"limit", "sort"},
+		},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		var got apierror.Body
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&got), tt.name)
+
+		assert.Equal(t, tt.status, w.Code, tt.name)
+		assert.Equal(t, tt.code, got.Code, tt.name)
+
+		if tt.message != "" {
+			assert.Equal(t, tt.message, got.Message, tt.name)
+		}
+
+		if tt.fields != nil {
+			var fields []string
+			for _, value := range got.Details["errors"].([]interface{}) {
+				fields = append(fields, value.(map[string]interface{})["field"].(string))
+			}
+
+			assert.Equal(t, tt.fields, fields, tt.name)
+			assert.Equal(t, tt.fields[0], got.Details["field"], tt.name)
+		}
+	}
+
+	status, err := usc.GetStatus(context.TODO(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusNew, status)
+}
+
+// findCurrencies — это реестр валют для тестов, в котором включены все известные валюты.
+type findCurrencies struct{}
+
+// Возвращает известную валюту по коду.
+func (findCurrencies) Lookup(code string) (currency.Currency, error) {
+	value, ok := currency.Find(code)
+	if !ok {
+		return currency.Currency{}, currency.ErrCurrencyNotFound
+	}
+
+	return value, nil
+}
+
+// Он проверяет, что из одновременных запросов с одним Idempotency-Key платеж создает только один, пока
+// запрос выполняется, повтор получает 409, а ответы 4xx, как и 201, возвращаются повторно.
+func TestControllerIdempotency(t *testing.T) {
+	t.Parallel()
+
+	repo := NewMemoryPaymentRepository()
+	usc := NewPaymentUseCase(repo, Options{IdempotencyTTL: time.Hour})
+
+	rates, err := NewRateTable(RateOptions{})
+	assert.NoError(t, err)
+
+	router := NewPaymentController(loggin.NewLogger(false), usc, findCurrencies{}, rates).Register(mux.NewRouter())
+
+	const body = `{"user_id": 1, "amount": "10.5", "user_email": "user@mail.ru", "currency": "usd"}`
+
+	post := func(key, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/payment", strings.NewReader(body))
+		r.Header.Set(IdempotencyKeyHeader, key)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		return w
+	}
+
+	code := func(w *httptest.ResponseRecorder) string {
+		var output apierror.Body
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&output))
+
+		return output.Code
+	}
+
+	// Одновременные запросы с одним ключом.
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			w := post("concurrent", body)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case w.Code == http.StatusCreated && w.Header().Get(IdempotencyReplayedHeader) == "":
+				created++
+			case w.Code == http.StatusCreated:
+			default:
+				assert.Equal(t, http.StatusConflict, w.Code)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, created)
+
+	data, err := repo.GetPayments(context.TODO(), PaymentUser{UserID: 1})
+	assert.NoError(t, err)
+	assert.Len(t, data, 1)
+
+	// Ключ зарезервирован выполняющимся запросом.
+	reserved, err := usc.ReserveIdempotencyKey(context.TODO(), IdempotencyKey{
+		Key:         "in-progress",
+		Fingerprint: fingerprint(httptest.NewRequest(http.MethodPost, "/payment", nil), []byte(body)),
+	})
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+
+	w := post("in-progress", body)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, CodeIdempotencyKeyInProgress, code(w))
+
+	w = post("in-progress", `{"user_id": 2}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, CodeIdempotencyKeyReused, code(w))
+
+	// Ответ 4xx сохраняется и возвращается повторно.
+	invalid := `{"user_id": 0, "amount": "10.5", "user_email": "user@mail.ru", "currency": "usd"}`
+
+	first := post("invalid", invalid)
+	assert.Equal(t, http.StatusUnprocessableEntity, first.Code)
+
+	second := post("invalid", invalid)
+	assert.Equal(t, http.StatusUnprocessableEntity, second.Code)
+	assert.Equal(t, "true", second.Header().Get(IdempotencyReplayedHeader))
+	assert.Equal(t, first.Body.String(), second.Body.String())
+}
@@ -1,5 +1,7 @@
 package payment
 
+import "time"
+
 // Это структура, содержащая поля, используемые для представления платежа.
 //
 // Поля снабжены тегами, которые используются пакетом sqlx для сопоставления полей со столбцами в базе
@@ -14,60 +16,233 @@ package payment
 // Поля также аннотируются тегами, которые используются в go-swagger.
 // @property {int64} ID - Уникальный идентификатор платежа.
 // @property {int64} UserID - ID пользователя, совершившего платеж.
-// @property {float64} Amount - Сумма платежа.
+// @property {Decimal} Amount - Сумма платежа.
 // @property {string} UserEmail - Электронная почта пользователя, совершившего платеж
 // @property {string} Currency - Валюта платежа.
 // @property {string} CreatedAt - Дата и время создания платежа.
 // @property {string} UpdatedAt - Дата и время последнего обновления платежа.
 // @property {string} Status - Статус платежа. Он может быть «ожидающим», «завершенным» или
 // «неудачным».
+// @property {Decimal} RefundedAmount - Сумма успешных возвратов по платежу.
+// @property {string} RefundStatus - Производное от RefundedAmount состояние возврата:
+// «partially_refunded» или «refunded».
+// @property {string} SettlementCurrency - Валюта расчетов с мерчантом.
+// @property {Decimal} SettlementAmount - Сумма в валюте расчетов.
+// @property {Decimal} FXRate - Курс, по которому сумма пересчитана в валюту расчетов.
+// @property {string} FXSource - Источник курса: «identity», «file», «admin» или «drift».
+// @property {string} FXRatedAt - Дата и время получения курса.
+// @property {Decimal} CapturedAmount - Списанная сумма. Для платежа, переведенного в «success» без
+// авторизации, равна сумме платежа.
+// @property {string} AuthorizedAt - Дата и время авторизации, пустое для неавторизованного платежа.
+// @property {int64} Version - Версия платежа, увеличивается при каждом изменении и отдается как ETag.
 type payment struct {
-	ID        int64   `json:"id" db:"id"`
-	UserID    int64   `json:"user_id" db:"user_id"`
-	Amount    float64 `json:"amount" db:"amount"`
-	UserEmail string  `json:"user_email" db:"user_email"`
-	Currency  string  `json:"currency" db:"currency"`
-	CreatedAt string  `json:"created_at" db:"created_at"`
-	UpdatedAt string  `json:"updated_at" db:"updated_at"`
-	Status    string  `json:"status" db:"status"`
+	ID                 int64   `json:"id" db:"id"`
+	UserID             int64   `json:"user_id" db:"user_id"`
+	Amount             Decimal `json:"amount" db:"amount"`
+	UserEmail          string  `json:"user_email" db:"user_email"`
+	Currency           string  `json:"currency" db:"currency"`
+	CreatedAt          string  `json:"created_at" db:"created_at"`
+	UpdatedAt          string  `json:"updated_at" db:"updated_at"`
+	Status             string  `json:"status" db:"status"`
+	RefundedAmount     Decimal `json:"refunded_amount" db:"refunded_amount"`
+	RefundStatus       string  `json:"refund_status,omitempty" db:"-"`
+	SettlementCurrency string  `json:"settlement_currency" db:"settlement_currency"`
+	SettlementAmount   Decimal `json:"settlement_amount" db:"settlement_amount"`
+	FXRate             Decimal `json:"fx_rate" db:"fx_rate"`
+	FXSource           string  `json:"fx_source" db:"fx_source"`
+	FXRatedAt          string  `json:"fx_rated_at" db:"fx_rated_at"`
+	CapturedAmount     Decimal `json:"captured_amount" db:"captured_amount"`
+	AuthorizedAt       string  `json:"authorized_at,omitempty" db:"authorized_at"`
+	Version            int64   `json:"version" db:"version"`
+}
+
+// Вычисляет RefundStatus по списанной сумме и сумме возвратов.
+func (p *payment) deriveRefundStatus() {
+	switch {
+	case p.RefundedAmount.IsZero():
+		p.RefundStatus = ""
+	case p.RefundedAmount.Cmp(p.CapturedAmount) < 0:
+		p.RefundStatus = RefundStatePartiallyRefunded
+	default:
+		p.RefundStatus = RefundStateRefunded
+	}
 }
 
 // «PaymentInput» — это структура с четырьмя полями: «UserID», «Amount», «UserEmail» и «Currency».
 //
 // Первое поле, `UserID`, представляет собой `int64` (64-битное целое число). Второе поле, «Сумма»,
-// представляет собой «float64» (64-битное число с плавающей запятой). Третье поле, `UserEmail`,
+// представляет собой «Decimal» (точное десятичное число). Третье поле, `UserEmail`,
 // представляет собой `строку` (строку символов). Четвертое поле «Валюта» также является строкой.
 //
-// Теги `json` в каждом поле сообщают компилятору Go
+// # Теги `json` в каждом поле сообщают компилятору Go
+//
+// Теги `validate` задают правила проверки тела запроса пакетом validator.
 // @property {int64} UserID - Идентификатор пользователя, который осуществляет платеж.
-// @property {float64} Amount - Сумма к оплате.
+// @property {Decimal} Amount - Сумма к оплате.
 // @property {string} UserEmail - Адрес электронной почты пользователя, который осуществляет платеж.
 // @property {string} Currency - Валюта платежа.
+// @property {string} SettlementCurrency - Валюта расчетов с мерчантом. По умолчанию совпадает с валютой
+// платежа.
+// @property {Settlement} Settlement - Расчет суммы в валюте расчетов, заполняется контроллером.
 type PaymentInput struct {
-	UserID    int64   `json:"user_id"`
-	Amount    float64 `json:"amount"`
-	UserEmail string  `json:"user_email"`
-	Currency  string  `json:"currency"`
+	UserID             int64      `json:"user_id" validate:"gt=0"`
+	Amount             Decimal    `json:"amount" validate:"gt=0"`
+	UserEmail          string     `json:"user_email" validate:"required,max=20,email"`
+	Currency           string     `json:"currency" validate:"required"`
+	SettlementCurrency string     `json:"settlement_currency,omitempty"`
+	Settlement         Settlement `json:"-"`
 }
 
-// PaymentUser — это структура, содержащая идентификатор пользователя и адрес электронной почты.
+// Возвращает расчет платежа. Платеж без расчета рассчитывается в своей валюте по курсу 1.
+func (input PaymentInput) settlement(now time.Time) Settlement {
+	if input.Settlement.Currency != "" {
+		return input.Settlement
+	}
+
+	return Settlement{
+		Currency: input.Currency,
+		Amount:   input.Amount,
+		Rate:     NewDecimal(1, 0),
+		Source:   RateSourceIdentity,
+		RatedAt:  now,
+	}
+}
+
+// PaymentUser — это структура, содержащая идентификатор пользователя или адрес электронной почты и
+// параметры страницы списка его платежей.
 // @property {int64} UserID - ID пользователя в вашей системе.
 // @property {string} UserEmail - Электронный адрес пользователя.
+// @property {int} Limit - Размер страницы.
+// @property {int64} AfterID - ID последнего платежа предыдущей страницы из курсора.
+// @property {string} Sort - Порядок сортировки по ID: «asc» или «desc».
+// @property {string} Status - Фильтр по статусу.
+// @property {string} Currency - Фильтр по валюте.
+// @property {Decimal} MinAmount - Минимальная сумма включительно.
+// @property {Decimal} MaxAmount - Максимальная сумма включительно.
+// @property {time.Time} CreatedFrom - Начало периода создания включительно.
+// @property {time.Time} CreatedTo - Конец периода создания, не включая его.
 type PaymentUser struct {
-	UserID    int64  `json:"user_id"`
-	UserEmail string `json:"user_email"`
+	UserID      int64     `json:"user_id"`
+	UserEmail   string    `json:"user_email"`
+	Limit       int       `json:"limit,omitempty" validate:"min=1,max=500"`
+	AfterID     int64     `json:"-"`
+	Sort        string    `json:"sort,omitempty" validate:"omitempty,oneof=asc desc"`
+	Status      string    `json:"status,omitempty" validate:"omitempty,status"`
+	Currency    string    `json:"currency,omitempty"`
+	MinAmount   Decimal   `json:"min_amount,omitempty" validate:"gte=0"`
+	MaxAmount   Decimal   `json:"max_amount,omitempty" validate:"gte=0"`
+	CreatedFrom time.Time `json:"created_from,omitempty"`
+	CreatedTo   time.Time `json:"created_to,omitempty"`
 }
 
 // PaymentsData — это структура, содержащая фрагмент платежных структур.
 // @property {[]payment} Data - Это массив платежей, которые мы будем возвращать.
+// @property {string} NextCursor - Курсор следующей страницы. Пустой, если страница последняя.
 type PaymentsData struct {
-	Data []payment `json:"data"`
+	Data       []payment `json:"data"`
+	NextCursor string    `json:"next_cursor,omitempty"`
 }
 
 // PaymentStatus — это структура, содержащая идентификатор и статус.
 // @property {int64} ID - Идентификатор платежа.
 // @property {string} Status - Статус платежа. Возможные значения:
+// @property {string} Reason - Необязательная причина смены статуса, сохраняется в истории.
+// @property {int64} Version - Ожидаемая версия платежа из заголовка If-Match. Ноль означает, что версия
+// не проверяется.
 type PaymentStatus struct {
-	ID     int64  `json:"id,omitempty"`
-	Status string `json:"status,omitempty"`
+	ID      int64  `json:"id,omitempty"`
+	Status  string `json:"status,omitempty" validate:"required,status"`
+	Reason  string `json:"reason,omitempty"`
+	Version int64  `json:"-"`
+}
+
+// IdempotencyKey — это сохраненный ответ на запрос с заголовком Idempotency-Key.
+// @property {string} Key - Значение заголовка Idempotency-Key.
+// @property {string} Fingerprint - Отпечаток запроса: sha256 от метода, пути и тела.
+// @property {int} StatusCode - HTTP-код сохраненного ответа. 0 означает, что запрос с этим ключом еще
+// выполняется.
+// @property {[]byte} Response - Тело сохраненного ответа.
+// @property {time.Time} ExpiresAt - Момент, после которого ключ можно использовать повторно.
+type IdempotencyKey struct {
+	Key         string
+	Fingerprint string
+	StatusCode  int
+	Response    []byte
+	ExpiresAt   time.Time
+}
+
+// Это структура, содержащая поля, используемые для представления возврата платежа.
+// @property {int64} ID - Уникальный идентификатор возврата.
+// @property {int64} PaymentID - ID платежа, по которому сделан возврат.
+// @property {Decimal} Amount - Сумма возврата.
+// @property {string} Status - Статус возврата: «pending», «succeeded» или «failed».
+// @property {string} CreatedAt - Дата и время создания возврата.
+// @property {string} UpdatedAt - Дата и время последнего обновления возврата.
+type refund struct {
+	ID        int64   `json:"id" db:"id"`
+	PaymentID int64   `json:"payment_id" db:"payment_id"`
+	Amount    Decimal `json:"amount" db:"amount"`
+	Status    string  `json:"status" db:"status"`
+	CreatedAt string  `json:"created_at" db:"created_at"`
+	UpdatedAt string  `json:"updated_at" db:"updated_at"`
+}
+
+// RefundInput — это структура с данными для создания возврата.
+// @property {int64} PaymentID - ID платежа, берется из пути запроса.
+// @property {Decimal} Amount - Сумма возврата. Если не указана, возвращается весь остаток платежа.
+type RefundInput struct {
+	PaymentID int64   `json:"-"`
+	Amount    Decimal `json:"amount,omitempty" validate:"gte=0"`
+}
+
+// RefundsData — это структура, содержащая фрагмент структур возвратов.
+// @property {[]refund} Data - Это массив возвратов платежа.
+type RefundsData struct {
+	Data []refund `json:"data"`
+}
+
+// Это структура, содержащая поля, используемые для представления перехода статуса платежа.
+// @property {int64} ID - Уникальный идентификатор перехода.
+// @property {int64} PaymentID - ID платежа.
+// @property {string} FromStatus - Статус до перехода. Пустой для созданного платежа.
+// @property {string} ToStatus - Статус после перехода.
+// @property {string} Reason - Причина перехода, если она была указана.
+// @property {string} Actor - Кто выполнил переход.
+// @property {string} CreatedAt - Дата и время перехода.
+type statusChange struct {
+	ID         int64  `json:"id" db:"id"`
+	PaymentID  int64  `json:"payment_id" db:"payment_id"`
+	FromStatus string `json:"from_status,omitempty" db:"from_status"`
+	ToStatus   string `json:"to_status" db:"to_status"`
+	Reason     string `json:"reason,omitempty" db:"reason"`
+	Actor      string `json:"actor" db:"actor"`
+	CreatedAt  string `json:"created_at" db:"created_at"`
+}
+
+// CaptureInput — это структура с данными для списания авторизованного платежа.
+// @property {int64} PaymentID - ID платежа, берется из пути запроса.
+// @property {Decimal} Amount - Сумма списания. Если не указана, списывается вся авторизованная сумма.
+// @property {string} Reason - Необязательная причина, сохраняется в истории.
+// @property {int64} Version - Версия платежа из заголовка If-Match. 0 - версия не проверяется.
+type CaptureInput struct {
+	PaymentID int64   `json:"-"`
+	Amount    Decimal `json:"amount,omitempty" validate:"gte=0"`
+	Reason    string  `json:"reason,omitempty"`
+	Version   int64   `json:"-"`
+}
+
+// CaptureData — это ответ на списание авторизованного платежа.
+// @property {int64} ID - Идентификатор платежа.
+// @property {string} Status - Статус платежа после списания.
+// @property {Decimal} CapturedAmount - Списанная сумма.
+type CaptureData struct {
+	ID             int64   `json:"id"`
+	Status         string  `json:"status"`
+	CapturedAmount Decimal `json:"captured_amount"`
+}
+
+// HistoryData — это структура, содержащая фрагмент структур переходов статуса.
+// @property {[]statusChange} Data - Это массив переходов в порядке их выполнения.
+type HistoryData struct {
+	Data []statusChange `json:"data"`
 }
@@ -0,0 +1,115 @@
+package payment
+
+import (
+	"errors"
+
+	"github.com/onlycodergod/payment-api-emulator/pkg/validator"
+)
+
+// Виды ошибок. По виду ошибки контроллер выбирает HTTP-код ответа: ErrNotFound - 404,
+// ErrTerminalStatus и ErrConflict - 409, ErrValidation - 422, ErrBadRequest - 400,
+// ErrPreconditionFailed - 412, ErrPayloadTooLarge - 413.
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrTerminalStatus     = errors.New("terminal status")
+	ErrValidation         = errors.New("validation failed")
+	ErrConflict           = errors.New("conflict")
+	ErrBadRequest         = errors.New("bad request")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrPayloadTooLarge    = errors.New("payload too large")
+)
+
+// Error — это ошибка платежа с видом, машиночитаемым кодом и подробностями для ответа клиенту.
+// errors.Is(err, ErrNotFound) проверяет вид ошибки, а errors.Is(err, ErrPaymentNotFound) - ее код.
+// @property {error} Kind - Вид ошибки: ErrNotFound, ErrTerminalStatus, ErrValidation, ErrConflict,
+// ErrBadRequest, ErrPreconditionFailed или ErrPayloadTooLarge.
+// @property {string} Code - Машиночитаемый код ошибки.
+// @property {string} Message - Описание ошибки.
+// @property Details - Подробности, например поле запроса с ошибкой.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	Details map[string]interface{}
+}
+
+// Возвращает описание ошибки.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Возвращает true, если target - вид этой ошибки или ошибка с тем же кодом.
+func (e *Error) Is(target error) bool {
+	if target == e.Kind {
+		return true
+	}
+
+	value, ok := target.(*Error)
+
+	return ok && value.Code == e.Code
+}
+
+// Возвращает ошибку вида kind с кодом code и описанием message.
+func newError(kind error, code, message string) *Error {
+	return &Error{
+		Kind:    kind,
+		Code:    code,
+		Message: message,
+	}
+}
+
+// Возвращает ошибку проверки входных данных с полем запроса field в подробностях.
+func invalidField(field, message string) *Error {
+	return &Error{
+		Kind:    ErrValidation,
+		Code:    CodeInvalidField,
+		Message: message,
+		Details: map[string]interface{}{
+			"field": field,
+		},
+	}
+}
+
+// Возвращает ошибку проверки входных данных с ошибками всех полей. В подробностях поле «field» - первое
+// поле с ошибкой, а «errors» - список всех полей с описаниями.
+func invalidFields(errs validator.Errors) *Error {
+	return &Error{
+		Kind:    ErrValidation,
+		Code:    CodeInvalidField,
+		Message: errs.Error(),
+		Details: map[string]interface{}{
+			"field":  errs[0].Field,
+			"errors": errs,
+		},
+	}
+}
+
+// Ошибки, которые возвращают репозиторий и варианты использования возвратов и списаний.
+var (
+	ErrPaymentNotFound      = newError(ErrNotFound, CodePaymentNotFound, PaymentNotFound)
+	ErrRefundNotAllowed     = newError(ErrConflict, CodeRefundNotAllowed, RefundNotAllowed)
+	ErrRefundExceedsAmount  = newError(ErrValidation, CodeRefundExceedsAmount, RefundExceedsAmount)
+	ErrInvalidAmount        = newError(ErrValidation, CodeInvalidAmount, "invalid amount")
+	ErrRateNotFound         = newError(ErrValidation, CodeRateNotFound, RateNotFound)
+	ErrNotAuthorized        = newError(ErrConflict, CodePaymentNotAuthorized, PaymentNotAuthorized)
+	ErrCaptureExceedsAmount = newError(ErrValidation, CodeCaptureExceedsAmount, CaptureExceedsAmount)
+	ErrVersionMismatch      = newError(ErrPreconditionFailed, CodeVersionMismatch, VersionMismatch)
+)
+
+// Ошибки разбора и проверки запроса, которые возвращает контроллер.
+var (
+	errInvalidID                = newError(ErrBadRequest, CodeInvalidID, InvalidQueryID)
+	errInvalidBody              = newError(ErrBadRequest, CodeInvalidBody, InvalidBodyData)
+	errInvalidIdempotencyKey    = newError(ErrBadRequest, CodeInvalidIdempotencyKey, InvalidIdempotencyKey)
+	errIdempotencyKeyReused     = newError(ErrValidation, CodeIdempotencyKeyReused, IdempotencyKeyReused)
+	errIdempotencyKeyInProgress = newError(ErrConflict, CodeIdempotencyKeyInProgress, IdempotencyKeyInProgress)
+	errBodyTooLarge             = newError(ErrPayloadTooLarge, CodeBodyTooLarge, BodyTooLarge)
+	errCurrencyDisabled         = &Error{
+		Kind:    ErrValidation,
+		Code:    CodeCurrencyDisabled,
+		Message: CurrencyDisabled,
+		Details: map[string]interface{}{
+			"field": "currency",
+		},
+	}
+)
@@ -0,0 +1,101 @@
+package payment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/apierror"
+	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет, что ошибки платежа относятся к своему виду и сохраняют код при обертывании.
+func TestErrorKinds(t *testing.T) {
+	t.Parallel()
+
+	wrapped := fmt.Errorf("payment-repository-CreateRefund, %w", ErrRefundExceedsAmount)
+
+	assert.ErrorIs(t, wrapped, ErrRefundExceedsAmount)
+	assert.ErrorIs(t, wrapped, ErrValidation)
+	assert.False(t, errors.Is(wrapped, ErrConflict))
+
+	assert.ErrorIs(t, ErrPaymentNotFound, ErrNotFound)
+	assert.ErrorIs(t, ErrNotAuthorized, ErrConflict)
+	assert.ErrorIs(t, &TransitionError{Current: StatusCanceled, Requested: StatusSuccess}, ErrTerminalStatus)
+	assert.ErrorIs(t, &TransitionError{Current: StatusNew, Requested: StatusNew}, ErrConflict)
+	assert.False(t, errors.Is(&TransitionError{Current: StatusNew, Requested: StatusNew}, ErrTerminalStatus))
+}
+
+// Он проверяет HTTP-код и тело ответа контроллера на ошибки разных видов.
+func TestControllerWriteError(t *testing.T) {
+	t.Parallel()
+
+	c := NewPaymentController(loggin.NewLogger(false), nil, nil, nil)
+
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		details map[string]interface{}
+	}{
+		{
+			name:   "Not found",
+			err:    fmt.Errorf("payment-repository-GetStatus, %w", ErrPaymentNotFound),
+			status: http.StatusNotFound,
+			code:   CodePaymentNotFound,
+		},
+		{
+			name:    "Terminal status",
+			err:     fmt.Errorf("payment-UseCase-CancelPayment, %w", &TransitionError{Current: StatusSuccess, Requested: StatusCanceled}),
+			status:  http.StatusConflict,
+			code:    CodeTerminalStatus,
+			details: map[string]interface{}{"current_status": StatusSuccess, "requested_status": StatusCanceled},
+		},
+		{
+			name:   "Conflict",
+			err:    ErrRefundNotAllowed,
+			status: http.StatusConflict,
+			code:   CodeRefundNotAllowed,
+		},
+		{
+			name:    "Validation",
+			err:     invalidField("user_email", InvalidBodyEmail),
+			status:  http.StatusUnprocessableEntity,
+			code:    CodeInvalidField,
+			details: map[string]interface{}{"field": "user_email"},
+		},
+		{
+			name:   "Malformed request",
+			err:    errInvalidBody,
+			status: http.StatusBadRequest,
+			code:   CodeInvalidBody,
+		},
+		{
+			name:   "Unknown error",
+			err:    errors.New("connection refused"),
+			status: http.StatusInternalServerError,
+			code:   CodeInternalError,
+		},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/payments/1/status", nil)
+		r.Header.Set(apierror.RequestIDHeader, "request-1")
+
+		w := httptest.NewRecorder()
+		c.writeError(w, r, tt.err)
+
+		var got apierror.Body
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&got), tt.name)
+
+		assert.Equal(t, tt.status, w.Code, tt.name)
+		assert.Equal(t, tt.code, got.Code, tt.name)
+		assert.Equal(t, tt.details, got.Details, tt.name)
+		assert.Equal(t, "request-1", got.RequestID, tt.name)
+	}
+}
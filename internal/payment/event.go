@@ -0,0 +1,102 @@
+package payment
+
+import (
+	"context"
+	"time"
+
+	"github.com/onlycodergod/payment-api-emulator/internal/outbox"
+	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
+)
+
+// Типы событий платежа.
+const (
+	EventPaymentCreated       = "payment.created"
+	EventPaymentStatusChanged = "payment.status_changed"
+)
+
+// Исполнители изменений платежа.
+const (
+	ActorSystem   = "system"
+	ActorAPI      = "api"
+	ActorScenario = "scenario"
+	ActorExpiry   = "expiry"
+)
+
+// Заголовок, в котором клиент API может передать исполнителя изменения.
+const ActorHeader = "X-Actor"
+
+// Ключ контекста, в котором хранится исполнитель изменения.
+type actorKey struct{}
+
+// Возвращает контекст, в котором исполнителем изменений платежа является actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Возвращает исполнителя изменения из контекста или ActorSystem, если он не задан.
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+
+	return ActorSystem
+}
+
+// PaymentEvent — это событие об изменении платежа.
+// @property {string} Type - Тип события: «payment.created» или «payment.status_changed».
+// @property {int64} PaymentID - ID платежа.
+// @property {string} OldStatus - Статус до изменения. Пустой для «payment.created».
+// @property {string} NewStatus - Статус после изменения.
+// @property {string} Reason - Причина изменения, если она была указана.
+// @property {time.Time} OccurredAt - Момент изменения.
+type PaymentEvent struct {
+	Type       string    `json:"type"`
+	PaymentID  int64     `json:"payment_id"`
+	OldStatus  string    `json:"old_status,omitempty"`
+	NewStatus  string    `json:"new_status"`
+	Reason     string    `json:"reason,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Возвращает событие платежа по записи outbox. Запись без старого статуса — это созданный платеж.
+func EventFromOutbox(value outbox.Event) PaymentEvent {
+	eventType := EventPaymentStatusChanged
+	if value.OldStatus == "" {
+		eventType = EventPaymentCreated
+	}
+
+	return PaymentEvent{
+		Type:       eventType,
+		PaymentID:  value.PaymentID,
+		OldStatus:  value.OldStatus,
+		NewStatus:  value.NewStatus,
+		Reason:     value.Reason,
+		OccurredAt: value.CreatedAt,
+	}
+}
+
+// Notifier — это интерфейс получателя событий платежа. Notify вызывается синхронно после
+// успешного изменения, поэтому реализация должна быстро вернуть управление и сама обработать свои
+// ошибки.
+// @property Notify - Получает событие платежа.
+type Notifier interface {
+	Notify(ctx context.Context, event PaymentEvent)
+}
+
+// Эта функция подписывает получателя на события платежей. Вызывается при запуске приложения.
+func (u *UseCase) Subscribe(notifier Notifier) {
+	u.notifiers = append(u.notifiers, notifier)
+}
+
+// Передает событие всем подписанным получателям.
+func (u *UseCase) notify(ctx context.Context, event PaymentEvent) {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+
+	loggin.FromContext(ctx).Named("payment").With("payment_id", event.PaymentID).Debugf("%s: %s -> %s", event.Type, event.OldStatus, event.NewStatus)
+
+	for _, notifier := range u.notifiers {
+		notifier.Notify(ctx, event)
+	}
+}
@@ -0,0 +1,235 @@
+package payment
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onlycodergod/payment-api-emulator/pkg/validator"
+)
+
+// Размер страницы списка платежей.
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+)
+
+// Порядок сортировки списка платежей по идентификатору.
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// Содержимое непрозрачного курсора страницы.
+// @property {int64} ID - ID последнего платежа предыдущей страницы.
+// @property {string} Sort - Порядок сортировки, в котором курсор был выдан.
+type pageCursor struct {
+	ID   int64  `json:"id"`
+	Sort string `json:"sort"`
+}
+
+// Кодирует курсор следующей страницы.
+func encodeCursor(PaymentID int64, sort string) string {
+	data, _ := json.Marshal(pageCursor{
+		ID:   PaymentID,
+		Sort: sort,
+	})
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Декодирует курсор, выданный encodeCursor.
+func decodeCursor(value string) (pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return pageCursor{}, err
+	}
+
+	var output pageCursor
+	if err := json.Unmarshal(data, &output); err != nil {
+		return pageCursor{}, err
+	}
+
+	if output.ID <= 0 || (output.Sort != SortAsc && output.Sort != SortDesc) {
+		return pageCursor{}, errors.New("malformed cursor")
+	}
+
+	return output, nil
+}
+
+// Разбирает параметры запроса списка платежей: limit, cursor, status, currency, min_amount,
+// max_amount, created_from, created_to и sort. Значения проверяются по тегам validate PaymentUser.
+// Возвращает ошибку проверки со всеми параметрами запроса с ошибками.
+func parsePaymentUser(query url.Values, input PaymentUser) (PaymentUser, error) {
+	var errs validator.Errors
+
+	invalid := func(field, message string) {
+		errs = append(errs, validator.FieldError{Field: field, Message: message})
+	}
+
+	input.Limit = DefaultPageLimit
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			invalid("limit", MustBeInteger)
+		} else {
+			input.Limit = limit
+		}
+	}
+
+	input.Sort = strings.ToLower(query.Get("sort"))
+
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := decodeCursor(value)
+		if err != nil || (input.Sort != "" && input.Sort != cursor.Sort) {
+			invalid("cursor", InvalidValue)
+		} else {
+			input.AfterID = cursor.ID
+			input.Sort = cursor.Sort
+		}
+	}
+
+	input.Status = query.Get("status")
+	input.Currency = strings.ToLower(query.Get("currency"))
+
+	var err error
+	if input.MinAmount, err = parseAmount(query.Get("min_amount")); err != nil {
+		invalid("min_amount", MustBeDecimal)
+	}
+
+	if input.MaxAmount, err = parseAmount(query.Get("max_amount")); err != nil {
+		invalid("max_amount", MustBeDecimal)
+	}
+
+	if input.CreatedFrom, err = parseTime(query.Get("created_from")); err != nil {
+		invalid("created_from", MustBeTime)
+	}
+
+	if input.CreatedTo, err = parseTime(query.Get("created_to")); err != nil {
+		invalid("created_to", MustBeTime)
+	}
+
+	if err := validate.Struct(input); err != nil {
+		errs = append(errs, err.(validator.Errors)...)
+	}
+
+	if !input.MaxAmount.IsZero() && input.MinAmount.Cmp(input.MaxAmount) > 0 {
+		invalid("min_amount", MustBeAtMostMaxAmount)
+	}
+
+	if !input.CreatedTo.IsZero() && !input.CreatedFrom.Before(input.CreatedTo) {
+		invalid("created_from", MustBeBeforeCreatedTo)
+	}
+
+	if len(errs) != 0 {
+		return PaymentUser{}, invalidFields(errs)
+	}
+
+	if input.Sort == "" {
+		input.Sort = SortAsc
+	}
+
+	return input, nil
+}
+
+// Разбирает сумму. Пустая строка означает отсутствие фильтра.
+func parseAmount(value string) (Decimal, error) {
+	if value == "" {
+		return Decimal{}, nil
+	}
+
+	return ParseDecimal(value)
+}
+
+// Разбирает время в формате RFC 3339. Пустая строка означает отсутствие фильтра.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+// Возвращает true, если платеж принадлежит пользователю и проходит фильтры и курсор страницы.
+// Повторяет условия запроса repository.GetPayments для хранилища в памяти.
+func (input PaymentUser) matches(value payment) bool {
+	if input.UserID != 0 && value.UserID != input.UserID {
+		return false
+	}
+
+	if input.UserID == 0 && value.UserEmail != input.UserEmail {
+		return false
+	}
+
+	if input.AfterID != 0 {
+		if input.Sort == SortDesc && value.ID >= input.AfterID {
+			return false
+		}
+
+		if input.Sort != SortDesc && value.ID <= input.AfterID {
+			return false
+		}
+	}
+
+	if input.Status != "" && value.Status != input.Status {
+		return false
+	}
+
+	if input.Currency != "" && value.Currency != input.Currency {
+		return false
+	}
+
+	if !input.MinAmount.IsZero() && value.Amount.Cmp(input.MinAmount) < 0 {
+		return false
+	}
+
+	if !input.MaxAmount.IsZero() && value.Amount.Cmp(input.MaxAmount) > 0 {
+		return false
+	}
+
+	if !input.CreatedFrom.IsZero() || !input.CreatedTo.IsZero() {
+		createdAt, err := time.Parse(time.RFC3339Nano, value.CreatedAt)
+		if err != nil {
+			return false
+		}
+
+		if !input.CreatedFrom.IsZero() && createdAt.Before(input.CreatedFrom) {
+			return false
+		}
+
+		if !input.CreatedTo.IsZero() && !createdAt.Before(input.CreatedTo) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// whereBuilder — это построитель условия WHERE. Условия задаются только константами кода с «?» на месте
+// значения, а значения передаются аргументами запроса, поэтому параметры клиента не попадают в SQL.
+// @property conditions - Условия с плейсхолдерами Postgres.
+// @property args - Аргументы запроса в порядке плейсхолдеров.
+type whereBuilder struct {
+	conditions []string
+	args       []interface{}
+}
+
+// Добавляет условие, заменяя «?» на следующий плейсхолдер.
+func (b *whereBuilder) add(condition string, value interface{}) {
+	b.args = append(b.args, value)
+	b.conditions = append(b.conditions, strings.Replace(condition, "?", "$"+strconv.Itoa(len(b.args)), 1))
+}
+
+// Возвращает следующий свободный номер плейсхолдера.
+func (b *whereBuilder) next() int {
+	return len(b.args) + 1
+}
+
+// Возвращает условия, объединенные через AND.
+func (b *whereBuilder) String() string {
+	return strings.Join(b.conditions, " AND ")
+}
@@ -0,0 +1,74 @@
+package payment
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет разбор параметров списка платежей.
+func TestParsePaymentUser(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		input  string
+		expect PaymentUser
+		err    string
+	}{
+		{
+			name:   "Defaults",
+			input:  "",
+			expect: PaymentUser{UserID: 1, Limit: DefaultPageLimit, Sort: SortAsc},
+		},
+		{
+			name:  "All filters",
+			input: "limit=10&sort=DESC&status=success&currency=USD&min_amount=1.5&max_amount=10&created_from=2022-07-01T00:00:00Z&created_to=2022-08-01T00:00:00Z",
+			expect: PaymentUser{
+				UserID:      1,
+				Limit:       10,
+				Sort:        SortDesc,
+				Status:      StatusSuccess,
+				Currency:    "usd",
+				MinAmount:   mustDecimal("1.5"),
+				MaxAmount:   mustDecimal("10"),
+				CreatedFrom: time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC),
+				CreatedTo:   time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:   "Cursor keeps its sort order",
+			input:  "cursor=" + encodeCursor(7, SortDesc),
+			expect: PaymentUser{UserID: 1, Limit: DefaultPageLimit, AfterID: 7, Sort: SortDesc},
+		},
+		{name: "Limit above maximum", input: "limit=501", err: "limit: must be at most 500"},
+		{name: "Zero limit", input: "limit=0", err: "limit: must be at least 1"},
+		{name: "Malformed limit", input: "limit=ten", err: "limit: must be an integer"},
+		{name: "Unknown sort", input: "sort=amount", err: "sort: must be one of: asc, desc"},
+		{name: "Malformed cursor", input: "cursor=abc", err: "cursor: invalid value"},
+		{name: "Cursor from another sort order", input: "sort=asc&cursor=" + encodeCursor(7, SortDesc), err: "cursor: invalid value"},
+		{name: "Unknown status", input: "status=unknown", err: "status: unknown value"},
+		{name: "Negative amount", input: "min_amount=-1", err: "min_amount: must be >= 0"},
+		{name: "Malformed amount", input: "max_amount=ten", err: "max_amount: must be a decimal number"},
+		{name: "Inverted amount range", input: "min_amount=10&max_amount=1", err: "min_amount: must be <= max_amount"},
+		{name: "Malformed time", input: "created_from=yesterday", err: "created_from: must be an RFC 3339 time"},
+		{name: "Inverted time range", input: "created_from=2022-08-01T00:00:00Z&created_to=2022-07-01T00:00:00Z", err: "created_from: must be before created_to"},
+		{name: "Several parameters", input: "limit=0&status=unknown", err: "limit: must be at least 1; status: unknown value"},
+	}
+
+	for _, tt := range tests {
+		query, err := url.ParseQuery(tt.input)
+		assert.NoError(t, err, tt.name)
+
+		got, err := parsePaymentUser(query, PaymentUser{UserID: 1})
+
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err, tt.name)
+		} else {
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, tt.expect, got, tt.name)
+		}
+	}
+}
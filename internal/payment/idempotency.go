@@ -0,0 +1,185 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Заголовки, которые используются для идемпотентных запросов.
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+)
+
+// Максимальная длина значения заголовка Idempotency-Key.
+const maxIdempotencyKeyLength = 255
+
+// Время, на которое Idempotency-Key резервируется за выполняющимся запросом. Если процесс упадет до
+// ответа, ключ освободится после этого времени.
+const IdempotencyLockTimeout = time.Minute
+
+// Это обертка над http.ResponseWriter, которая запоминает код и тело ответа.
+// @property {int} status - HTTP-код ответа.
+// @property body - Копия тела ответа.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// Запоминает код ответа и передает его дальше.
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Запоминает тело ответа и передает его дальше.
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+
+	r.body.Write(b)
+
+	return r.ResponseWriter.Write(b)
+}
+
+// Возвращает отпечаток запроса: sha256 от метода, пути и тела.
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method))
+	hash.Write([]byte(r.URL.Path))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Возвращает true, если ответ с кодом status сохраняется по Idempotency-Key и возвращается повторно.
+// Ответы 409, 429 и 5xx не сохраняются, поэтому после них запрос можно повторить с тем же ключом.
+func storable(status int) bool {
+	if status == http.StatusConflict || status == http.StatusTooManyRequests {
+		return false
+	}
+
+	return status >= http.StatusOK && status < http.StatusInternalServerError
+}
+
+// detachedContext — это контекст, который сохраняет значения родителя, но не отменяется вместе с ним.
+type detachedContext struct {
+	context.Context
+}
+
+// Возвращает пустой срок: контекст не ограничен сроком родителя.
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+// Возвращает nil: контекст не отменяется.
+func (detachedContext) Done() <-chan struct{} { return nil }
+
+// Возвращает nil: контекст не отменяется.
+func (detachedContext) Err() error { return nil }
+
+// Оборачивает обработчик поддержкой заголовка Idempotency-Key. Ключ резервируется до вызова
+// обработчика, поэтому из одновременных запросов с одним ключом выполняется только один, а остальные
+// получают 409, пока он не завершится. Повторный запрос с тем же ключом и телом получает сохраненный
+// ответ, а запрос с тем же ключом и другим телом - 422. Ответы, после которых запрос можно повторить
+// (409, 429 и 5xx), не сохраняются, и резерв ключа снимается.
+func (c *controller) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			c.writeError(w, r, errInvalidIdempotencyKey)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodySize))
+		if err != nil {
+			c.writeError(w, r, bodyError(err))
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		reservation := IdempotencyKey{
+			Key:         key,
+			Fingerprint: fingerprint(r, body),
+		}
+
+		reserved, err := c.UseCase.ReserveIdempotencyKey(
+			r.Context(),
+			reservation,
+		)
+		if err != nil {
+			c.writeError(w, r, err)
+			return
+		}
+
+		if !reserved {
+			c.replay(w, r, reservation)
+			return
+		}
+
+		// Ответ сохраняется и резерв снимается и тогда, когда клиент уже разорвал соединение.
+		ctx := detachedContext{r.Context()}
+		recorder := &responseRecorder{ResponseWriter: w}
+		completed := false
+
+		// Если обработчик прерван паникой, резерв снимается, чтобы запрос можно было повторить.
+		defer func() {
+			if completed {
+				return
+			}
+
+			if err := c.UseCase.ReleaseIdempotencyKey(ctx, reservation); err != nil {
+				c.logger.WithContext(ctx).Error(err)
+			}
+		}()
+
+		next(recorder, r)
+
+		if !storable(recorder.status) {
+			return
+		}
+
+		reservation.StatusCode = recorder.status
+		reservation.Response = recorder.body.Bytes()
+		completed = true
+
+		if err := c.UseCase.SaveIdempotencyKey(ctx, reservation); err != nil {
+			c.logger.WithContext(ctx).Error(err)
+		}
+	}
+}
+
+// Отвечает на запрос, ключ которого занят: сохраненным ответом, 422, если тело запроса другое, или
+// 409, если запрос с этим ключом еще выполняется.
+func (c *controller) replay(w http.ResponseWriter, r *http.Request, reservation IdempotencyKey) {
+	stored, ok, err := c.UseCase.GetIdempotencyKey(
+		r.Context(),
+		reservation.Key,
+	)
+	if err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	switch {
+	case ok && stored.Fingerprint != reservation.Fingerprint:
+		c.writeError(w, r, errIdempotencyKeyReused)
+	case !ok || stored.StatusCode == 0:
+		c.writeError(w, r, errIdempotencyKeyInProgress)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(IdempotencyReplayedHeader, "true")
+		w.WriteHeader(stored.StatusCode)
+		w.Write(stored.Response)
+	}
+}
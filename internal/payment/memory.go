@@ -0,0 +1,524 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/onlycodergod/payment-api-emulator/internal/currency"
+	"github.com/onlycodergod/payment-api-emulator/internal/outbox"
+)
+
+// Максимальная длина user_email, как у столбца VARCHAR(20).
+const memoryEmailLength = 20
+
+// memoryRepository — это потокобезопасная реализация PaymentRepository в памяти, которая повторяет
+// поведение Postgres: последовательные идентификаторы, created_at/updated_at, проверки перечислений,
+// внешний ключ валюты на список ISO 4217 и ограничение на переход из терминальных статусов.
+// @property mu - Мьютекс, который защищает все поля репозитория.
+// @property tx - Мьютекс, который удерживается на время WithinTransaction, поэтому транзакции
+// выполняются по одной.
+// @property {int64} lastID - Последний выданный идентификатор платежа, как у SERIAL.
+// @property payments - Платежи по идентификатору.
+// @property idempotencyKeys - Сохраненные ответы по Idempotency-Key.
+// @property {int64} lastRefundID - Последний выданный идентификатор возврата.
+// @property refunds - Возвраты в порядке создания.
+// @property outbox - События outbox в порядке записи.
+// @property published - Идентификаторы опубликованных событий outbox.
+// @property history - История переходов статусов в порядке записи.
+// @property now - Источник текущего времени.
+type memoryRepository struct {
+	mu              sync.RWMutex
+	tx              sync.Mutex
+	lastID          int64
+	payments        map[int64]payment
+	idempotencyKeys map[string]IdempotencyKey
+	lastRefundID    int64
+	refunds         []refund
+	outbox          []outbox.Event
+	published       map[int64]struct{}
+	history         []statusChange
+	now             func() time.Time
+}
+
+// Он создает новый пустой репозиторий в памяти и возвращает указатель на него.
+func NewMemoryPaymentRepository() *memoryRepository {
+	return &memoryRepository{
+		payments:        make(map[int64]payment),
+		idempotencyKeys: make(map[string]IdempotencyKey),
+		refunds:         make([]refund, 0),
+		outbox:          make([]outbox.Event, 0),
+		published:       make(map[int64]struct{}),
+		history:         make([]statusChange, 0),
+		now:             time.Now,
+	}
+}
+
+// memoryTxKey — это ключ контекста, который отмечает, что вызов выполняется внутри WithinTransaction.
+type memoryTxKey struct{}
+
+// Выполняет fn, пока удерживается мьютекс транзакций, поэтому проверка статуса в fn и его изменение
+// не пересекаются с другими транзакциями. Вложенный вызов выполняется в уже открытой транзакции.
+// Изменения при ошибке fn не откатываются.
+func (r *memoryRepository) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(memoryTxKey{}) != nil {
+		return fn(ctx)
+	}
+
+	r.tx.Lock()
+	defer r.tx.Unlock()
+
+	return fn(context.WithValue(ctx, memoryTxKey{}, true))
+}
+
+// Возвращает платеж по ID. Платеж защищен мьютексом транзакций WithinTransaction.
+func (r *memoryRepository) LockPayment(ctx context.Context, PaymentID int64) (payment, error) {
+	value, err := r.GetPayment(ctx, PaymentID)
+	if err != nil {
+		return payment{}, fmt.Errorf("payment-memoryRepository-LockPayment, %w", err)
+	}
+
+	return value, nil
+}
+
+// Возвращает текущее время в формате, в котором Postgres отдает TIMESTAMP WITH TIME ZONE.
+func (r *memoryRepository) timestamp() string {
+	return r.now().UTC().Format(time.RFC3339Nano)
+}
+
+// Создание нового платежа.
+func (r *memoryRepository) CreatePayment(ctx context.Context, input PaymentInput) (int64, error) {
+	if _, ok := currency.Find(input.Currency); !ok {
+		return 0, fmt.Errorf("payment-memoryRepository-CreatePayment, currency %q violates foreign key constraint", input.Currency)
+	}
+
+	if _, ok := currency.Find(input.Settlement.Currency); input.Settlement.Currency != "" && !ok {
+		return 0, fmt.Errorf("payment-memoryRepository-CreatePayment, settlement_currency %q violates foreign key constraint", input.Settlement.Currency)
+	}
+
+	if input.Amount.Sign() <= 0 {
+		return 0, fmt.Errorf("payment-memoryRepository-CreatePayment, %s", "amount violates check constraint")
+	}
+
+	if len(input.UserEmail) > memoryEmailLength {
+		return 0, fmt.Errorf("payment-memoryRepository-CreatePayment, %s", "user_email value too long")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastID++
+	now := r.timestamp()
+	settlement := input.settlement(r.now())
+
+	r.payments[r.lastID] = payment{
+		ID:                 r.lastID,
+		UserID:             input.UserID,
+		Amount:             input.Amount,
+		UserEmail:          input.UserEmail,
+		Currency:           input.Currency,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+		Status:             StatusNew,
+		SettlementCurrency: settlement.Currency,
+		SettlementAmount:   settlement.Amount,
+		FXRate:             settlement.Rate,
+		FXSource:           settlement.Source,
+		FXRatedAt:          settlement.RatedAt.UTC().Format(time.RFC3339Nano),
+		Version:            1,
+	}
+	r.recordTransition(ctx, r.lastID, "", StatusNew, "")
+
+	return r.lastID, nil
+}
+
+// Обновление статуса платежа.
+func (r *memoryRepository) UpdateStatus(ctx context.Context, input PaymentStatus) (int64, error) {
+	if !paymentStates.IsKnown(input.Status) {
+		return 0, fmt.Errorf("payment-memoryRepository-UpdateStatus, invalid input value for enum valid_status: %q", input.Status)
+	}
+
+	return r.setStatus(ctx, input.ID, input.Status, input.Reason, input.Version, paymentStates.Sources(input.Status)), nil
+}
+
+// Переводит платеж в статус to, если текущий статус входит в sources, записывает событие outbox и
+// историю и возвращает количество измененных строк. Переход в «success» списывает всю сумму платежа,
+// переход в «authorized» запоминает время авторизации. Ненулевая version должна совпадать с версией
+// платежа.
+func (r *memoryRepository) setStatus(ctx context.Context, PaymentID int64, to, reason string, version int64, sources []string) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	value, ok := r.payments[PaymentID]
+	if !ok || !contains(sources, value.Status) || (version != 0 && value.Version != version) {
+		return 0
+	}
+
+	r.recordTransition(ctx, PaymentID, value.Status, to, reason)
+
+	switch to {
+	case StatusSuccess:
+		value.CapturedAmount = value.Amount
+	case StatusAuthorized:
+		value.AuthorizedAt = r.timestamp()
+	}
+
+	value.Status = to
+	value.UpdatedAt = r.timestamp()
+	value.Version++
+	r.payments[PaymentID] = value
+
+	return 1
+}
+
+// Записывает событие outbox и строку истории. Вызывается под блокировкой вместе с изменением платежа.
+func (r *memoryRepository) recordTransition(ctx context.Context, PaymentID int64, from, to, reason string) {
+	actor := truncate(ActorFromContext(ctx), actorLength)
+
+	r.outbox = append(r.outbox, outbox.Event{
+		ID:        int64(len(r.outbox) + 1),
+		PaymentID: PaymentID,
+		OldStatus: from,
+		NewStatus: to,
+		Reason:    reason,
+		Actor:     actor,
+		CreatedAt: r.now().UTC(),
+	})
+
+	r.history = append(r.history, statusChange{
+		ID:         int64(len(r.history) + 1),
+		PaymentID:  PaymentID,
+		FromStatus: from,
+		ToStatus:   to,
+		Reason:     reason,
+		Actor:      actor,
+		CreatedAt:  r.timestamp(),
+	})
+}
+
+// Получение статуса платежа.
+func (r *memoryRepository) GetStatus(ctx context.Context, PaymentID int64) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	value, ok := r.payments[PaymentID]
+	if !ok {
+		return "", fmt.Errorf("payment-memoryRepository-GetStatus, %w", ErrPaymentNotFound)
+	}
+
+	return value.Status, nil
+}
+
+// Функция, которая возвращает платеж по его ID.
+func (r *memoryRepository) GetPayment(ctx context.Context, PaymentID int64) (payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	value, ok := r.payments[PaymentID]
+	if !ok {
+		return payment{}, fmt.Errorf("payment-memoryRepository-GetPayment, %w", ErrPaymentNotFound)
+	}
+
+	value.deriveRefundStatus()
+
+	return value, nil
+}
+
+// Функция, которая возвращает страницу платежей пользователя по его ID или email с фильтрами.
+// Возвращается до Limit+1 строк: лишняя строка означает, что есть следующая страница.
+func (r *memoryRepository) GetPayments(ctx context.Context, input PaymentUser) ([]payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	output := make([]payment, 0)
+	for _, value := range r.payments {
+		if input.matches(value) {
+			output = append(output, value)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		if input.Sort == SortDesc {
+			return output[i].ID > output[j].ID
+		}
+
+		return output[i].ID < output[j].ID
+	})
+
+	limit := input.Limit
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+
+	if len(output) > limit+1 {
+		output = output[:limit+1]
+	}
+
+	for i := range output {
+		output[i].deriveRefundStatus()
+	}
+
+	return output, nil
+}
+
+// Обновление статуса "Отмены" платежа.
+func (r *memoryRepository) CancelPayment(ctx context.Context, input PaymentStatus) (int64, error) {
+	return r.setStatus(ctx, input.ID, StatusCanceled, input.Reason, input.Version, paymentStates.Sources(StatusCanceled)), nil
+}
+
+// Отмена авторизованного платежа. Платеж в любом другом статусе не меняется.
+func (r *memoryRepository) VoidPayment(ctx context.Context, input PaymentStatus) (int64, error) {
+	return r.setStatus(ctx, input.ID, StatusCanceled, input.Reason, input.Version, []string{StatusAuthorized}), nil
+}
+
+// Списание авторизованного платежа. Проверка суммы и смена статуса выполняются под одной блокировкой.
+func (r *memoryRepository) CapturePayment(ctx context.Context, input CaptureInput) (Decimal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	value, ok := r.payments[input.PaymentID]
+	if !ok {
+		return Decimal{}, fmt.Errorf("payment-memoryRepository-CapturePayment, %w", ErrPaymentNotFound)
+	}
+
+	cur, _ := currency.Find(value.Currency)
+
+	captured, err := captureAmount(value.Amount, value.Status, cur, input.Amount)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("payment-memoryRepository-CapturePayment, %w", err)
+	}
+
+	r.recordTransition(ctx, input.PaymentID, value.Status, StatusSuccess, input.Reason)
+
+	value.Status = StatusSuccess
+	value.CapturedAmount = captured
+	value.UpdatedAt = r.timestamp()
+	value.Version++
+	r.payments[input.PaymentID] = value
+
+	return captured, nil
+}
+
+// Получение не истекшего сохраненного ответа по Idempotency-Key.
+func (r *memoryRepository) GetIdempotencyKey(ctx context.Context, key string) (IdempotencyKey, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	value, ok := r.idempotencyKeys[key]
+	if !ok || !value.ExpiresAt.After(r.now()) {
+		return IdempotencyKey{}, false, nil
+	}
+
+	return value, true, nil
+}
+
+// Резервирование Idempotency-Key за выполняющимся запросом. Возвращает false, если ключ занят и не
+// истек.
+func (r *memoryRepository) ReserveIdempotencyKey(ctx context.Context, input IdempotencyKey) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if value, ok := r.idempotencyKeys[input.Key]; ok && value.ExpiresAt.After(r.now()) {
+		return false, nil
+	}
+
+	r.idempotencyKeys[input.Key] = IdempotencyKey{
+		Key:         input.Key,
+		Fingerprint: input.Fingerprint,
+		ExpiresAt:   input.ExpiresAt,
+	}
+
+	return true, nil
+}
+
+// Сохранение ответа по зарезервированному Idempotency-Key. Ключ, который уже не зарезервирован этим
+// запросом, не перезаписывается.
+func (r *memoryRepository) SaveIdempotencyKey(ctx context.Context, input IdempotencyKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.reservedIdempotencyKey(input) {
+		return nil
+	}
+
+	r.idempotencyKeys[input.Key] = input
+
+	return nil
+}
+
+// Снятие резерва Idempotency-Key, ответ на который не сохраняется, чтобы запрос можно было повторить.
+func (r *memoryRepository) ReleaseIdempotencyKey(ctx context.Context, input IdempotencyKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.reservedIdempotencyKey(input) {
+		delete(r.idempotencyKeys, input.Key)
+	}
+
+	return nil
+}
+
+// Возвращает true, если ключ зарезервирован запросом с тем же отпечатком и ответ еще не сохранен.
+func (r *memoryRepository) reservedIdempotencyKey(input IdempotencyKey) bool {
+	value, ok := r.idempotencyKeys[input.Key]
+
+	return ok && value.Fingerprint == input.Fingerprint && value.StatusCode == 0
+}
+
+// Создание возврата. Проверка остатка и запись выполняются под одной блокировкой.
+func (r *memoryRepository) CreateRefund(ctx context.Context, input RefundInput) (refund, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	value, ok := r.payments[input.PaymentID]
+	if !ok {
+		return refund{}, fmt.Errorf("payment-memoryRepository-CreateRefund, %w", ErrPaymentNotFound)
+	}
+
+	cur, _ := currency.Find(value.Currency)
+
+	amount, err := refundAmount(value.CapturedAmount, value.RefundedAmount, value.Status, cur, input.Amount)
+	if err != nil {
+		return refund{}, fmt.Errorf("payment-memoryRepository-CreateRefund, %w", err)
+	}
+
+	r.lastRefundID++
+	now := r.timestamp()
+
+	output := refund{
+		ID:        r.lastRefundID,
+		PaymentID: input.PaymentID,
+		Amount:    amount,
+		Status:    RefundStatusSucceeded,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	r.refunds = append(r.refunds, output)
+
+	value.RefundedAmount = value.RefundedAmount.Add(amount)
+	value.UpdatedAt = now
+	value.Version++
+	r.payments[input.PaymentID] = value
+
+	return output, nil
+}
+
+// Функция, которая возвращает срез возвратов платежа.
+func (r *memoryRepository) GetRefunds(ctx context.Context, PaymentID int64) ([]refund, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	output := make([]refund, 0)
+	for _, value := range r.refunds {
+		if value.PaymentID == PaymentID {
+			output = append(output, value)
+		}
+	}
+
+	return output, nil
+}
+
+// Функция, которая возвращает не опубликованные события outbox в порядке их записи.
+func (r *memoryRepository) FetchOutbox(ctx context.Context, limit int) ([]outbox.Event, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	output := make([]outbox.Event, 0)
+	for _, value := range r.outbox {
+		if len(output) == limit {
+			break
+		}
+
+		if _, ok := r.published[value.ID]; !ok {
+			output = append(output, value)
+		}
+	}
+
+	return output, nil
+}
+
+// Отмечает события outbox опубликованными.
+func (r *memoryRepository) MarkOutboxPublished(ctx context.Context, ids []int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range ids {
+		r.published[id] = struct{}{}
+	}
+
+	return nil
+}
+
+// Функция, которая возвращает историю переходов статуса платежа в порядке их выполнения.
+func (r *memoryRepository) GetHistory(ctx context.Context, PaymentID int64) ([]statusChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	output := make([]statusChange, 0)
+	for _, value := range r.history {
+		if value.PaymentID == PaymentID {
+			output = append(output, value)
+		}
+	}
+
+	return output, nil
+}
+
+// Функция, которая возвращает платежи в статусе status с идентификатором больше AfterID,
+// упорядоченные по идентификатору.
+func (r *memoryRepository) GetPaymentsByStatus(ctx context.Context, status string, AfterID int64, limit int) ([]payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	output := make([]payment, 0)
+	for _, value := range r.payments {
+		if value.Status == status && value.ID > AfterID {
+			output = append(output, value)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].ID < output[j].ID
+	})
+
+	if len(output) > limit {
+		output = output[:limit]
+	}
+
+	for i := range output {
+		output[i].deriveRefundStatus()
+	}
+
+	return output, nil
+}
+
+// Функция, которая возвращает авторизованные платежи, авторизация которых выполнена раньше before,
+// упорядоченные по идентификатору.
+func (r *memoryRepository) GetExpiredAuthorizations(ctx context.Context, before time.Time, limit int) ([]payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	output := make([]payment, 0)
+	for _, value := range r.payments {
+		if value.Status != StatusAuthorized {
+			continue
+		}
+
+		authorizedAt, err := time.Parse(time.RFC3339Nano, value.AuthorizedAt)
+		if err == nil && authorizedAt.Before(before) {
+			output = append(output, value)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].ID < output[j].ID
+	})
+
+	if len(output) > limit {
+		output = output[:limit]
+	}
+
+	return output, nil
+}
@@ -0,0 +1,164 @@
+package payment
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет, что репозиторий в памяти повторяет проверки Postgres при создании платежа.
+func TestMemoryCreatePayment(t *testing.T) {
+	t.Parallel()
+
+	r := NewMemoryPaymentRepository()
+
+	tests := []struct {
+		name   string
+		input  PaymentInput
+		expect int64
+		err    bool
+	}{
+		{
+			name:   "Create payment",
+			input:  PaymentInput{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("10.5"), Currency: "usd"},
+			expect: 1,
+		},
+		{
+			name:   "Second payment gets next id",
+			input:  PaymentInput{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("1"), Currency: "rub"},
+			expect: 2,
+		},
+		{
+			name:  "Unknown currency",
+			input: PaymentInput{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("10.5"), Currency: "xxx"},
+			err:   true,
+		},
+		{
+			name:  "Non-positive amount",
+			input: PaymentInput{UserID: 1, UserEmail: "user@mail.ru", Amount: Decimal{}, Currency: "usd"},
+			err:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := r.CreatePayment(context.TODO(), tt.input)
+
+		if tt.err {
+			assert.Error(t, err, tt.name)
+		} else {
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, tt.expect, got, tt.name)
+		}
+	}
+}
+
+// Он проверяет, что репозиторий в памяти не меняет статус в обход машины состояний.
+func TestMemoryUpdateStatus(t *testing.T) {
+	t.Parallel()
+
+	r := NewMemoryPaymentRepository()
+	ctx := context.TODO()
+
+	id, err := r.CreatePayment(ctx, PaymentInput{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("10.5"), Currency: "usd"})
+	assert.NoError(t, err)
+
+	rows, err := r.UpdateStatus(ctx, PaymentStatus{ID: id, Status: StatusSuccess})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rows)
+
+	rows, err = r.CancelPayment(ctx, PaymentStatus{ID: id})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), rows)
+
+	_, err = r.UpdateStatus(ctx, PaymentStatus{ID: id, Status: "unknown"})
+	assert.Error(t, err)
+
+	status, err := r.GetStatus(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSuccess, status)
+
+	_, err = r.GetStatus(ctx, 100)
+	assert.Error(t, err)
+}
+
+// Он создает платежи из нескольких горутин и проверяет, что идентификаторы не повторяются.
+func TestMemoryConcurrentCreate(t *testing.T) {
+	t.Parallel()
+
+	r := NewMemoryPaymentRepository()
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := r.CreatePayment(context.TODO(), PaymentInput{UserID: 7, UserEmail: "user@mail.ru", Amount: mustDecimal("1"), Currency: "eur"})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	data, err := r.GetPayments(context.TODO(), PaymentUser{UserID: 7})
+	assert.NoError(t, err)
+	assert.Len(t, data, 50)
+
+	for i, value := range data {
+		assert.Equal(t, int64(i+1), value.ID)
+	}
+}
+
+// Он проверяет, что страницы списка платежей по курсору покрывают все платежи пользователя без
+// повторов в обоих порядках сортировки.
+func TestMemoryGetPaymentsPages(t *testing.T) {
+	t.Parallel()
+
+	r := NewMemoryPaymentRepository()
+	u := NewPaymentUseCase(r, Options{})
+	ctx := context.TODO()
+
+	for i := 0; i < 5; i++ {
+		_, err := r.CreatePayment(ctx, PaymentInput{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("10"), Currency: "usd"})
+		assert.NoError(t, err)
+	}
+
+	_, err := r.CreatePayment(ctx, PaymentInput{UserID: 2, UserEmail: "other@mail.ru", Amount: mustDecimal("10"), Currency: "usd"})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		sort   string
+		expect []int64
+	}{
+		{sort: SortAsc, expect: []int64{1, 2, 3, 4, 5}},
+		{sort: SortDesc, expect: []int64{5, 4, 3, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		input := PaymentUser{UserID: 1, Limit: 2, Sort: tt.sort}
+		got := make([]int64, 0)
+		pages := 0
+
+		for {
+			data, err := u.GetPayments(ctx, input)
+			assert.NoError(t, err)
+
+			for _, value := range data.Data {
+				got = append(got, value.ID)
+			}
+			pages++
+
+			if data.NextCursor == "" {
+				break
+			}
+
+			cursor, err := decodeCursor(data.NextCursor)
+			assert.NoError(t, err)
+			input.AfterID = cursor.ID
+		}
+
+		assert.Equal(t, tt.expect, got, tt.sort)
+		assert.Equal(t, 3, pages, tt.sort)
+	}
+}
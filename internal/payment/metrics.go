@@ -0,0 +1,35 @@
+package payment
+
+import (
+	"context"
+
+	"github.com/onlycodergod/payment-api-emulator/pkg/metrics"
+)
+
+// Metrics — это получатель событий платежей, который считает созданные платежи и смены статусов.
+// Подписывается на варианты использования, поэтому учитывает изменения из API, сценариев и истечения
+// авторизаций.
+// @property {*metrics.Counter} created - Количество созданных платежей.
+// @property {*metrics.Counter} transitions - Количество смен статуса по исходному и новому статусу.
+type Metrics struct {
+	created     *metrics.Counter
+	transitions *metrics.Counter
+}
+
+// > Эта функция регистрирует метрики платежей в registry и возвращает указатель на них.
+func NewMetrics(registry *metrics.Registry) *Metrics {
+	return &Metrics{
+		created:     registry.NewCounter("payments_created_total", "Total number of created payments."),
+		transitions: registry.NewCounter("payment_status_transitions_total", "Total number of payment status transitions.", "from", "to"),
+	}
+}
+
+// Он увеличивает счетчик созданных платежей или смен статуса.
+func (m *Metrics) Notify(ctx context.Context, event PaymentEvent) {
+	switch event.Type {
+	case EventPaymentCreated:
+		m.created.Inc()
+	case EventPaymentStatusChanged:
+		m.transitions.Inc(event.OldStatus, event.NewStatus)
+	}
+}
@@ -0,0 +1,40 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/onlycodergod/payment-api-emulator/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет, что метрики платежей считают созданные платежи и смены статусов по исходному и новому
+// статусу.
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	registry := metrics.NewRegistry()
+
+	usc := NewPaymentUseCase(NewMemoryPaymentRepository(), Options{})
+	usc.Subscribe(NewMetrics(registry))
+
+	ctx := context.TODO()
+
+	for i := 0; i < 3; i++ {
+		_, err := usc.CreatePayment(ctx, PaymentInput{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("10"), Currency: "usd"})
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, usc.UpdateStatus(ctx, PaymentStatus{ID: 1, Status: StatusAuthorized}))
+	assert.NoError(t, usc.UpdateStatus(ctx, PaymentStatus{ID: 2, Status: StatusAuthorized}))
+	assert.NoError(t, usc.CancelPayment(ctx, PaymentStatus{ID: 3}))
+	assert.Error(t, usc.CancelPayment(ctx, PaymentStatus{ID: 3}))
+
+	var buffer bytes.Buffer
+	assert.NoError(t, registry.Write(&buffer))
+
+	assert.Contains(t, buffer.String(), "payments_created_total 3\n")
+	assert.Contains(t, buffer.String(), `payment_status_transitions_total{from="new",to="authorized"} 2`+"\n")
+	assert.Contains(t, buffer.String(), `payment_status_transitions_total{from="new",to="canceled"} 1`+"\n")
+}
@@ -0,0 +1,352 @@
+package payment
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/onlycodergod/payment-api-emulator/internal/currency"
+)
+
+// Количество знаков после точки, которое хранит Decimal.
+const decimalScale = 6
+
+// Количество миллионных долей в единице.
+const decimalUnit = 1000000
+
+// Максимальное количество цифр целой части. Сумма двух Decimal не выходит за пределы int64.
+const decimalIntegerDigits = 12
+
+// Decimal — это точное десятичное число с не более чем шестью знаками после точки. Хранится как
+// целое число миллионных долей, поэтому сложение и сравнение не дают ошибок округления float64.
+// В JSON и SQL передается десятичной записью без потери точности.
+// @property {int64} micros - Значение в миллионных долях.
+type Decimal struct {
+	micros int64
+}
+
+// Возвращает Decimal, равный units * 10^-exponent. Экспонента должна быть от 0 до 6.
+func NewDecimal(units int64, exponent int) Decimal {
+	for i := exponent; i < decimalScale; i++ {
+		units *= 10
+	}
+
+	return Decimal{micros: units}
+}
+
+// Разбирает десятичную запись вида «-123.45». Экспоненциальная запись и больше шести значащих знаков
+// после точки не допускаются.
+func ParseDecimal(value string) (Decimal, error) {
+	input := value
+
+	negative := false
+	if strings.HasPrefix(value, "-") || strings.HasPrefix(value, "+") {
+		negative = value[0] == '-'
+		value = value[1:]
+	}
+
+	integer, fraction := value, ""
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		integer, fraction = value[:i], value[i+1:]
+	}
+
+	if (integer == "" && fraction == "") || !isDigits(integer) || !isDigits(fraction) {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", input)
+	}
+
+	integer = strings.TrimLeft(integer, "0")
+	if len(integer) > decimalIntegerDigits {
+		return Decimal{}, fmt.Errorf("decimal %q is out of range", input)
+	}
+
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > decimalScale {
+		return Decimal{}, fmt.Errorf("decimal %q has more than %d fractional digits", input, decimalScale)
+	}
+
+	digits := integer + fraction + strings.Repeat("0", decimalScale-len(fraction))
+
+	micros, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", input)
+	}
+
+	if negative {
+		micros = -micros
+	}
+
+	return Decimal{micros: micros}, nil
+}
+
+// Возвращает true, если строка состоит только из цифр.
+func isDigits(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if value[i] < '0' || value[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Возвращает самую короткую точную десятичную запись, например «10.5».
+func (d Decimal) String() string {
+	return d.StringFixed(0)
+}
+
+// Возвращает десятичную запись не менее чем с exponent знаками после точки, например «10.50» для
+// exponent 2. Значащие знаки не отбрасываются.
+func (d Decimal) StringFixed(exponent int) string {
+	micros := d.micros
+
+	sign := ""
+	if micros < 0 {
+		sign = "-"
+		micros = -micros
+	}
+
+	integer := strconv.FormatInt(micros/decimalUnit, 10)
+	fraction := fmt.Sprintf("%06d", micros%decimalUnit)
+
+	digits := decimalScale - d.trailingZeros()
+	if digits < exponent {
+		digits = exponent
+	}
+
+	if digits == 0 {
+		return sign + integer
+	}
+
+	return sign + integer + "." + fraction[:digits]
+}
+
+// Возвращает количество нулей в конце дробной части из шести знаков.
+func (d Decimal) trailingZeros() int {
+	micros := d.micros
+	if micros < 0 {
+		micros = -micros
+	}
+
+	zeros := 0
+	for zeros < decimalScale && micros%10 == 0 {
+		micros /= 10
+		zeros++
+	}
+
+	return zeros
+}
+
+// Возвращает количество значащих знаков после точки.
+func (d Decimal) Exponent() int {
+	return decimalScale - d.trailingZeros()
+}
+
+// Возвращает сумму d и other.
+func (d Decimal) Add(other Decimal) Decimal {
+	return Decimal{micros: d.micros + other.micros}
+}
+
+// Возвращает разность d и other.
+func (d Decimal) Sub(other Decimal) Decimal {
+	return Decimal{micros: d.micros - other.micros}
+}
+
+// Возвращает произведение d и other, округленное до exponent знаков после точки (половина - от нуля).
+func (d Decimal) Mul(other Decimal, exponent int) (Decimal, error) {
+	product := new(big.Int).Mul(big.NewInt(d.micros), big.NewInt(other.micros))
+
+	return decimalFromQuo(product, pow10(2*decimalScale-exponent), exponent)
+}
+
+// Возвращает 1/d, округленное до exponent знаков после точки (половина - от нуля).
+func (d Decimal) Inverse(exponent int) (Decimal, error) {
+	if d.IsZero() {
+		return Decimal{}, fmt.Errorf("division of one by zero")
+	}
+
+	return decimalFromQuo(pow10(decimalScale+exponent), big.NewInt(d.micros), exponent)
+}
+
+// Возвращает Decimal из частного num/den в единицах 10^-exponent, округленного половиной от нуля.
+func decimalFromQuo(num, den *big.Int, exponent int) (Decimal, error) {
+	if den.Sign() < 0 {
+		num, den = new(big.Int).Neg(num), new(big.Int).Neg(den)
+	}
+
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(den) >= 0 {
+		quo.Add(quo, big.NewInt(int64(num.Sign())))
+	}
+
+	micros := quo.Mul(quo, pow10(decimalScale-exponent))
+	if new(big.Int).Abs(micros).Cmp(pow10(decimalIntegerDigits+decimalScale)) >= 0 {
+		return Decimal{}, fmt.Errorf("decimal %s is out of range", micros.String())
+	}
+
+	return Decimal{micros: micros.Int64()}, nil
+}
+
+// Возвращает 10^n.
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// Возвращает -1, 0 или 1, если d меньше, равно или больше other.
+func (d Decimal) Cmp(other Decimal) int {
+	switch {
+	case d.micros < other.micros:
+		return -1
+	case d.micros > other.micros:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Сравнивает число с десятичной записью value. Нужен правилам gt, gte, lt и lte пакета validator.
+func (d Decimal) CompareString(value string) (int, error) {
+	other, err := ParseDecimal(value)
+	if err != nil {
+		return 0, err
+	}
+
+	return d.Cmp(other), nil
+}
+
+// Возвращает -1, 0 или 1 для отрицательного, нулевого и положительного числа.
+func (d Decimal) Sign() int {
+	return d.Cmp(Decimal{})
+}
+
+// Возвращает true, если число равно нулю.
+func (d Decimal) IsZero() bool {
+	return d.micros == 0
+}
+
+// Записывает число в JSON числом без потери точности.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Читает число из JSON-числа или строки. null оставляет значение без изменений.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	value := string(data)
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+
+	parsed, err := ParseDecimal(value)
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+
+	return nil
+}
+
+// Читает число из текстовой записи, например из YAML.
+func (d *Decimal) UnmarshalText(data []byte) error {
+	parsed, err := ParseDecimal(string(data))
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+
+	return nil
+}
+
+// Передает число в базу данных десятичной строкой, которую Postgres приводит к numeric без потерь.
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Читает число из столбца numeric. Драйвер Postgres отдает numeric текстом.
+func (d *Decimal) Scan(src interface{}) error {
+	var value string
+
+	switch v := src.(type) {
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	case int64:
+		*d = NewDecimal(v, 0)
+		return nil
+	case float64:
+		value = strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		*d = Decimal{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Decimal", src)
+	}
+
+	parsed, err := ParseDecimal(value)
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+
+	return nil
+}
+
+// Money — это сумма в конкретной валюте. Создается через NewMoney, который проверяет, что у суммы не
+// больше знаков после точки, чем допускает валюта.
+// @property {Decimal} Amount - Сумма.
+// @property {currency.Currency} Currency - Валюта реестра.
+type Money struct {
+	Amount   Decimal
+	Currency currency.Currency
+}
+
+// Возвращает сумму в валюте cur. Возвращает ErrInvalidAmount, если у суммы больше знаков после точки,
+// чем экспонента валюты.
+func NewMoney(amount Decimal, cur currency.Currency) (Money, error) {
+	if amount.Exponent() > cur.MinorUnits {
+		return Money{}, fmt.Errorf("%w: %s has more than %d fractional digits for %s", ErrInvalidAmount, amount.String(), cur.MinorUnits, cur.Code)
+	}
+
+	return Money{
+		Amount:   amount,
+		Currency: cur,
+	}, nil
+}
+
+// Возвращает сумму в валюте с кодом code по списку ISO 4217. Для валюты вне списка экспонента
+// считается равной 2.
+func moneyOf(amount Decimal, code string) Money {
+	cur, ok := currency.Find(code)
+	if !ok {
+		cur = currency.Currency{Code: code, MinorUnits: 2}
+	}
+
+	return Money{
+		Amount:   amount,
+		Currency: cur,
+	}
+}
+
+// Возвращает сумму с количеством знаков после точки, равным экспоненте валюты, например «10.50».
+func (m Money) Format() string {
+	return m.Amount.StringFixed(m.Currency.MinorUnits)
+}
+
+// Возвращает сумму в минимальных единицах валюты, например в центах.
+func (m Money) MinorUnits() int64 {
+	micros := m.Amount.micros
+	for i := m.Currency.MinorUnits; i < decimalScale; i++ {
+		micros /= 10
+	}
+
+	return micros
+}
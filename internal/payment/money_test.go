@@ -0,0 +1,162 @@
+package payment
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/onlycodergod/payment-api-emulator/internal/currency"
+	"github.com/stretchr/testify/assert"
+)
+
+// Он разбирает десятичную запись и падает, если она некорректна.
+func mustDecimal(value string) Decimal {
+	output, err := ParseDecimal(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return output
+}
+
+// Он проверяет разбор и запись десятичных чисел.
+func TestParseDecimal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		input  string
+		expect string
+		err    bool
+	}{
+		{name: "Integer", input: "10", expect: "10"},
+		{name: "Fraction", input: "10.50", expect: "10.5"},
+		{name: "Leading dot", input: ".25", expect: "0.25"},
+		{name: "Negative", input: "-0.000001", expect: "-0.000001"},
+		{name: "Seven fractional digits", input: "0.0000001", err: true},
+		{name: "Exponent notation", input: "1e3", err: true},
+		{name: "Out of range", input: "1000000000000", err: true},
+		{name: "Empty", input: "", err: true},
+		{name: "Only sign", input: "-", err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDecimal(tt.input)
+
+		if tt.err {
+			assert.Error(t, err, tt.name)
+		} else {
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, tt.expect, got.String(), tt.name)
+		}
+	}
+}
+
+// Он проверяет, что сложение не дает ошибок округления.
+func TestDecimalAdd(t *testing.T) {
+	t.Parallel()
+
+	sum := mustDecimal("0.1").Add(mustDecimal("0.2"))
+
+	assert.Equal(t, 0, sum.Cmp(mustDecimal("0.3")))
+	assert.Equal(t, "0.3", sum.String())
+	assert.Equal(t, "0.1", sum.Sub(mustDecimal("0.2")).String())
+}
+
+// Он проверяет умножение и обратное число с округлением половины от нуля.
+func TestDecimalMul(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		value    string
+		other    string
+		exponent int
+		expect   string
+	}{
+		{name: "Exact", value: "10.5", other: "2", exponent: 2, expect: "21"},
+		{name: "Round half up", value: "0.125", other: "1", exponent: 2, expect: "0.13"},
+		{name: "Round half away from zero", value: "-0.125", other: "1", exponent: 2, expect: "-0.13"},
+		{name: "Round to units", value: "1.01", other: "136.5", exponent: 0, expect: "138"},
+	}
+
+	for _, tt := range tests {
+		got, err := mustDecimal(tt.value).Mul(mustDecimal(tt.other), tt.exponent)
+
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.expect, got.String(), tt.name)
+	}
+
+	_, err := mustDecimal("999999999999").Mul(mustDecimal("10"), 2)
+	assert.Error(t, err)
+
+	inverse, err := mustDecimal("3").Inverse(6)
+	assert.NoError(t, err)
+	assert.Equal(t, "0.333333", inverse.String())
+
+	_, err = Decimal{}.Inverse(6)
+	assert.Error(t, err)
+}
+
+// Он проверяет, что число без изменений проходит через JSON и SQL.
+func TestDecimalRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	input := PaymentInput{Amount: mustDecimal("1234567.89")}
+
+	data, err := json.Marshal(input)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"amount":1234567.89`)
+
+	var output PaymentInput
+	assert.NoError(t, json.Unmarshal(data, &output))
+	assert.Equal(t, input.Amount, output.Amount)
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount":"0.30"}`), &output))
+	assert.Equal(t, mustDecimal("0.3"), output.Amount)
+
+	value, err := input.Amount.Value()
+	assert.NoError(t, err)
+
+	var scanned Decimal
+	assert.NoError(t, scanned.Scan([]byte(value.(string))))
+	assert.Equal(t, input.Amount, scanned)
+
+	assert.NoError(t, scanned.Scan(10.13))
+	assert.Equal(t, mustDecimal("10.13"), scanned)
+}
+
+// Он проверяет, что сумма не может иметь больше знаков после точки, чем допускает валюта.
+func TestNewMoney(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		amount   string
+		currency string
+		expect   string
+		minor    int64
+		err      error
+	}{
+		{name: "Cents", amount: "10.5", currency: "usd", expect: "10.50", minor: 1050},
+		{name: "Integer", amount: "7", currency: "rub", expect: "7.00", minor: 700},
+		{name: "Three minor units", amount: "1.5", currency: "kwd", expect: "1.500", minor: 1500},
+		{name: "No minor units", amount: "500", currency: "jpy", expect: "500", minor: 500},
+		{name: "Too many fractional digits", amount: "10.505", currency: "eur", err: ErrInvalidAmount},
+		{name: "Fraction for currency without minor units", amount: "1.5", currency: "jpy", err: ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		cur, ok := currency.Find(tt.currency)
+		assert.True(t, ok, tt.name)
+
+		got, err := NewMoney(mustDecimal(tt.amount), cur)
+
+		if tt.err != nil {
+			assert.ErrorIs(t, err, tt.err, tt.name)
+		} else {
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, tt.expect, got.Format(), tt.name)
+			assert.Equal(t, tt.minor, got.MinorUnits(), tt.name)
+		}
+	}
+}
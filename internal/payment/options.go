@@ -0,0 +1,10 @@
+package payment
+
+import "time"
+
+// Options — это структура с настройками варианта использования платежей.
+// @property {time.Duration} IdempotencyTTL - Время, в течение которого сохраненный ответ
+// возвращается повторно по тому же Idempotency-Key.
+type Options struct {
+	IdempotencyTTL time.Duration
+}
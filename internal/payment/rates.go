@@ -0,0 +1,276 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/onlycodergod/payment-api-emulator/internal/currency"
+)
+
+// Источник курса, который сохраняется вместе с платежом. «drift» - курс из файла или API со случайным
+// отклонением.
+const (
+	RateSourceIdentity = "identity"
+	RateSourceFile     = "file"
+	RateSourceAdmin    = "admin"
+	RateSourceDrift    = "drift"
+)
+
+// Количество знаков после точки у курса.
+const rateExponent = decimalScale
+
+// Rate — это курс обмена одной единицы валюты From на валюту To.
+// @property {string} From - Валюта платежа.
+// @property {string} To - Валюта расчетов.
+// @property {Decimal} Rate - Сколько единиц To стоит одна единица From.
+// @property {string} Source - Откуда взят курс: «file» или «admin».
+// @property {time.Time} UpdatedAt - Момент загрузки курса.
+type Rate struct {
+	From      string    `json:"from" yaml:"from"`
+	To        string    `json:"to" yaml:"to"`
+	Rate      Decimal   `json:"rate" yaml:"rate"`
+	Source    string    `json:"source,omitempty" yaml:"-"`
+	UpdatedAt time.Time `json:"updated_at,omitempty" yaml:"-"`
+}
+
+// Rates — это таблица курсов из YAML-файла.
+// @property {[]Rate} Rates - Курсы. Обратный курс вычисляется, если он не задан явно.
+type Rates struct {
+	Rates []Rate `yaml:"rates"`
+}
+
+// Settlement — это расчет платежа в валюте расчетов.
+// @property {string} Currency - Валюта расчетов.
+// @property {Decimal} Amount - Сумма в валюте расчетов, округленная до экспоненты валюты.
+// @property {Decimal} Rate - Примененный курс.
+// @property {string} Source - Источник курса: «identity», «file», «admin» или «drift».
+// @property {time.Time} RatedAt - Момент получения курса.
+type Settlement struct {
+	Currency string
+	Amount   Decimal
+	Rate     Decimal
+	Source   string
+	RatedAt  time.Time
+}
+
+// Читает таблицу курсов из YAML-файла и проверяет ее.
+func LoadRates(path string) ([]Rate, error) {
+	var rates Rates
+
+	if err := cleanenv.ReadConfig(path, &rates); err != nil {
+		return nil, fmt.Errorf("payment-LoadRates, %s", err.Error())
+	}
+
+	if err := validateRates(rates.Rates); err != nil {
+		return nil, fmt.Errorf("payment-LoadRates, %s", err.Error())
+	}
+
+	return rates.Rates, nil
+}
+
+// Проверяет, что курсы положительные, валюты пары различаются и пара задана один раз.
+func validateRates(rates []Rate) error {
+	seen := make(map[string]struct{}, len(rates))
+
+	for i, rate := range rates {
+		from, to := strings.ToLower(rate.From), strings.ToLower(rate.To)
+		if from == "" || to == "" || from == to {
+			return fmt.Errorf("rate %d: from and to must be different currencies", i)
+		}
+
+		if rate.Rate.Sign() <= 0 {
+			return fmt.Errorf("rate %s/%s: rate must be positive", from, to)
+		}
+
+		if _, ok := seen[from+"/"+to]; ok {
+			return fmt.Errorf("rate %s/%s: duplicate pair", from, to)
+		}
+
+		seen[from+"/"+to] = struct{}{}
+	}
+
+	return nil
+}
+
+// RateOptions — это структура с настройками таблицы курсов.
+// @property {int64} Seed - Начальное значение генератора отклонения курса, 0 - случайный seed.
+// @property {float64} Drift - Наибольшее относительное отклонение курса при каждом расчете, например
+// 0.01 - до 1% в обе стороны. 0 - курс не отклоняется.
+type RateOptions struct {
+	Seed  int64
+	Drift float64
+}
+
+// RateTable — это потокобезопасная таблица курсов обмена. Курсы загружаются из файла или задаются через
+// /admin/fx/rates, при ненулевом Drift каждый расчет отклоняет курс на случайную величину.
+// @property mu - Мьютекс, который защищает курсы, настройки и генератор.
+// @property rates - Курсы по паре «from/to».
+// @property {int64} seed - Начальное значение генератора отклонения.
+// @property {float64} drift - Наибольшее относительное отклонение курса.
+// @property random - Генератор случайных чисел для отклонения.
+// @property now - Источник текущего времени.
+type RateTable struct {
+	mu     sync.Mutex
+	rates  map[string]Rate
+	seed   int64
+	drift  float64
+	random *rand.Rand
+	now    func() time.Time
+}
+
+// > Эта функция создает новый экземпляр структуры RateTable без курсов и возвращает указатель на нее.
+// Одинаковый seed дает одинаковую последовательность отклонений, 0 - случайный seed.
+func NewRateTable(options RateOptions) (*RateTable, error) {
+	t := &RateTable{
+		rates: make(map[string]Rate),
+		now:   time.Now,
+	}
+
+	if err := t.Configure(options.Seed, options.Drift); err != nil {
+		return nil, fmt.Errorf("payment-NewRateTable, %s", err.Error())
+	}
+
+	return t, nil
+}
+
+// Меняет отклонение курса и пересоздает генератор с новым seed. 0 - случайный seed.
+func (t *RateTable) Configure(seed int64, drift float64) error {
+	if drift < 0 || drift >= 1 {
+		return errors.New("drift must be at least 0 and less than 1")
+	}
+
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.seed = seed
+	t.drift = drift
+	t.random = rand.New(rand.NewSource(seed))
+
+	return nil
+}
+
+// Заменяет все курсы. Курсы проверяются до замены, при ошибке старые курсы сохраняются.
+func (t *RateTable) SetRates(rates []Rate, source string) error {
+	if err := validateRates(rates); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now().UTC()
+
+	t.rates = make(map[string]Rate, len(rates))
+	for _, rate := range rates {
+		rate.From = strings.ToLower(rate.From)
+		rate.To = strings.ToLower(rate.To)
+		rate.Source = source
+		rate.UpdatedAt = now
+
+		t.rates[rate.From+"/"+rate.To] = rate
+	}
+
+	return nil
+}
+
+// Возвращает seed, отклонение и копию курсов, упорядоченную по паре.
+func (t *RateTable) Rates() (int64, float64, []Rate) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rates := make([]Rate, 0, len(t.rates))
+	for _, rate := range t.rates {
+		rates = append(rates, rate)
+	}
+
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].From+"/"+rates[i].To < rates[j].From+"/"+rates[j].To
+	})
+
+	return t.seed, t.drift, rates
+}
+
+// Пересчитывает сумму в валюту расчетов. Для одной и той же валюты курс равен 1. Если задан только
+// обратный курс, используется 1/курс. Возвращает ErrRateNotFound, если курса нет, и ErrInvalidAmount,
+// если сумма после округления до экспоненты валюты расчетов стала нулевой.
+func (t *RateTable) Convert(amount Money, to currency.Currency) (Settlement, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now().UTC()
+
+	if amount.Currency.Code == to.Code {
+		return Settlement{
+			Currency: to.Code,
+			Amount:   amount.Amount,
+			Rate:     NewDecimal(1, 0),
+			Source:   RateSourceIdentity,
+			RatedAt:  now,
+		}, nil
+	}
+
+	rate, source, err := t.quote(amount.Currency.Code, to.Code)
+	if err != nil {
+		return Settlement{}, err
+	}
+
+	settled, err := amount.Amount.Mul(rate, to.MinorUnits)
+	if err != nil {
+		return Settlement{}, fmt.Errorf("%w: %s", ErrInvalidAmount, err.Error())
+	}
+
+	if settled.Sign() <= 0 {
+		return Settlement{}, fmt.Errorf("%w: %s %s is zero in %s", ErrInvalidAmount, amount.Amount.String(), amount.Currency.Code, to.Code)
+	}
+
+	return Settlement{
+		Currency: to.Code,
+		Amount:   settled,
+		Rate:     rate,
+		Source:   source,
+		RatedAt:  now,
+	}, nil
+}
+
+// Возвращает курс пары с отклонением и его источник. Вызывается под блокировкой.
+func (t *RateTable) quote(from, to string) (Decimal, string, error) {
+	var rate Decimal
+	var source string
+
+	if value, ok := t.rates[from+"/"+to]; ok {
+		rate, source = value.Rate, value.Source
+	} else if value, ok := t.rates[to+"/"+from]; ok {
+		inverse, err := value.Rate.Inverse(rateExponent)
+		if err != nil || inverse.IsZero() {
+			return Decimal{}, "", fmt.Errorf("%w: %s/%s", ErrRateNotFound, from, to)
+		}
+
+		rate, source = inverse, value.Source
+	} else {
+		return Decimal{}, "", fmt.Errorf("%w: %s/%s", ErrRateNotFound, from, to)
+	}
+
+	if t.drift == 0 {
+		return rate, source, nil
+	}
+
+	factor := NewDecimal(int64(math.Round((1+(t.random.Float64()*2-1)*t.drift)*decimalUnit)), decimalScale)
+
+	drifted, err := rate.Mul(factor, rateExponent)
+	if err != nil || drifted.Sign() <= 0 {
+		return rate, source, nil
+	}
+
+	return drifted, RateSourceDrift, nil
+}
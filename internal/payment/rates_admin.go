@@ -0,0 +1,99 @@
+package payment
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/apierror"
+)
+
+// Это константа, определяющая маршрут.
+const FXRates = "/admin/fx/rates"
+
+// RateSettings — это таблица курсов и настройки отклонения, которые читает и меняет административный
+// эндпоинт.
+// @property {int64} Seed - Начальное значение генератора отклонения. При изменении 0 оставляет текущий
+// генератор.
+// @property {*float64} Drift - Наибольшее относительное отклонение курса. При изменении не передается,
+// чтобы оставить текущее.
+// @property {[]Rate} Rates - Курсы.
+type RateSettings struct {
+	Seed  int64    `json:"seed"`
+	Drift *float64 `json:"drift"`
+	Rates []Rate   `json:"rates"`
+}
+
+// Эта функция регистрирует административные обработчики таблицы курсов.
+func (t *RateTable) Register(router *mux.Router) *mux.Router {
+	router.HandleFunc(FXRates, t.GetSettings).Methods(http.MethodGet)
+	router.HandleFunc(FXRates, t.PutSettings).Methods(http.MethodPut)
+	return router
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/admin/fx/rates` методом `GET`.
+func (t *RateTable) GetSettings(w http.ResponseWriter, r *http.Request) {
+	seed, drift, rates := t.Rates()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(
+		RateSettings{
+			Seed:  seed,
+			Drift: &drift,
+			Rates: rates,
+		},
+	)
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/admin/fx/rates` методом `PUT`. Заменяет все курсы и, если переданы seed или drift, меняет
+// отклонение.
+func (t *RateTable) PutSettings(w http.ResponseWriter, r *http.Request) {
+	var input RateSettings
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.Body{
+			Code:    CodeInvalidBody,
+			Message: InvalidBodyData,
+		})
+		return
+	}
+
+	if err := validateRates(input.Rates); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.Body{
+			Code:    CodeInvalidRates,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if input.Seed != 0 || input.Drift != nil {
+		seed, drift, _ := t.Rates()
+		if input.Seed != 0 {
+			seed = input.Seed
+		}
+
+		if input.Drift != nil {
+			drift = *input.Drift
+		}
+
+		if err := t.Configure(seed, drift); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.Body{
+				Code:    CodeInvalidRates,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := t.SetRates(input.Rates, RateSourceAdmin); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.Body{
+			Code:    CodeInvalidRates,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	t.GetSettings(w, r)
+}
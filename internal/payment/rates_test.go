@@ -0,0 +1,144 @@
+package payment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onlycodergod/payment-api-emulator/internal/currency"
+	"github.com/stretchr/testify/assert"
+)
+
+// Он возвращает валюту из списка ISO 4217 и падает, если ее нет.
+func mustCurrency(code string) currency.Currency {
+	value, ok := currency.Find(code)
+	if !ok {
+		panic("unknown currency " + code)
+	}
+
+	return value
+}
+
+// Он проверяет, что пример таблицы курсов из config читается и проходит проверку.
+func TestLoadRates(t *testing.T) {
+	t.Parallel()
+
+	rates, err := LoadRates("../../config/rates.yml")
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, rates)
+	assert.Equal(t, Rate{From: "usd", To: "eur", Rate: mustDecimal("0.98")}, rates[0])
+}
+
+// Он проверяет пересчет суммы в валюту расчетов.
+func TestRateTableConvert(t *testing.T) {
+	t.Parallel()
+
+	ratedAt := time.Date(2022, 7, 20, 12, 0, 0, 0, time.UTC)
+
+	table, err := NewRateTable(RateOptions{Seed: 1})
+	assert.NoError(t, err)
+
+	table.now = func() time.Time { return ratedAt }
+	assert.NoError(t, table.SetRates(
+		[]Rate{
+			{From: "usd", To: "eur", Rate: mustDecimal("0.98")},
+			{From: "USD", To: "JPY", Rate: mustDecimal("136.5")},
+			{From: "usd", To: "rub", Rate: mustDecimal("0.000001")},
+		},
+		RateSourceFile,
+	))
+
+	tests := []struct {
+		name   string
+		amount string
+		from   string
+		to     string
+		expect Settlement
+		err    error
+	}{
+		{
+			name:   "Same currency",
+			amount: "10.5",
+			from:   "usd",
+			to:     "usd",
+			expect: Settlement{Currency: "usd", Amount: mustDecimal("10.5"), Rate: mustDecimal("1"), Source: RateSourceIdentity, RatedAt: ratedAt},
+		},
+		{
+			name:   "Direct rate rounded to cents",
+			amount: "10.55",
+			from:   "usd",
+			to:     "eur",
+			expect: Settlement{Currency: "eur", Amount: mustDecimal("10.34"), Rate: mustDecimal("0.98"), Source: RateSourceFile, RatedAt: ratedAt},
+		},
+		{
+			name:   "Rounded to currency without minor units",
+			amount: "1.01",
+			from:   "usd",
+			to:     "jpy",
+			expect: Settlement{Currency: "jpy", Amount: mustDecimal("138"), Rate: mustDecimal("136.5"), Source: RateSourceFile, RatedAt: ratedAt},
+		},
+		{
+			name:   "Inverse rate",
+			amount: "98",
+			from:   "eur",
+			to:     "usd",
+			expect: Settlement{Currency: "usd", Amount: mustDecimal("100"), Rate: mustDecimal("1.020408"), Source: RateSourceFile, RatedAt: ratedAt},
+		},
+		{
+			name:   "No rate",
+			amount: "1",
+			from:   "eur",
+			to:     "jpy",
+			err:    ErrRateNotFound,
+		},
+		{
+			name:   "Zero after rounding",
+			amount: "1",
+			from:   "usd",
+			to:     "rub",
+			err:    ErrInvalidAmount,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := table.Convert(Money{Amount: mustDecimal(tt.amount), Currency: mustCurrency(tt.from)}, mustCurrency(tt.to))
+
+		if tt.err != nil {
+			assert.ErrorIs(t, err, tt.err, tt.name)
+		} else {
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, tt.expect, got, tt.name)
+		}
+	}
+}
+
+// Он проверяет, что отклонение курса ограничено drift и повторяется при одинаковом seed.
+func TestRateTableDrift(t *testing.T) {
+	t.Parallel()
+
+	sequence := func() []Settlement {
+		table, err := NewRateTable(RateOptions{Seed: 42, Drift: 0.01})
+		assert.NoError(t, err)
+
+		assert.NoError(t, table.SetRates([]Rate{{From: "usd", To: "eur", Rate: mustDecimal("1")}}, RateSourceAdmin))
+
+		output := make([]Settlement, 0, 20)
+		for n := 0; n < 20; n++ {
+			value, err := table.Convert(Money{Amount: mustDecimal("100"), Currency: mustCurrency("usd")}, mustCurrency("eur"))
+			assert.NoError(t, err)
+
+			assert.Equal(t, RateSourceDrift, value.Source)
+			assert.True(t, value.Rate.Cmp(mustDecimal("0.99")) >= 0 && value.Rate.Cmp(mustDecimal("1.01")) <= 0, value.Rate.String())
+
+			value.RatedAt = time.Time{}
+			output = append(output, value)
+		}
+
+		return output
+	}
+
+	assert.Equal(t, sequence(), sequence())
+
+	_, err := NewRateTable(RateOptions{Drift: 1})
+	assert.Error(t, err)
+}
@@ -4,9 +4,82 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
+
+	"github.com/onlycodergod/payment-api-emulator/internal/currency"
+	"github.com/onlycodergod/payment-api-emulator/internal/outbox"
+	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
+)
+
+const (
+	payments        = "payments"
+	idempotencyKeys = "idempotency_keys"
+	refunds         = "refunds"
+	paymentOutbox   = "payment_outbox"
+	statusHistory   = "payment_status_history"
+	currencies      = "currencies"
 )
 
-const payments = "payments"
+// Столбцы платежа в порядке, в котором их читает scanPayment.
+const paymentColumns = `id,
+						user_id,
+						user_email,
+						currency,
+						amount,
+						created_at,
+						updated_at,
+						status,
+						refunded_amount,
+						settlement_currency,
+						settlement_amount,
+						fx_rate,
+						fx_source,
+						fx_rated_at,
+						captured_amount,
+						authorized_at,
+						version`
+
+// scanner — это строка результата запроса: *sql.Row или *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// Читает платеж из строки со столбцами paymentColumns и вычисляет состояние возврата.
+func scanPayment(row scanner) (payment, error) {
+	value := payment{}
+	var authorizedAt sql.NullString
+
+	err := row.Scan(
+		&value.ID,
+		&value.UserID,
+		&value.UserEmail,
+		&value.Currency,
+		&value.Amount,
+		&value.CreatedAt,
+		&value.UpdatedAt,
+		&value.Status,
+		&value.RefundedAmount,
+		&value.SettlementCurrency,
+		&value.SettlementAmount,
+		&value.FXRate,
+		&value.FXSource,
+		&value.FXRatedAt,
+		&value.CapturedAmount,
+		&authorizedAt,
+		&value.Version,
+	)
+	if err != nil {
+		return payment{}, err
+	}
+
+	value.AuthorizedAt = authorizedAt.String
+	value.deriveRefundStatus()
+
+	return value, nil
+}
+
+// Максимальная длина исполнителя, как у столбца payment_outbox.actor.
+const actorLength = 64
 
 // Репозиторий — это структура с полем db, которое является указателем на sql.DB.
 // @property db - Это указатель на объект sql.DB, который будет использоваться для подключения к базе
@@ -22,10 +95,49 @@ func NewPaymentRepository(db *sql.DB) *repository {
 	}
 }
 
-// Создание нового платежа.
+// txKey — это ключ контекста, под которым WithinTransaction хранит открытую транзакцию.
+type txKey struct{}
+
+// querier — это общие методы *sql.DB и *sql.Tx, через которые выполняются запросы.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Возвращает транзакцию из контекста, если запрос выполняется внутри WithinTransaction, иначе
+// соединение с базой данных.
+func (r *repository) conn(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+
+	return r.db
+}
+
+// Выполняет fn в одной транзакции. Транзакция передается в fn через контекст, поэтому все методы
+// репозитория, вызванные с этим контекстом, выполняются в ней. Вложенный вызов присоединяется к
+// внешней транзакции. При ошибке fn транзакция откатывается.
+func (r *repository) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// Создание нового платежа. Вместе с платежом в той же транзакции записывается событие outbox.
 func (r *repository) CreatePayment(ctx context.Context, input PaymentInput) (int64, error) {
-	const format = `INSERT INTO %s (user_id, user_email, amount, currency)
-						VALUES ($1, $2, $3, $4)
+	const format = `INSERT INTO %s (
+						user_id,
+						user_email,
+						amount,
+						currency,
+						settlement_currency,
+						settlement_amount,
+						fx_rate,
+						fx_source,
+						fx_rated_at
+					)
+						VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 					RETURNING id`
 
 	query := fmt.Sprintf(
@@ -33,25 +145,35 @@ func (r *repository) CreatePayment(ctx context.Context, input PaymentInput) (int
 		payments,
 	)
 
-	row := r.db.QueryRowContext(
-		ctx,
-		query,
-		input.UserID,
-		input.UserEmail,
-		input.Amount,
-		input.Currency,
-	)
-
-	if err := row.Err(); err != nil {
-		return 0, fmt.Errorf("payment-repository-CreatePayment, %s", err.Error())
-	}
+	settlement := input.settlement(time.Now().UTC())
 
 	var id int64
-	if err := row.Scan(&id); err != nil {
-		if err == sql.ErrNoRows {
-			return 0, fmt.Errorf("payment-repository-CreatePayment, %s", "no result")
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(
+			ctx,
+			query,
+			input.UserID,
+			input.UserEmail,
+			input.Amount,
+			input.Currency,
+			settlement.Currency,
+			settlement.Amount,
+			settlement.Rate,
+			settlement.Source,
+			settlement.RatedAt,
+		)
+
+		if err := row.Scan(&id); err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("%s", "no result")
+			}
+
+			return err
 		}
 
+		return r.recordTransition(ctx, tx, id, "", StatusNew, "")
+	})
+	if err != nil {
 		return 0, fmt.Errorf("payment-repository-CreatePayment, %s", err.Error())
 	}
 
@@ -60,28 +182,120 @@ func (r *repository) CreatePayment(ctx context.Context, input PaymentInput) (int
 
 // Обновление статуса платежа.
 func (r *repository) UpdateStatus(ctx context.Context, input PaymentStatus) (int64, error) {
-	const format = `UPDATE %s SET status = $1
-						WHERE id = $2
-						AND status NOT IN ($3, $4)`
+	rows, err := r.setStatus(ctx, input.ID, input.Status, input.Reason, input.Version, paymentStates.Sources(input.Status))
+	if err != nil {
+		return 0, fmt.Errorf("payment-reposiroty-UpdateStatus, %s", err.Error())
+	}
+
+	return rows, nil
+}
+
+// Переводит платеж в статус to, если текущий статус входит в sources, и в той же транзакции
+// записывает событие outbox и историю. Переход в «success» списывает всю сумму платежа, переход в
+// «authorized» запоминает время авторизации. Ненулевая version должна совпадать с версией платежа.
+// Возвращает количество измененных строк.
+func (r *repository) setStatus(ctx context.Context, PaymentID int64, to, reason string, version int64, sources []string) (int64, error) {
+	const format = `UPDATE %[1]s p SET status = $1,
+						captured_amount = CASE WHEN $1 = '%[3]s' THEN p.amount ELSE p.captured_amount END,
+						authorized_at = CASE WHEN $1 = '%[4]s' THEN NOW() ELSE p.authorized_at END
+						FROM (SELECT id, status, version from %[1]s WHERE id = $2 FOR UPDATE) old
+						WHERE p.id = old.id
+						AND old.status IN (%[2]s)%[5]s
+					RETURNING old.status`
+
+	if len(sources) == 0 {
+		return 0, nil
+	}
+
+	args := []interface{}{to, PaymentID}
+	for _, status := range sources {
+		args = append(args, status)
+	}
+
+	// Версия проверяется, только если клиент передал If-Match.
+	var versionClause string
+	if version != 0 {
+		args = append(args, version)
+		versionClause = fmt.Sprintf(" AND old.version = $%d", len(args))
+	}
 
 	query := fmt.Sprintf(
 		format,
 		payments,
+		placeholders(3, len(sources)),
+		StatusSuccess,
+		StatusAuthorized,
+		versionClause,
 	)
 
-	rows, err := r.db.ExecContext(
+	var rows int64
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		var from string
+
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			args...,
+		).Scan(&from)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		rows = 1
+
+		return r.recordTransition(ctx, tx, PaymentID, from, to, reason)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return rows, nil
+}
+
+// Записывает событие outbox и строку истории о переходе статуса платежа в транзакции tx.
+// Исполнитель берется из контекста.
+func (r *repository) recordTransition(ctx context.Context, tx *sql.Tx, PaymentID int64, from, to, reason string) error {
+	const outboxFormat = `INSERT INTO %s (payment_id, old_status, new_status, reason, actor)
+						VALUES ($1, $2, $3, $4, $5)`
+
+	const historyFormat = `INSERT INTO %s (payment_id, from_status, to_status, reason, actor)
+						VALUES ($1, $2, $3, $4, $5)`
+
+	var oldStatus interface{}
+	if from != "" {
+		oldStatus = from
+	}
+
+	actor := truncate(ActorFromContext(ctx), actorLength)
+
+	_, err := tx.ExecContext(
 		ctx,
-		query,
-		input.Status,
-		input.ID,
-		StatusSuccess,
-		StatusFailure,
+		fmt.Sprintf(outboxFormat, paymentOutbox),
+		PaymentID,
+		oldStatus,
+		to,
+		reason,
+		actor,
 	)
 	if err != nil {
-		return 0, fmt.Errorf("payment-reposiroty-UpdateStatus, %s", err.Error())
+		return err
 	}
 
-	return rows.RowsAffected()
+	_, err = tx.ExecContext(
+		ctx,
+		fmt.Sprintf(historyFormat, statusHistory),
+		PaymentID,
+		oldStatus,
+		to,
+		reason,
+		actor,
+	)
+
+	return err
 }
 
 // Получение статуса платежа.
@@ -94,7 +308,7 @@ func (r *repository) GetStatus(ctx context.Context, PaymentID int64) (string, er
 		payments,
 	)
 
-	rows := r.db.QueryRowContext(
+	rows := r.conn(ctx).QueryRowContext(
 		ctx,
 		query,
 		PaymentID,
@@ -103,7 +317,7 @@ func (r *repository) GetStatus(ctx context.Context, PaymentID int64) (string, er
 	var status string
 	if err := rows.Scan(&status); err != nil {
 		if err == sql.ErrNoRows {
-			return "", fmt.Errorf("payment-repository-CreatePayment, %s", "no result")
+			return "", fmt.Errorf("payment-repository-GetStatus, %w", ErrPaymentNotFound)
 		}
 
 		return "", fmt.Errorf("payment-reposiroty-GetStatus, %s", err.Error())
@@ -112,104 +326,746 @@ func (r *repository) GetStatus(ctx context.Context, PaymentID int64) (string, er
 	return status, nil
 }
 
-// Функция, которая возвращает срез платежей.
+// Функция, которая возвращает платеж по его ID.
+func (r *repository) GetPayment(ctx context.Context, PaymentID int64) (payment, error) {
+	const format = `SELECT %s from %s
+						WHERE id = $1`
+
+	value, err := r.findPayment(ctx, format, PaymentID)
+	if err != nil {
+		return payment{}, fmt.Errorf("payment-repository-GetPayment, %w", err)
+	}
+
+	return value, nil
+}
+
+// Функция, которая возвращает платеж по его ID и блокирует его строку до конца транзакции. Вызывается
+// внутри WithinTransaction, чтобы проверка статуса и его изменение не пересекались с другими
+// запросами.
+func (r *repository) LockPayment(ctx context.Context, PaymentID int64) (payment, error) {
+	const format = `SELECT %s from %s
+						WHERE id = $1
+					FOR UPDATE`
+
+	value, err := r.findPayment(ctx, format, PaymentID)
+	if err != nil {
+		return payment{}, fmt.Errorf("payment-repository-LockPayment, %w", err)
+	}
+
+	return value, nil
+}
+
+// Читает платеж по ID запросом format со столбцами paymentColumns. Возвращает ErrPaymentNotFound, если
+// платежа нет.
+func (r *repository) findPayment(ctx context.Context, format string, PaymentID int64) (payment, error) {
+	query := fmt.Sprintf(
+		format,
+		paymentColumns,
+		payments,
+	)
+
+	value, err := scanPayment(
+		r.conn(ctx).QueryRowContext(
+			ctx,
+			query,
+			PaymentID,
+		),
+	)
+	if err == sql.ErrNoRows {
+		return payment{}, ErrPaymentNotFound
+	}
+
+	return value, err
+}
+
+// Функция, которая возвращает страницу платежей пользователя по его ID или email с фильтрами.
+// Возвращается до Limit+1 строк: лишняя строка означает, что есть следующая страница.
 func (r *repository) GetPayments(ctx context.Context, input PaymentUser) ([]payment, error) {
-	var arg string
-	var value interface{}
+	const format = `SELECT %s from %s
+						WHERE %s
+					ORDER BY id %s
+					LIMIT $%d`
+
+	where := &whereBuilder{}
 
-	if input.UserEmail != "" && input.UserID == 0 {
-		arg = "user_email"
-		value = input.UserEmail
+	if input.UserID != 0 {
+		where.add("user_id = ?", input.UserID)
+	} else {
+		where.add("user_email = ?", input.UserEmail)
 	}
 
-	if input.UserID != 0 && input.UserEmail == "" {
-		arg = "user_id"
-		value = input.UserID
+	order := "ASC"
+	if input.Sort == SortDesc {
+		order = "DESC"
 	}
 
+	if input.AfterID != 0 {
+		if input.Sort == SortDesc {
+			where.add("id < ?", input.AfterID)
+		} else {
+			where.add("id > ?", input.AfterID)
+		}
+	}
+
+	if input.Status != "" {
+		where.add("status = ?", input.Status)
+	}
+
+	if input.Currency != "" {
+		where.add("currency = ?", input.Currency)
+	}
+
+	if !input.MinAmount.IsZero() {
+		where.add("amount >= ?", input.MinAmount)
+	}
+
+	if !input.MaxAmount.IsZero() {
+		where.add("amount <= ?", input.MaxAmount)
+	}
+
+	if !input.CreatedFrom.IsZero() {
+		where.add("created_at >= ?", input.CreatedFrom)
+	}
+
+	if !input.CreatedTo.IsZero() {
+		where.add("created_at < ?", input.CreatedTo)
+	}
+
+	limit := input.Limit
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+
+	query := fmt.Sprintf(
+		format,
+		paymentColumns,
+		payments,
+		where.String(),
+		order,
+		where.next(),
+	)
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		append(where.args, limit+1)...,
+	)
+	if err != nil {
+		return []payment{}, fmt.Errorf("payment-reposiroty-GetPayments, %s", err.Error())
+	}
+
+	defer rows.Close()
+
+	output := make([]payment, 0)
+	for rows.Next() {
+		value, err := scanPayment(rows)
+		if err != nil {
+			return []payment{}, fmt.Errorf("payment-reposiroty-GetPayments, %s", err.Error())
+		}
+
+		output = append(output, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []payment{}, fmt.Errorf("payment-reposiroty-GetPayments, %s", err.Error())
+	}
+
+	return output, nil
+}
+
+// Обновление статуса "Отмены" платежа.
+func (r *repository) CancelPayment(ctx context.Context, input PaymentStatus) (int64, error) {
+	rows, err := r.setStatus(ctx, input.ID, StatusCanceled, input.Reason, input.Version, paymentStates.Sources(StatusCanceled))
+	if err != nil {
+		return 0, fmt.Errorf("payment-repository-CancelPayment, %s", err.Error())
+	}
+
+	return rows, nil
+}
+
+// Отмена авторизованного платежа. Платеж в любом другом статусе не меняется.
+func (r *repository) VoidPayment(ctx context.Context, input PaymentStatus) (int64, error) {
+	rows, err := r.setStatus(ctx, input.ID, StatusCanceled, input.Reason, input.Version, []string{StatusAuthorized})
+	if err != nil {
+		return 0, fmt.Errorf("payment-repository-VoidPayment, %s", err.Error())
+	}
+
+	return rows, nil
+}
+
+// Списание авторизованного платежа полностью или частично. Платеж блокируется на время транзакции,
+// переводится в «success», а списанная сумма, событие outbox и история записываются вместе.
+func (r *repository) CapturePayment(ctx context.Context, input CaptureInput) (Decimal, error) {
+	const selectFormat = `SELECT p.amount, p.status, c.code, c.numeric_code, c.minor_units
+						from %s p
+						JOIN %s c ON c.code = p.currency
+						WHERE p.id = $1
+						FOR UPDATE OF p`
+
+	const updateFormat = `UPDATE %s SET status = $1, captured_amount = $2
+						WHERE id = $3`
+
+	var captured Decimal
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		var amount Decimal
+		var status string
+		var cur currency.Currency
+
+		err := tx.QueryRowContext(
+			ctx,
+			fmt.Sprintf(selectFormat, payments, currencies),
+			input.PaymentID,
+		).Scan(&amount, &status, &cur.Code, &cur.Numeric, &cur.MinorUnits)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrPaymentNotFound
+			}
+
+			return err
+		}
+
+		captured, err = captureAmount(amount, status, cur, input.Amount)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			fmt.Sprintf(updateFormat, payments),
+			StatusSuccess,
+			captured,
+			input.PaymentID,
+		)
+		if err != nil {
+			return err
+		}
+
+		return r.recordTransition(ctx, tx, input.PaymentID, status, StatusSuccess, input.Reason)
+	})
+	if err != nil {
+		return Decimal{}, fmt.Errorf("payment-repository-CapturePayment, %w", err)
+	}
+
+	return captured, nil
+}
+
+// Получение не истекшего сохраненного ответа по Idempotency-Key.
+func (r *repository) GetIdempotencyKey(ctx context.Context, key string) (IdempotencyKey, bool, error) {
+	const format = `SELECT key, fingerprint, status_code, response, expires_at from %s
+						WHERE key = $1
+						AND expires_at > NOW()`
+
+	query := fmt.Sprintf(
+		format,
+		idempotencyKeys,
+	)
+
+	row := r.db.QueryRowContext(
+		ctx,
+		query,
+		key,
+	)
+
+	var output IdempotencyKey
+	var response string
+	err := row.Scan(
+		&output.Key,
+		&output.Fingerprint,
+		&output.StatusCode,
+		&response,
+		&output.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return IdempotencyKey{}, false, nil
+		}
+
+		return IdempotencyKey{}, false, fmt.Errorf("payment-repository-GetIdempotencyKey, %s", err.Error())
+	}
+
+	output.Response = []byte(response)
+
+	return output, true, nil
+}
+
+// Резервирование Idempotency-Key за выполняющимся запросом: ключ записывается с кодом 0 до
+// input.ExpiresAt. Возвращает false, если ключ занят и не истек. Проверка и запись выполняются одним
+// запросом, поэтому из одновременных запросов с одним ключом ключ получает только один.
+func (r *repository) ReserveIdempotencyKey(ctx context.Context, input IdempotencyKey) (bool, error) {
+	const format = `INSERT INTO %[1]s (key, fingerprint, status_code, response, expires_at)
+						VALUES ($1, $2, 0, '', $3)
+					ON CONFLICT (key) DO UPDATE SET
+						fingerprint = EXCLUDED.fingerprint,
+						status_code = 0,
+						response = '',
+						created_at = NOW(),
+						expires_at = EXCLUDED.expires_at
+					WHERE %[1]s.expires_at <= NOW()`
+
+	query := fmt.Sprintf(
+		format,
+		idempotencyKeys,
+	)
+
+	result, err := r.db.ExecContext(
+		ctx,
+		query,
+		input.Key,
+		input.Fingerprint,
+		input.ExpiresAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("payment-repository-ReserveIdempotencyKey, %s", err.Error())
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("payment-repository-ReserveIdempotencyKey, %s", err.Error())
+	}
+
+	return affected == 1, nil
+}
+
+// Сохранение ответа по зарезервированному Idempotency-Key. Ключ, который уже не зарезервирован этим
+// запросом, не перезаписывается.
+func (r *repository) SaveIdempotencyKey(ctx context.Context, input IdempotencyKey) error {
+	const format = `UPDATE %s SET
+						status_code = $3,
+						response = $4,
+						expires_at = $5
+					WHERE key = $1
+					AND fingerprint = $2
+					AND status_code = 0`
+
+	query := fmt.Sprintf(
+		format,
+		idempotencyKeys,
+	)
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		input.Key,
+		input.Fingerprint,
+		input.StatusCode,
+		string(input.Response),
+		input.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("payment-repository-SaveIdempotencyKey, %s", err.Error())
+	}
+
+	return nil
+}
+
+// Снятие резерва Idempotency-Key, ответ на который не сохраняется, чтобы запрос можно было повторить.
+func (r *repository) ReleaseIdempotencyKey(ctx context.Context, input IdempotencyKey) error {
+	const format = `DELETE FROM %s
+						WHERE key = $1
+						AND fingerprint = $2
+						AND status_code = 0`
+
+	query := fmt.Sprintf(
+		format,
+		idempotencyKeys,
+	)
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		input.Key,
+		input.Fingerprint,
+	)
+	if err != nil {
+		return fmt.Errorf("payment-repository-ReleaseIdempotencyKey, %s", err.Error())
+	}
+
+	return nil
+}
+
+// Выполняет fn в транзакции: при ошибке транзакция откатывается, иначе фиксируется. Если в контексте
+// уже есть транзакция WithinTransaction, fn выполняется в ней, а фиксирует ее внешний вызов.
+func (r *repository) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(tx)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			loggin.FromContext(ctx).Named("payment").Errorf("payment-repository-withTx, rollback: %s", rollbackErr.Error())
+		}
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Создание возврата. Платеж блокируется на время транзакции, поэтому сумма возвратов не может
+// превысить сумму платежа даже при параллельных запросах.
+func (r *repository) CreateRefund(ctx context.Context, input RefundInput) (refund, error) {
+	const selectFormat = `SELECT p.captured_amount, p.refunded_amount, p.status, c.code, c.numeric_code, c.minor_units
+						from %s p
+						JOIN %s c ON c.code = p.currency
+						WHERE p.id = $1
+						FOR UPDATE OF p`
+
+	const insertFormat = `INSERT INTO %s (payment_id, amount, status)
+						VALUES ($1, $2, $3)
+					RETURNING id, created_at, updated_at`
+
+	const updateFormat = `UPDATE %s SET refunded_amount = refunded_amount + $1
+						WHERE id = $2`
+
+	output := refund{
+		PaymentID: input.PaymentID,
+		Amount:    input.Amount,
+		Status:    RefundStatusSucceeded,
+	}
+
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		var captured, refunded Decimal
+		var status string
+		var cur currency.Currency
+
+		err := tx.QueryRowContext(
+			ctx,
+			fmt.Sprintf(selectFormat, payments, currencies),
+			input.PaymentID,
+		).Scan(&captured, &refunded, &status, &cur.Code, &cur.Numeric, &cur.MinorUnits)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrPaymentNotFound
+			}
+
+			return err
+		}
+
+		output.Amount, err = refundAmount(captured, refunded, status, cur, input.Amount)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRowContext(
+			ctx,
+			fmt.Sprintf(insertFormat, refunds),
+			input.PaymentID,
+			output.Amount,
+			output.Status,
+		).Scan(&output.ID, &output.CreatedAt, &output.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			fmt.Sprintf(updateFormat, payments),
+			output.Amount,
+			input.PaymentID,
+		)
+
+		return err
+	})
+	if err != nil {
+		return refund{}, fmt.Errorf("payment-repository-CreateRefund, %w", err)
+	}
+
+	return output, nil
+}
+
+// Функция, которая возвращает срез возвратов платежа.
+func (r *repository) GetRefunds(ctx context.Context, PaymentID int64) ([]refund, error) {
 	const format = `SELECT
 						id,
-						user_id,
-						user_email,
-						currency,
+						payment_id,
 						amount,
+						status,
 						created_at,
-						updated_at,
-						status
+						updated_at
 					from %s
-						WHERE %s = $1`
+						WHERE payment_id = $1
+					ORDER BY id`
 
 	query := fmt.Sprintf(
 		format,
-		payments,
-		arg,
+		refunds,
 	)
 
 	rows, err := r.db.QueryContext(
 		ctx,
 		query,
-		value,
+		PaymentID,
 	)
 	if err != nil {
-		return []payment{}, fmt.Errorf("payment-reposiroty-GetPayments, %s", err.Error())
+		return []refund{}, fmt.Errorf("payment-repository-GetRefunds, %s", err.Error())
 	}
 
 	defer rows.Close()
 
-	output := make([]payment, 0)
+	output := make([]refund, 0)
 	for rows.Next() {
-		value := payment{}
+		value := refund{}
 
 		err := rows.Scan(
 			&value.ID,
-			&value.UserID,
-			&value.UserEmail,
-			&value.Currency,
+			&value.PaymentID,
 			&value.Amount,
+			&value.Status,
 			&value.CreatedAt,
 			&value.UpdatedAt,
-			&value.Status,
 		)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				return []payment{}, fmt.Errorf("payment-repository-CreatePayment, %s", "no result")
-			}
+			return []refund{}, fmt.Errorf("payment-repository-GetRefunds, %s", err.Error())
+		}
 
-			return []payment{}, fmt.Errorf("payment-reposiroty-GetPayments, %s", err.Error())
+		output = append(output, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []refund{}, fmt.Errorf("payment-repository-GetRefunds, %s", err.Error())
+	}
+
+	return output, nil
+}
+
+// Функция, которая возвращает не опубликованные события outbox в порядке их записи.
+func (r *repository) FetchOutbox(ctx context.Context, limit int) ([]outbox.Event, error) {
+	const format = `SELECT
+						id,
+						payment_id,
+						COALESCE(old_status::text, ''),
+						new_status,
+						reason,
+						actor,
+						created_at
+					from %s
+						WHERE published_at IS NULL
+					ORDER BY id
+					LIMIT $1`
+
+	query := fmt.Sprintf(
+		format,
+		paymentOutbox,
+	)
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		limit,
+	)
+	if err != nil {
+		return []outbox.Event{}, fmt.Errorf("payment-repository-FetchOutbox, %s", err.Error())
+	}
+
+	defer rows.Close()
+
+	output := make([]outbox.Event, 0)
+	for rows.Next() {
+		value := outbox.Event{}
+
+		err := rows.Scan(
+			&value.ID,
+			&value.PaymentID,
+			&value.OldStatus,
+			&value.NewStatus,
+			&value.Reason,
+			&value.Actor,
+			&value.CreatedAt,
+		)
+		if err != nil {
+			return []outbox.Event{}, fmt.Errorf("payment-repository-FetchOutbox, %s", err.Error())
 		}
 
 		output = append(output, value)
 	}
 
 	if err := rows.Err(); err != nil {
-		return []payment{}, fmt.Errorf("payment-reposiroty-GetPayments, %s", err.Error())
+		return []outbox.Event{}, fmt.Errorf("payment-repository-FetchOutbox, %s", err.Error())
 	}
 
 	return output, nil
 }
 
-// Обновление статуса "Отмены" платежа.
-func (r *repository) CancelPayment(ctx context.Context, PaymentID int64) (int64, error) {
-	const format = `UPDATE %s SET status = $1
-						WHERE id = $2
-						AND status NOT IN ($3, $4)`
+// Отмечает события outbox опубликованными.
+func (r *repository) MarkOutboxPublished(ctx context.Context, ids []int64) error {
+	const format = `UPDATE %s SET published_at = NOW()
+						WHERE id IN (%s)`
+
+	if len(ids) == 0 {
+		return nil
+	}
 
 	query := fmt.Sprintf(
 		format,
-		payments,
+		paymentOutbox,
+		placeholders(1, len(ids)),
 	)
 
-	rows, err := r.db.ExecContext(
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("payment-repository-MarkOutboxPublished, %s", err.Error())
+	}
+
+	return nil
+}
+
+// Функция, которая возвращает историю переходов статуса платежа в порядке их выполнения.
+func (r *repository) GetHistory(ctx context.Context, PaymentID int64) ([]statusChange, error) {
+	const format = `SELECT
+						id,
+						payment_id,
+						COALESCE(from_status::text, ''),
+						to_status,
+						reason,
+						actor,
+						created_at
+					from %s
+						WHERE payment_id = $1
+					ORDER BY id`
+
+	query := fmt.Sprintf(
+		format,
+		statusHistory,
+	)
+
+	rows, err := r.db.QueryContext(
 		ctx,
 		query,
-		StatusCanceled,
 		PaymentID,
-		StatusSuccess,
-		StatusFailure,
 	)
 	if err != nil {
-		return 0, fmt.Errorf("payment-repository-CancelPayment, %s", err.Error())
+		return []statusChange{}, fmt.Errorf("payment-repository-GetHistory, %s", err.Error())
 	}
 
-	return rows.RowsAffected()
+	defer rows.Close()
+
+	output := make([]statusChange, 0)
+	for rows.Next() {
+		value := statusChange{}
+
+		err := rows.Scan(
+			&value.ID,
+			&value.PaymentID,
+			&value.FromStatus,
+			&value.ToStatus,
+			&value.Reason,
+			&value.Actor,
+			&value.CreatedAt,
+		)
+		if err != nil {
+			return []statusChange{}, fmt.Errorf("payment-repository-GetHistory, %s", err.Error())
+		}
+
+		output = append(output, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []statusChange{}, fmt.Errorf("payment-repository-GetHistory, %s", err.Error())
+	}
+
+	return output, nil
+}
+
+// Функция, которая возвращает платежи в статусе status с идентификатором больше AfterID,
+// упорядоченные по идентификатору.
+func (r *repository) GetPaymentsByStatus(ctx context.Context, status string, AfterID int64, limit int) ([]payment, error) {
+	const format = `SELECT %s from %s
+						WHERE status = $1
+						AND id > $2
+					ORDER BY id
+					LIMIT $3`
+
+	query := fmt.Sprintf(
+		format,
+		paymentColumns,
+		payments,
+	)
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		status,
+		AfterID,
+		limit,
+	)
+	if err != nil {
+		return []payment{}, fmt.Errorf("payment-repository-GetPaymentsByStatus, %s", err.Error())
+	}
+
+	defer rows.Close()
+
+	output := make([]payment, 0)
+	for rows.Next() {
+		value, err := scanPayment(rows)
+		if err != nil {
+			return []payment{}, fmt.Errorf("payment-repository-GetPaymentsByStatus, %s", err.Error())
+		}
+
+		output = append(output, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []payment{}, fmt.Errorf("payment-repository-GetPaymentsByStatus, %s", err.Error())
+	}
+
+	return output, nil
+}
+
+// Функция, которая возвращает авторизованные платежи, авторизация которых выполнена раньше before,
+// упорядоченные по идентификатору.
+func (r *repository) GetExpiredAuthorizations(ctx context.Context, before time.Time, limit int) ([]payment, error) {
+	const format = `SELECT %s from %s
+						WHERE status = $1
+						AND authorized_at < $2
+					ORDER BY id
+					LIMIT $3`
+
+	query := fmt.Sprintf(
+		format,
+		paymentColumns,
+		payments,
+	)
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		StatusAuthorized,
+		before,
+		limit,
+	)
+	if err != nil {
+		return []payment{}, fmt.Errorf("payment-repository-GetExpiredAuthorizations, %s", err.Error())
+	}
+
+	defer rows.Close()
+
+	output := make([]payment, 0)
+	for rows.Next() {
+		value, err := scanPayment(rows)
+		if err != nil {
+			return []payment{}, fmt.Errorf("payment-repository-GetExpiredAuthorizations, %s", err.Error())
+		}
+
+		output = append(output, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []payment{}, fmt.Errorf("payment-repository-GetExpiredAuthorizations, %s", err.Error())
+	}
+
+	return output, nil
 }
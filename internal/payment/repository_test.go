@@ -2,10 +2,13 @@ package payment
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/onlycodergod/payment-api-emulator/internal/outbox"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -23,6 +26,8 @@ func TestCreatePayment(t *testing.T) {
 
 	r := NewPaymentRepository(db)
 
+	ratedAt := time.Date(2022, 7, 20, 12, 0, 0, 0, time.UTC)
+
 	tests := []struct {
 		name   string
 		mock   func()
@@ -35,30 +40,73 @@ func TestCreatePayment(t *testing.T) {
 			mock: func() {
 				rows := sqlmock.NewRows([]string{"id"}).AddRow(1)
 
+				dbMock.ExpectBegin()
 				dbMock.ExpectQuery("INSERT INTO payments").
-					WithArgs(1, "user_email", 10.5, "currency").
+					WithArgs(1, "user_email", "10.5", "currency", "currency", "10.5", "1", RateSourceIdentity, sqlmock.AnyArg()).
 					WillReturnRows(rows)
+				dbMock.ExpectExec("INSERT INTO payment_outbox").
+					WithArgs(1, nil, StatusNew, "", ActorSystem).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				dbMock.ExpectExec("INSERT INTO payment_status_history").
+					WithArgs(1, nil, StatusNew, "", ActorSystem).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				dbMock.ExpectCommit()
 			},
 			input: PaymentInput{
 				UserID:    1,
 				UserEmail: "user_email",
-				Amount:    10.5,
+				Amount:    mustDecimal("10.5"),
 				Currency:  "currency",
 			},
 			expect: 1,
 			err:    nil,
 		},
+		{
+			name: "Create payment with settlement currency",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"id"}).AddRow(2)
+
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("INSERT INTO payments").
+					WithArgs(1, "user_email", "10.5", "usd", "eur", "10.29", "0.98", RateSourceFile, ratedAt).
+					WillReturnRows(rows)
+				dbMock.ExpectExec("INSERT INTO payment_outbox").
+					WithArgs(2, nil, StatusNew, "", ActorSystem).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				dbMock.ExpectExec("INSERT INTO payment_status_history").
+					WithArgs(2, nil, StatusNew, "", ActorSystem).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				dbMock.ExpectCommit()
+			},
+			input: PaymentInput{
+				UserID:    1,
+				UserEmail: "user_email",
+				Amount:    mustDecimal("10.5"),
+				Currency:  "usd",
+				Settlement: Settlement{
+					Currency: "eur",
+					Amount:   mustDecimal("10.29"),
+					Rate:     mustDecimal("0.98"),
+					Source:   RateSourceFile,
+					RatedAt:  ratedAt,
+				},
+			},
+			expect: 2,
+			err:    nil,
+		},
 		{
 			name: "Fail",
 			mock: func() {
+				dbMock.ExpectBegin()
 				dbMock.ExpectQuery("INSERT INTO payments").
-					WithArgs(0, "", 0.0, "").
+					WithArgs(0, "", "0", "", "", "0", "1", RateSourceIdentity, sqlmock.AnyArg()).
 					WillReturnError(errors.New("insert error"))
+				dbMock.ExpectRollback()
 			},
 			input: PaymentInput{
 				UserID:    0,
 				UserEmail: "",
-				Amount:    0.0,
+				Amount:    Decimal{},
 				Currency:  "",
 			},
 			err: errors.New("insert error"),
@@ -72,12 +120,8 @@ func TestCreatePayment(t *testing.T) {
 			// Вызов функции CreatePayment с входными данными.
 			got, err := r.CreatePayment(
 				context.TODO(),
-				PaymentInput{
-					UserID:    tt.input.UserID,
-					UserEmail: tt.input.UserEmail,
-					Amount:    tt.input.Amount,
-					Currency:  tt.input.Currency,
-				})
+				tt.input,
+			)
 
 			if tt.err != nil {
 				assert.Error(t, err)
@@ -115,9 +159,17 @@ func TestUpdateStatus(t *testing.T) {
 		{
 			name: "Update status to success",
 			mock: func() {
-				dbMock.ExpectExec("UPDATE payments").
-					WithArgs("success", 1, StatusSuccess, StatusFailure).
-					WillReturnResult(sqlmock.NewResult(0, 1))
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("UPDATE payments").
+					WithArgs("success", 1, StatusNew, StatusAuthorized).
+					WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(StatusNew))
+				dbMock.ExpectExec("INSERT INTO payment_outbox").
+					WithArgs(1, StatusNew, StatusSuccess, "", ActorSystem).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				dbMock.ExpectExec("INSERT INTO payment_status_history").
+					WithArgs(1, StatusNew, StatusSuccess, "", ActorSystem).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				dbMock.ExpectCommit()
 			},
 			input: PaymentStatus{
 				ID:     1,
@@ -129,23 +181,67 @@ func TestUpdateStatus(t *testing.T) {
 		{
 			name: "Update status to failure",
 			mock: func() {
-				dbMock.ExpectExec("UPDATE payments").
-					WithArgs("failure", 1, StatusSuccess, StatusFailure).
-					WillReturnResult(sqlmock.NewResult(0, 1))
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("UPDATE payments").
+					WithArgs("failure", 1, StatusNew, StatusAuthorized, StatusError).
+					WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(StatusError))
+				dbMock.ExpectExec("INSERT INTO payment_outbox").
+					WithArgs(1, StatusError, StatusFailure, "declined by issuer", ActorSystem).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				dbMock.ExpectExec("INSERT INTO payment_status_history").
+					WithArgs(1, StatusError, StatusFailure, "declined by issuer", ActorSystem).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				dbMock.ExpectCommit()
 			},
 			input: PaymentStatus{
 				ID:     1,
 				Status: "failure",
+				Reason: "declined by issuer",
 			},
 			expect: 1,
 			err:    nil,
 		},
+		{
+			name: "Transition is not allowed",
+			mock: func() {
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("UPDATE payments").
+					WithArgs("success", 1, StatusNew, StatusAuthorized).
+					WillReturnError(sql.ErrNoRows)
+				dbMock.ExpectCommit()
+			},
+			input: PaymentStatus{
+				ID:     1,
+				Status: "success",
+			},
+			expect: 0,
+			err:    nil,
+		},
+		{
+			name: "Version does not match",
+			mock: func() {
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery(`UPDATE payments (.+) AND old.version = \$5`).
+					WithArgs("success", 1, StatusNew, StatusAuthorized, 2).
+					WillReturnError(sql.ErrNoRows)
+				dbMock.ExpectCommit()
+			},
+			input: PaymentStatus{
+				ID:      1,
+				Status:  "success",
+				Version: 2,
+			},
+			expect: 0,
+			err:    nil,
+		},
 		{
 			name: "Fail",
 			mock: func() {
-				dbMock.ExpectExec("UPDATE payments").
-					WithArgs("failure", 1, StatusSuccess, StatusFailure).
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("UPDATE payments").
+					WithArgs("failure", 1, StatusNew, StatusAuthorized, StatusError).
 					WillReturnError(errors.New("update error"))
+				dbMock.ExpectRollback()
 			},
 			input: PaymentStatus{
 				ID:     1,
@@ -162,8 +258,10 @@ func TestUpdateStatus(t *testing.T) {
 			got, err := r.UpdateStatus(
 				context.TODO(),
 				PaymentStatus{
-					ID:     tt.input.ID,
-					Status: tt.input.Status,
+					ID:      tt.input.ID,
+					Status:  tt.input.Status,
+					Reason:  tt.input.Reason,
+					Version: tt.input.Version,
 				})
 
 			if tt.err != nil {
@@ -244,6 +342,191 @@ func TestGetStatus(t *testing.T) {
 	}
 }
 
+// Он создает фиктивное соединение с базой данных.
+func TestGetPayment(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	r := NewPaymentRepository(db)
+
+	tests := []struct {
+		name   string
+		mock   func()
+		input  int64
+		expect payment
+		err    error
+	}{
+		{
+			name: "Get payment",
+			mock: func() {
+				row := sqlmock.NewRows([]string{"id", "user_id", "user_email", "currency", "amount", "created_at", "updated_at", "status", "refunded_amount", "settlement_currency", "settlement_amount", "fx_rate", "fx_source", "fx_rated_at", "captured_amount", "authorized_at", "version"}).
+					AddRow(1, 1, "user_email", "usd", 10.5, "created_at", "updated_at", StatusSuccess, 10.5, "usd", 10.5, 1, RateSourceIdentity, "fx_rated_at", 10.5, "authorized_at", 1)
+
+				dbMock.ExpectQuery("SELECT (.+) from payments").
+					WithArgs(1).
+					WillReturnRows(row)
+			},
+			input:  1,
+			expect: payment{1, 1, mustDecimal("10.5"), "user_email", "usd", "created_at", "updated_at", StatusSuccess, mustDecimal("10.5"), RefundStateRefunded, "usd", mustDecimal("10.5"), mustDecimal("1"), RateSourceIdentity, "fx_rated_at", mustDecimal("10.5"), "authorized_at", 1},
+		},
+		{
+			name: "Not found",
+			mock: func() {
+				dbMock.ExpectQuery("SELECT (.+) from payments").
+					WithArgs(2).
+					WillReturnError(sql.ErrNoRows)
+			},
+			input: 2,
+			err:   ErrPaymentNotFound,
+		},
+		{
+			name: "Fail",
+			mock: func() {
+				dbMock.ExpectQuery("SELECT (.+) from payments").
+					WithArgs(3).
+					WillReturnError(errors.New("connection refused"))
+			},
+			input: 3,
+			err:   errors.New("connection refused"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.GetPayment(
+				context.TODO(),
+				tt.input,
+			)
+
+			if tt.err != nil {
+				assert.Error(t, err)
+				if errors.Is(tt.err, ErrPaymentNotFound) {
+					assert.ErrorIs(t, err, ErrPaymentNotFound)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expect, got)
+			}
+
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+// Он проверяет, что методы репозитория внутри WithinTransaction выполняются в одной транзакции,
+// которая откатывается при ошибке.
+func TestWithinTransaction(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	r := NewPaymentRepository(db)
+
+	columns := []string{"id", "user_id", "user_email", "currency", "amount", "created_at", "updated_at", "status", "refunded_amount", "settlement_currency", "settlement_amount", "fx_rate", "fx_source", "fx_rated_at", "captured_amount", "authorized_at", "version"}
+
+	tests := []struct {
+		name string
+		mock func()
+		fn   func(ctx context.Context) error
+		err  error
+	}{
+		{
+			name: "Lock and update in one transaction",
+			mock: func() {
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("SELECT (.+) from payments (.+) FOR UPDATE").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(1, 1, "user_email", "usd", 10.5, "created_at", "updated_at", StatusNew, 0, "usd", 10.5, 1, RateSourceIdentity, "fx_rated_at", 0, nil, 1))
+				dbMock.ExpectQuery("UPDATE payments").
+					WithArgs(StatusCanceled, 1, StatusNew, StatusAuthorized, StatusError).
+					WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(StatusNew))
+				dbMock.ExpectExec("INSERT INTO payment_outbox").
+					WithArgs(1, StatusNew, StatusCanceled, "", ActorSystem).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				dbMock.ExpectExec("INSERT INTO payment_status_history").
+					WithArgs(1, StatusNew, StatusCanceled, "", ActorSystem).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				dbMock.ExpectCommit()
+			},
+			fn: func(ctx context.Context) error {
+				if _, err := r.LockPayment(ctx, 1); err != nil {
+					return err
+				}
+
+				_, err := r.CancelPayment(ctx, PaymentStatus{ID: 1})
+
+				return err
+			},
+		},
+		{
+			name: "Not found",
+			mock: func() {
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("SELECT (.+) from payments (.+) FOR UPDATE").
+					WithArgs(2).
+					WillReturnError(sql.ErrNoRows)
+				dbMock.ExpectRollback()
+			},
+			fn: func(ctx context.Context) error {
+				_, err := r.LockPayment(ctx, 2)
+
+				return err
+			},
+			err: ErrPaymentNotFound,
+		},
+		{
+			name: "Nested transaction joins the outer one",
+			mock: func() {
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("SELECT status from payments").
+					WithArgs(3).
+					WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(StatusNew))
+				dbMock.ExpectRollback()
+			},
+			fn: func(ctx context.Context) error {
+				return r.WithinTransaction(ctx, func(ctx context.Context) error {
+					if _, err := r.GetStatus(ctx, 3); err != nil {
+						return err
+					}
+
+					return ErrVersionMismatch
+				})
+			},
+			err: ErrVersionMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			err := r.WithinTransaction(context.TODO(), tt.fn)
+
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
 // Он создает фиктивное соединение с базой данных.
 func TestGetPayments(t *testing.T) {
 	t.Parallel()
@@ -258,6 +541,9 @@ func TestGetPayments(t *testing.T) {
 
 	r := NewPaymentRepository(db)
 
+	createdFrom := time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+	createdTo := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+
 	tests := []struct {
 		name   string
 		mock   func()
@@ -268,40 +554,67 @@ func TestGetPayments(t *testing.T) {
 		{
 			name: "Get user payments by ID",
 			mock: func() {
-				rows := sqlmock.NewRows([]string{"id", "user_id", "user_email", "currency", "amount", "created_at", "updated_at", "status"}).
-					AddRow(1, 1, "user_email", "currency", 10.5, "created_at", "updated_at", "status").
-					AddRow(2, 2, "user_email", "currency", 10.5, "created_at", "updated_at", "status")
+				rows := sqlmock.NewRows([]string{"id", "user_id", "user_email", "currency", "amount", "created_at", "updated_at", "status", "refunded_amount", "settlement_currency", "settlement_amount", "fx_rate", "fx_source", "fx_rated_at", "captured_amount", "authorized_at", "version"}).
+					AddRow(1, 1, "user_email", "currency", 10.5, "created_at", "updated_at", "status", 0, "currency", 10.5, 1, RateSourceIdentity, "fx_rated_at", 10.5, nil, 1).
+					AddRow(2, 2, "user_email", "currency", 10.5, "created_at", "updated_at", "status", 5.25, "currency", 10.5, 1, RateSourceIdentity, "fx_rated_at", 10.5, nil, 1)
 
 				dbMock.ExpectQuery("SELECT").
-					WithArgs(1).
+					WithArgs(1, DefaultPageLimit+1).
 					WillReturnRows(rows)
 			},
 			input: PaymentUser{
 				UserID: 1,
 			},
 			expect: []payment{
-				{1, 1, 10.5, "user_email", "currency", "created_at", "updated_at", "status"},
-				{2, 2, 10.5, "user_email", "currency", "created_at", "updated_at", "status"},
+				{1, 1, mustDecimal("10.5"), "user_email", "currency", "created_at", "updated_at", "status", Decimal{}, "", "currency", mustDecimal("10.5"), mustDecimal("1"), RateSourceIdentity, "fx_rated_at", mustDecimal("10.5"), "", 1},
+				{2, 2, mustDecimal("10.5"), "user_email", "currency", "created_at", "updated_at", "status", mustDecimal("5.25"), RefundStatePartiallyRefunded, "currency", mustDecimal("10.5"), mustDecimal("1"), RateSourceIdentity, "fx_rated_at", mustDecimal("10.5"), "", 1},
 			},
 			err: nil,
 		},
 		{
 			name: "Get user payments by Email",
 			mock: func() {
-				rows := sqlmock.NewRows([]string{"id", "user_id", "user_email", "currency", "amount", "created_at", "updated_at", "status"}).
-					AddRow(1, 1, "user_email", "currency", 10.5, "created_at", "updated_at", "status").
-					AddRow(2, 2, "user_email", "currency", 10.5, "created_at", "updated_at", "status")
+				rows := sqlmock.NewRows([]string{"id", "user_id", "user_email", "currency", "amount", "created_at", "updated_at", "status", "refunded_amount", "settlement_currency", "settlement_amount", "fx_rate", "fx_source", "fx_rated_at", "captured_amount", "authorized_at", "version"}).
+					AddRow(1, 1, "user_email", "currency", 10.5, "created_at", "updated_at", "status", 0, "currency", 10.5, 1, RateSourceIdentity, "fx_rated_at", 10.5, nil, 1).
+					AddRow(2, 2, "user_email", "currency", 10.5, "created_at", "updated_at", "status", 5.25, "currency", 10.5, 1, RateSourceIdentity, "fx_rated_at", 10.5, nil, 1)
 
 				dbMock.ExpectQuery("SELECT").
-					WithArgs("email").
+					WithArgs("email", DefaultPageLimit+1).
 					WillReturnRows(rows)
 			},
 			input: PaymentUser{
 				UserEmail: "email",
 			},
 			expect: []payment{
-				{1, 1, 10.5, "user_email", "currency", "created_at", "updated_at", "status"},
-				{2, 2, 10.5, "user_email", "currency", "created_at", "updated_at", "status"},
+				{1, 1, mustDecimal("10.5"), "user_email", "currency", "created_at", "updated_at", "status", Decimal{}, "", "currency", mustDecimal("10.5"), mustDecimal("1"), RateSourceIdentity, "fx_rated_at", mustDecimal("10.5"), "", 1},
+				{2, 2, mustDecimal("10.5"), "user_email", "currency", "created_at", "updated_at", "status", mustDecimal("5.25"), RefundStatePartiallyRefunded, "currency", mustDecimal("10.5"), mustDecimal("1"), RateSourceIdentity, "fx_rated_at", mustDecimal("10.5"), "", 1},
+			},
+			err: nil,
+		},
+		{
+			name: "Get filtered page in descending order",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"id", "user_id", "user_email", "currency", "amount", "created_at", "updated_at", "status", "refunded_amount", "settlement_currency", "settlement_amount", "fx_rate", "fx_source", "fx_rated_at", "captured_amount", "authorized_at", "version"}).
+					AddRow(4, 1, "user_email", "usd", 10.5, "created_at", "updated_at", StatusSuccess, 0, "usd", 10.5, 1, RateSourceIdentity, "fx_rated_at", 10.5, nil, 1)
+
+				dbMock.ExpectQuery(`WHERE user_id = \$1 AND id < \$2 AND status = \$3 AND currency = \$4 AND amount >= \$5 AND amount <= \$6 AND created_at >= \$7 AND created_at < \$8\s+ORDER BY id DESC\s+LIMIT \$9`).
+					WithArgs(1, 5, StatusSuccess, "usd", "1", "100", createdFrom, createdTo, 11).
+					WillReturnRows(rows)
+			},
+			input: PaymentUser{
+				UserID:      1,
+				Limit:       10,
+				AfterID:     5,
+				Sort:        SortDesc,
+				Status:      StatusSuccess,
+				Currency:    "usd",
+				MinAmount:   mustDecimal("1"),
+				MaxAmount:   mustDecimal("100"),
+				CreatedFrom: createdFrom,
+				CreatedTo:   createdTo,
+			},
+			expect: []payment{
+				{4, 1, mustDecimal("10.5"), "user_email", "usd", "created_at", "updated_at", StatusSuccess, Decimal{}, "", "usd", mustDecimal("10.5"), mustDecimal("1"), RateSourceIdentity, "fx_rated_at", mustDecimal("10.5"), "", 1},
 			},
 			err: nil,
 		},
@@ -309,7 +622,7 @@ func TestGetPayments(t *testing.T) {
 			name: "Fail",
 			mock: func() {
 				dbMock.ExpectQuery("SELECT").
-					WithArgs(1).
+					WithArgs(1, DefaultPageLimit+1).
 					WillReturnError(errors.New("not found"))
 			},
 			input: PaymentUser{
@@ -325,10 +638,7 @@ func TestGetPayments(t *testing.T) {
 
 			got, err := r.GetPayments(
 				context.TODO(),
-				PaymentUser{
-					UserID:    tt.input.UserID,
-					UserEmail: tt.input.UserEmail,
-				},
+				tt.input,
 			)
 
 			if tt.err != nil {
@@ -360,30 +670,45 @@ func TestCancelPayment(t *testing.T) {
 	tests := []struct {
 		name   string
 		mock   func()
-		input  int64
+		input  PaymentStatus
 		expect int64
 		err    error
 	}{
 		{
 			name: "Cancel payment",
 			mock: func() {
-				dbMock.ExpectExec("UPDATE payments").
-					WithArgs("canceled", 1, StatusSuccess, StatusFailure).
-					WillReturnResult(sqlmock.NewResult(0, 1))
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("UPDATE payments").
+					WithArgs("canceled", 1, StatusNew, StatusAuthorized, StatusError).
+					WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(StatusNew))
+				dbMock.ExpectExec("INSERT INTO payment_outbox").
+					WithArgs(1, StatusNew, StatusCanceled, "customer request", ActorAPI).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				dbMock.ExpectExec("INSERT INTO payment_status_history").
+					WithArgs(1, StatusNew, StatusCanceled, "customer request", ActorAPI).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				dbMock.ExpectCommit()
+			},
+			input: PaymentStatus{
+				ID:     1,
+				Reason: "customer request",
 			},
-			input:  1,
 			expect: 1,
 			err:    nil,
 		},
 		{
 			name: "Fail",
 			mock: func() {
-				dbMock.ExpectExec("UPDATE payments").
-					WithArgs("canceled", 1, StatusSuccess, StatusFailure).
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("UPDATE payments").
+					WithArgs("canceled", 1, StatusNew, StatusAuthorized, StatusError).
 					WillReturnError(errors.New("update error"))
+				dbMock.ExpectRollback()
 			},
-			input: 1,
-			err:   errors.New("update error"),
+			input: PaymentStatus{
+				ID: 1,
+			},
+			err: errors.New("update error"),
 		},
 	}
 
@@ -392,7 +717,85 @@ func TestCancelPayment(t *testing.T) {
 			tt.mock()
 
 			got, err := r.CancelPayment(
-				context.TODO(),
+				WithActor(context.TODO(), ActorAPI),
+				tt.input,
+			)
+
+			if tt.err != nil {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expect, got)
+			}
+
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+// Он проверяет отмену авторизованного платежа.
+func TestVoidPayment(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	r := NewPaymentRepository(db)
+
+	tests := []struct {
+		name   string
+		mock   func()
+		input  PaymentStatus
+		expect int64
+		err    error
+	}{
+		{
+			name: "Void authorized payment",
+			mock: func() {
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("UPDATE payments").
+					WithArgs(StatusCanceled, 1, StatusAuthorized).
+					WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(StatusAuthorized))
+				dbMock.ExpectExec("INSERT INTO payment_outbox").
+					WithArgs(1, StatusAuthorized, StatusCanceled, ReasonAuthorizationExpired, ActorExpiry).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				dbMock.ExpectExec("INSERT INTO payment_status_history").
+					WithArgs(1, StatusAuthorized, StatusCanceled, ReasonAuthorizationExpired, ActorExpiry).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				dbMock.ExpectCommit()
+			},
+			input: PaymentStatus{
+				ID:     1,
+				Reason: ReasonAuthorizationExpired,
+			},
+			expect: 1,
+		},
+		{
+			name: "Payment is not authorized",
+			mock: func() {
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("UPDATE payments").
+					WithArgs(StatusCanceled, 1, StatusAuthorized).
+					WillReturnError(sql.ErrNoRows)
+				dbMock.ExpectCommit()
+			},
+			input: PaymentStatus{
+				ID: 1,
+			},
+			expect: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.VoidPayment(
+				WithActor(context.TODO(), ActorExpiry),
 				tt.input,
 			)
 
@@ -407,3 +810,851 @@ func TestCancelPayment(t *testing.T) {
 		})
 	}
 }
+
+// Он проверяет полное и частичное списание авторизованного платежа.
+func TestCapturePayment(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	r := NewPaymentRepository(db)
+
+	columns := []string{"amount", "status", "code", "numeric_code", "minor_units"}
+
+	tests := []struct {
+		name   string
+		mock   func()
+		input  CaptureInput
+		expect Decimal
+		err    error
+	}{
+		{
+			name: "Capture full amount",
+			mock: func() {
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("SELECT (.+) from payments (.+) FOR UPDATE").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(10.5, StatusAuthorized, "usd", "840", 2))
+				dbMock.ExpectExec("UPDATE payments SET status = \\$1, captured_amount = \\$2").
+					WithArgs(StatusSuccess, "10.5", 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				dbMock.ExpectExec("INSERT INTO payment_outbox").
+					WithArgs(1, StatusAuthorized, StatusSuccess, "", ActorAPI).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				dbMock.ExpectExec("INSERT INTO payment_status_history").
+					WithArgs(1, StatusAuthorized, StatusSuccess, "", ActorAPI).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				dbMock.ExpectCommit()
+			},
+			input: CaptureInput{
+				PaymentID: 1,
+			},
+			expect: mustDecimal("10.5"),
+		},
+		{
+			name: "Capture part of the amount",
+			mock: func() {
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("SELECT (.+) from payments (.+) FOR UPDATE").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(10.5, StatusAuthorized, "usd", "840", 2))
+				dbMock.ExpectExec("UPDATE payments SET status = \\$1, captured_amount = \\$2").
+					WithArgs(StatusSuccess, "4.5", 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				dbMock.ExpectExec("INSERT INTO payment_outbox").
+					WithArgs(1, StatusAuthorized, StatusSuccess, "partial shipment", ActorAPI).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				dbMock.ExpectExec("INSERT INTO payment_status_history").
+					WithArgs(1, StatusAuthorized, StatusSuccess, "partial shipment", ActorAPI).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				dbMock.ExpectCommit()
+			},
+			input: CaptureInput{
+				PaymentID: 1,
+				Amount:    mustDecimal("4.5"),
+				Reason:    "partial shipment",
+			},
+			expect: mustDecimal("4.5"),
+		},
+		{
+			name: "Capture exceeds authorized amount",
+			mock: func() {
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("SELECT (.+) from payments (.+) FOR UPDATE").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(10.5, StatusAuthorized, "usd", "840", 2))
+				dbMock.ExpectRollback()
+			},
+			input: CaptureInput{
+				PaymentID: 1,
+				Amount:    mustDecimal("11"),
+			},
+			err: ErrCaptureExceedsAmount,
+		},
+		{
+			name: "Payment is not authorized",
+			mock: func() {
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("SELECT (.+) from payments (.+) FOR UPDATE").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(10.5, StatusNew, "usd", "840", 2))
+				dbMock.ExpectRollback()
+			},
+			input: CaptureInput{
+				PaymentID: 1,
+			},
+			err: ErrNotAuthorized,
+		},
+		{
+			name: "Payment not found",
+			mock: func() {
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("SELECT (.+) from payments (.+) FOR UPDATE").
+					WithArgs(1).
+					WillReturnError(sql.ErrNoRows)
+				dbMock.ExpectRollback()
+			},
+			input: CaptureInput{
+				PaymentID: 1,
+			},
+			err: ErrPaymentNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.CapturePayment(
+				WithActor(context.TODO(), ActorAPI),
+				tt.input,
+			)
+
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expect, got)
+			}
+
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+// Он проверяет получение авторизаций, срок удержания которых истек.
+func TestGetExpiredAuthorizations(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	r := NewPaymentRepository(db)
+
+	before := time.Date(2022, 7, 30, 12, 0, 0, 0, time.UTC)
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "user_email", "currency", "amount", "created_at", "updated_at", "status", "refunded_amount", "settlement_currency", "settlement_amount", "fx_rate", "fx_source", "fx_rated_at", "captured_amount", "authorized_at", "version"}).
+		AddRow(3, 1, "user_email", "usd", 10.5, "created_at", "updated_at", StatusAuthorized, 0, "usd", 10.5, 1, RateSourceIdentity, "fx_rated_at", 0, "authorized_at", 1)
+
+	dbMock.ExpectQuery("SELECT (.+) from payments\\s+WHERE status = \\$1\\s+AND authorized_at < \\$2").
+		WithArgs(StatusAuthorized, before, 10).
+		WillReturnRows(rows)
+
+	got, err := r.GetExpiredAuthorizations(context.TODO(), before, 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []payment{
+		{3, 1, mustDecimal("10.5"), "user_email", "usd", "created_at", "updated_at", StatusAuthorized, Decimal{}, "", "usd", mustDecimal("10.5"), mustDecimal("1"), RateSourceIdentity, "fx_rated_at", Decimal{}, "authorized_at", 1},
+	}, got)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+// Он проверяет получение сохраненного ответа по Idempotency-Key.
+func TestGetIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	r := NewPaymentRepository(db)
+
+	expiresAt := time.Date(2022, 6, 21, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		mock   func()
+		input  string
+		expect IdempotencyKey
+		found  bool
+		err    error
+	}{
+		{
+			name: "Get stored response",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"key", "fingerprint", "status_code", "response", "expires_at"}).
+					AddRow("key", "fingerprint", 201, `{"id":1}`, expiresAt)
+
+				dbMock.ExpectQuery("SELECT (.+) from idempotency_keys").
+					WithArgs("key").
+					WillReturnRows(rows)
+			},
+			input: "key",
+			expect: IdempotencyKey{
+				Key:         "key",
+				Fingerprint: "fingerprint",
+				StatusCode:  201,
+				Response:    []byte(`{"id":1}`),
+				ExpiresAt:   expiresAt,
+			},
+			found: true,
+			err:   nil,
+		},
+		{
+			name: "Key not found",
+			mock: func() {
+				dbMock.ExpectQuery("SELECT (.+) from idempotency_keys").
+					WithArgs("missing").
+					WillReturnError(sql.ErrNoRows)
+			},
+			input: "missing",
+			found: false,
+			err:   nil,
+		},
+		{
+			name: "Fail",
+			mock: func() {
+				dbMock.ExpectQuery("SELECT (.+) from idempotency_keys").
+					WithArgs("key").
+					WillReturnError(errors.New("select error"))
+			},
+			input: "key",
+			err:   errors.New("select error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, found, err := r.GetIdempotencyKey(
+				context.TODO(),
+				tt.input,
+			)
+
+			if tt.err != nil {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.found, found)
+				assert.Equal(t, tt.expect, got)
+			}
+
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+// Он проверяет резервирование Idempotency-Key: свободный или истекший ключ резервируется, а занятый -
+// нет.
+func TestReserveIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	r := NewPaymentRepository(db)
+
+	expiresAt := time.Date(2022, 6, 21, 12, 0, 0, 0, time.UTC)
+	input := IdempotencyKey{
+		Key:         "key",
+		Fingerprint: "fingerprint",
+		ExpiresAt:   expiresAt,
+	}
+
+	tests := []struct {
+		name   string
+		mock   func()
+		expect bool
+		err    error
+	}{
+		{
+			name: "Reserved",
+			mock: func() {
+				dbMock.ExpectExec("INSERT INTO idempotency_keys (.+) ON CONFLICT (.+) WHERE idempotency_keys.expires_at <= NOW()").
+					WithArgs("key", "fingerprint", expiresAt).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expect: true,
+			err:    nil,
+		},
+		{
+			name: "Key is taken",
+			mock: func() {
+				dbMock.ExpectExec("INSERT INTO idempotency_keys").
+					WithArgs("key", "fingerprint", expiresAt).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			expect: false,
+			err:    nil,
+		},
+		{
+			name: "Fail",
+			mock: func() {
+				dbMock.ExpectExec("INSERT INTO idempotency_keys").
+					WithArgs("key", "fingerprint", expiresAt).
+					WillReturnError(errors.New("insert error"))
+			},
+			err: errors.New("insert error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.ReserveIdempotencyKey(
+				context.TODO(),
+				input,
+			)
+
+			if tt.err != nil {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expect, got)
+			}
+
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+// Он проверяет сохранение ответа по зарезервированному Idempotency-Key и снятие резерва.
+func TestSaveIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	r := NewPaymentRepository(db)
+
+	expiresAt := time.Date(2022, 6, 21, 12, 0, 0, 0, time.UTC)
+	input := IdempotencyKey{
+		Key:         "key",
+		Fingerprint: "fingerprint",
+		StatusCode:  201,
+		Response:    []byte(`{"id":1}`),
+		ExpiresAt:   expiresAt,
+	}
+
+	tests := []struct {
+		name string
+		mock func()
+		call func() error
+		err  error
+	}{
+		{
+			name: "Save response",
+			mock: func() {
+				dbMock.ExpectExec("UPDATE idempotency_keys SET (.+) WHERE key = (.+) AND status_code = 0").
+					WithArgs("key", "fingerprint", 201, `{"id":1}`, expiresAt).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			call: func() error { return r.SaveIdempotencyKey(context.TODO(), input) },
+			err:  nil,
+		},
+		{
+			name: "Save fail",
+			mock: func() {
+				dbMock.ExpectExec("UPDATE idempotency_keys").
+					WithArgs("key", "fingerprint", 201, `{"id":1}`, expiresAt).
+					WillReturnError(errors.New("update error"))
+			},
+			call: func() error { return r.SaveIdempotencyKey(context.TODO(), input) },
+			err:  errors.New("update error"),
+		},
+		{
+			name: "Release",
+			mock: func() {
+				dbMock.ExpectExec("DELETE FROM idempotency_keys WHERE key = (.+) AND status_code = 0").
+					WithArgs("key", "fingerprint").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			call: func() error { return r.ReleaseIdempotencyKey(context.TODO(), input) },
+			err:  nil,
+		},
+		{
+			name: "Release fail",
+			mock: func() {
+				dbMock.ExpectExec("DELETE FROM idempotency_keys").
+					WithArgs("key", "fingerprint").
+					WillReturnError(errors.New("delete error"))
+			},
+			call: func() error { return r.ReleaseIdempotencyKey(context.TODO(), input) },
+			err:  errors.New("delete error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			err := tt.call()
+
+			if tt.err != nil {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+// Он проверяет создание возврата в транзакции с блокировкой платежа.
+func TestCreateRefund(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	r := NewPaymentRepository(db)
+
+	tests := []struct {
+		name   string
+		mock   func()
+		input  RefundInput
+		expect refund
+		err    error
+	}{
+		{
+			name: "Partial refund",
+			mock: func() {
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("SELECT (.+) from payments (.+) FOR UPDATE").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"captured_amount", "refunded_amount", "status", "code", "numeric_code", "minor_units"}).AddRow(10.5, 0, StatusSuccess, "usd", "840", 2))
+				dbMock.ExpectQuery("INSERT INTO refunds").
+					WithArgs(1, "4.5", RefundStatusSucceeded).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(1, "created_at", "updated_at"))
+				dbMock.ExpectExec("UPDATE payments SET refunded_amount").
+					WithArgs("4.5", 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				dbMock.ExpectCommit()
+			},
+			input: RefundInput{PaymentID: 1, Amount: mustDecimal("4.5")},
+			expect: refund{
+				ID:        1,
+				PaymentID: 1,
+				Amount:    mustDecimal("4.5"),
+				Status:    RefundStatusSucceeded,
+				CreatedAt: "created_at",
+				UpdatedAt: "updated_at",
+			},
+			err: nil,
+		},
+		{
+			name: "Full refund of the remaining amount",
+			mock: func() {
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("SELECT (.+) from payments (.+) FOR UPDATE").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"captured_amount", "refunded_amount", "status", "code", "numeric_code", "minor_units"}).AddRow(10.5, 4.5, StatusSuccess, "usd", "840", 2))
+				dbMock.ExpectQuery("INSERT INTO refunds").
+					WithArgs(1, "6", RefundStatusSucceeded).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(2, "created_at", "updated_at"))
+				dbMock.ExpectExec("UPDATE payments SET refunded_amount").
+					WithArgs("6", 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				dbMock.ExpectCommit()
+			},
+			input: RefundInput{PaymentID: 1},
+			expect: refund{
+				ID:        2,
+				PaymentID: 1,
+				Amount:    mustDecimal("6"),
+				Status:    RefundStatusSucceeded,
+				CreatedAt: "created_at",
+				UpdatedAt: "updated_at",
+			},
+			err: nil,
+		},
+		{
+			name: "Refund exceeds amount",
+			mock: func() {
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("SELECT (.+) from payments (.+) FOR UPDATE").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"captured_amount", "refunded_amount", "status", "code", "numeric_code", "minor_units"}).AddRow(10.5, 10, StatusSuccess, "usd", "840", 2))
+				dbMock.ExpectRollback()
+			},
+			input: RefundInput{PaymentID: 1, Amount: mustDecimal("1")},
+			err:   ErrRefundExceedsAmount,
+		},
+		{
+			name: "Payment is not successful",
+			mock: func() {
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery("SELECT (.+) from payments (.+) FOR UPDATE").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"captured_amount", "refunded_amount", "status", "code", "numeric_code", "minor_units"}).AddRow(10.5, 0, StatusNew, "usd", "840", 2))
+				dbMock.ExpectRollback()
+			},
+			input: RefundInput{PaymentID: 1, Amount: mustDecimal("1")},
+			err:   ErrRefundNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.CreateRefund(
+				context.TODO(),
+				tt.input,
+			)
+
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expect, got)
+			}
+
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+// Он проверяет получение возвратов платежа.
+func TestGetRefunds(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	r := NewPaymentRepository(db)
+
+	tests := []struct {
+		name   string
+		mock   func()
+		input  int64
+		expect []refund
+		err    error
+	}{
+		{
+			name: "Get payment refunds",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"id", "payment_id", "amount", "status", "created_at", "updated_at"}).
+					AddRow(1, 1, 4.5, RefundStatusSucceeded, "created_at", "updated_at").
+					AddRow(2, 1, 6, RefundStatusSucceeded, "created_at", "updated_at")
+
+				dbMock.ExpectQuery("SELECT (.+) from refunds").
+					WithArgs(1).
+					WillReturnRows(rows)
+			},
+			input: 1,
+			expect: []refund{
+				{1, 1, mustDecimal("4.5"), RefundStatusSucceeded, "created_at", "updated_at"},
+				{2, 1, mustDecimal("6"), RefundStatusSucceeded, "created_at", "updated_at"},
+			},
+			err: nil,
+		},
+		{
+			name: "Fail",
+			mock: func() {
+				dbMock.ExpectQuery("SELECT (.+) from refunds").
+					WithArgs(1).
+					WillReturnError(errors.New("select error"))
+			},
+			input: 1,
+			err:   errors.New("select error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.GetRefunds(
+				context.TODO(),
+				tt.input,
+			)
+
+			if tt.err != nil {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expect, got)
+			}
+
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+// Он проверяет получение истории переходов статуса платежа.
+func TestGetHistory(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	r := NewPaymentRepository(db)
+
+	tests := []struct {
+		name   string
+		mock   func()
+		input  int64
+		expect []statusChange
+		err    error
+	}{
+		{
+			name: "Get history",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"id", "payment_id", "from_status", "to_status", "reason", "actor", "created_at"}).
+					AddRow(1, 1, "", StatusNew, "", ActorAPI, "created_at").
+					AddRow(2, 1, StatusNew, StatusCanceled, "customer request", ActorAPI, "created_at")
+
+				dbMock.ExpectQuery("SELECT (.+) from payment_status_history").
+					WithArgs(1).
+					WillReturnRows(rows)
+			},
+			input: 1,
+			expect: []statusChange{
+				{1, 1, "", StatusNew, "", ActorAPI, "created_at"},
+				{2, 1, StatusNew, StatusCanceled, "customer request", ActorAPI, "created_at"},
+			},
+			err: nil,
+		},
+		{
+			name: "Fail",
+			mock: func() {
+				dbMock.ExpectQuery("SELECT (.+) from payment_status_history").
+					WithArgs(1).
+					WillReturnError(errors.New("select error"))
+			},
+			input: 1,
+			err:   errors.New("select error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.GetHistory(
+				context.TODO(),
+				tt.input,
+			)
+
+			if tt.err != nil {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expect, got)
+			}
+
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+// Он проверяет чтение платежей в статусе после заданного ID.
+func TestGetPaymentsByStatus(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	r := NewPaymentRepository(db)
+
+	tests := []struct {
+		name   string
+		mock   func()
+		expect []payment
+		err    error
+	}{
+		{
+			name: "Get new payments",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"id", "user_id", "user_email", "currency", "amount", "created_at", "updated_at", "status", "refunded_amount", "settlement_currency", "settlement_amount", "fx_rate", "fx_source", "fx_rated_at", "captured_amount", "authorized_at", "version"}).
+					AddRow(6, 1, "user_email", "currency", 10.5, "created_at", "updated_at", StatusNew, 0, "currency", 10.5, 1, RateSourceIdentity, "fx_rated_at", 10.5, nil, 1).
+					AddRow(7, 2, "user_email", "currency", 10.5, "created_at", "updated_at", StatusNew, 0, "currency", 10.5, 1, RateSourceIdentity, "fx_rated_at", 10.5, nil, 1)
+
+				dbMock.ExpectQuery("SELECT (.+) from payments").
+					WithArgs(StatusNew, 5, 10).
+					WillReturnRows(rows)
+			},
+			expect: []payment{
+				{6, 1, mustDecimal("10.5"), "user_email", "currency", "created_at", "updated_at", StatusNew, Decimal{}, "", "currency", mustDecimal("10.5"), mustDecimal("1"), RateSourceIdentity, "fx_rated_at", mustDecimal("10.5"), "", 1},
+				{7, 2, mustDecimal("10.5"), "user_email", "currency", "created_at", "updated_at", StatusNew, Decimal{}, "", "currency", mustDecimal("10.5"), mustDecimal("1"), RateSourceIdentity, "fx_rated_at", mustDecimal("10.5"), "", 1},
+			},
+			err: nil,
+		},
+		{
+			name: "Fail",
+			mock: func() {
+				dbMock.ExpectQuery("SELECT (.+) from payments").
+					WithArgs(StatusNew, 5, 10).
+					WillReturnError(errors.New("select error"))
+			},
+			err: errors.New("select error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.GetPaymentsByStatus(
+				context.TODO(),
+				StatusNew,
+				5,
+				10,
+			)
+
+			if tt.err != nil {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expect, got)
+			}
+
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+// Он проверяет чтение не опубликованных событий outbox.
+func TestFetchOutbox(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	r := NewPaymentRepository(db)
+
+	createdAt := time.Date(2022, 7, 5, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		mock   func()
+		input  int
+		expect []outbox.Event
+		err    error
+	}{
+		{
+			name: "Fetch pending events",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"id", "payment_id", "old_status", "new_status", "reason", "actor", "created_at"}).
+					AddRow(1, 1, "", StatusNew, "", ActorAPI, createdAt).
+					AddRow(2, 1, StatusNew, StatusSuccess, "captured", ActorAPI, createdAt)
+
+				dbMock.ExpectQuery("SELECT (.+) from payment_outbox").
+					WithArgs(10).
+					WillReturnRows(rows)
+			},
+			input: 10,
+			expect: []outbox.Event{
+				{ID: 1, PaymentID: 1, NewStatus: StatusNew, Actor: ActorAPI, CreatedAt: createdAt},
+				{ID: 2, PaymentID: 1, OldStatus: StatusNew, NewStatus: StatusSuccess, Reason: "captured", Actor: ActorAPI, CreatedAt: createdAt},
+			},
+			err: nil,
+		},
+		{
+			name: "Fail",
+			mock: func() {
+				dbMock.ExpectQuery("SELECT (.+) from payment_outbox").
+					WithArgs(10).
+					WillReturnError(errors.New("select error"))
+			},
+			input: 10,
+			err:   errors.New("select error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.FetchOutbox(
+				context.TODO(),
+				tt.input,
+			)
+
+			if tt.err != nil {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expect, got)
+			}
+
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+// Он проверяет отметку событий outbox опубликованными.
+func TestMarkOutboxPublished(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	r := NewPaymentRepository(db)
+
+	dbMock.ExpectExec("UPDATE payment_outbox SET published_at").
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	assert.NoError(t, r.MarkOutboxPublished(context.TODO(), []int64{1, 2}))
+	assert.NoError(t, r.MarkOutboxPublished(context.TODO(), []int64{}))
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
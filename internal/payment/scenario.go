@@ -0,0 +1,340 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
+)
+
+// ScenarioMatch — это условия правила сценария. Пустое условие не проверяется, правило без условий
+// подходит любому платежу.
+// @property {string} AmountSuffix - Окончание суммы платежа, записанной с количеством знаков после точки
+// по валюте, например «.13».
+// @property {[]string} Emails - Адреса электронной почты пользователей.
+// @property {string} Currency - Валюта платежа.
+type ScenarioMatch struct {
+	AmountSuffix string   `yaml:"amount_suffix"`
+	Emails       []string `yaml:"emails"`
+	Currency     string   `yaml:"currency"`
+}
+
+// ScenarioRule — это правило, которое определяет итоговый статус нового платежа.
+// @property {string} Name - Название правила, попадает в причину перехода.
+// @property {ScenarioMatch} Match - Условия правила.
+// @property {float64} Probability - Вероятность срабатывания от 0 до 1. Если не задана, правило
+// срабатывает всегда.
+// @property {int64} After - Через сколько секунд после создания платежа применяется статус.
+// @property {string} Status - Статус, в который переводится платеж.
+// @property {string} Reason - Причина перехода. По умолчанию «scenario: <Name>».
+type ScenarioRule struct {
+	Name        string        `yaml:"name"`
+	Match       ScenarioMatch `yaml:"match"`
+	Probability float64       `yaml:"probability"`
+	After       int64         `yaml:"after"`
+	Status      string        `yaml:"status"`
+	Reason      string        `yaml:"reason"`
+}
+
+// Scenarios — это набор правил сценария эмулятора.
+// @property {int64} Seed - Начальное значение генератора случайных чисел. Одинаковый seed дает
+// одинаковые решения для одной и той же последовательности платежей. 0 - случайный seed.
+// @property {[]ScenarioRule} Rules - Правила в порядке проверки, срабатывает первое подходящее.
+type Scenarios struct {
+	Seed  int64          `yaml:"seed"`
+	Rules []ScenarioRule `yaml:"rules"`
+}
+
+// Читает правила сценария из YAML-файла и проверяет их.
+func LoadScenarios(path string) (Scenarios, error) {
+	var scenarios Scenarios
+
+	if err := cleanenv.ReadConfig(path, &scenarios); err != nil {
+		return Scenarios{}, fmt.Errorf("payment-LoadScenarios, %s", err.Error())
+	}
+
+	if err := scenarios.Validate(); err != nil {
+		return Scenarios{}, fmt.Errorf("payment-LoadScenarios, %s", err.Error())
+	}
+
+	return scenarios, nil
+}
+
+// Проверяет, что каждое правило переводит новый платеж в допустимый статус и имеет корректные
+// вероятность и задержку.
+func (s Scenarios) Validate() error {
+	for i, rule := range s.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d: name is required", i)
+		}
+
+		if !paymentStates.CanTransition(StatusNew, rule.Status) {
+			return fmt.Errorf("rule %q: status %q is not reachable from %q", rule.Name, rule.Status, StatusNew)
+		}
+
+		if rule.Probability < 0 || rule.Probability > 1 {
+			return fmt.Errorf("rule %q: probability must be between 0 and 1", rule.Name)
+		}
+
+		if rule.After < 0 {
+			return fmt.Errorf("rule %q: after must not be negative", rule.Name)
+		}
+	}
+
+	return nil
+}
+
+// Возвращает true, если платеж удовлетворяет всем условиям правила.
+func (m ScenarioMatch) matches(value payment) bool {
+	if m.AmountSuffix != "" && !strings.HasSuffix(moneyOf(value.Amount, value.Currency).Format(), m.AmountSuffix) {
+		return false
+	}
+
+	if m.Currency != "" && !strings.EqualFold(m.Currency, value.Currency) {
+		return false
+	}
+
+	if len(m.Emails) == 0 {
+		return true
+	}
+
+	for _, email := range m.Emails {
+		if strings.EqualFold(email, value.UserEmail) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ScenarioOptions — это структура с настройками фонового применения сценария.
+// @property {time.Duration} Interval - Период опроса новых платежей.
+// @property {int} BatchSize - Сколько новых платежей читается за один запрос.
+type ScenarioOptions struct {
+	Interval  time.Duration
+	BatchSize int
+}
+
+// Решение сценария по одному платежу.
+// @property {int64} PaymentID - ID платежа.
+// @property {string} Status - Статус, в который переводится платеж.
+// @property {string} Reason - Причина перехода.
+// @property {time.Time} Due - Момент, начиная с которого применяется статус.
+type scenarioDecision struct {
+	PaymentID int64
+	Status    string
+	Reason    string
+	Due       time.Time
+}
+
+// ScenarioWorker — это фоновый процесс, который применяет правила сценария к новым платежам. Каждый
+// опрос читает все платежи в статусе new, поэтому платеж с меньшим ID, транзакция которого
+// зафиксирована позже, не пропускается, а после перезапуска решения принимаются заново. Решение по
+// платежу принимается один раз, пока он остается новым, а статус меняется через UseCase.UpdateStatus,
+// когда наступает его время, поэтому переход попадает в историю, outbox и webhook как обычный.
+// @property {PaymentRepository} repo - Это хранилище, из которого читаются новые платежи.
+// @property {PaymentUseCase} UseCase - Это вариант использования, через который меняется статус.
+// @property logger - Это регистратор ошибок применения.
+// @property {Scenarios} scenarios - Это правила сценария.
+// @property {ScenarioOptions} options - Это настройки опроса.
+// @property random - Генератор случайных чисел для вероятностных правил.
+// @property decided - ID новых платежей, по которым уже принято решение, в том числе без сработавшего
+// правила. Платежи, которые больше не в статусе new, удаляются при следующем опросе.
+// @property pending - Решения, время которых еще не наступило.
+// @property now - Источник текущего времени.
+type ScenarioWorker struct {
+	repo      PaymentRepository
+	UseCase   PaymentUseCase
+	logger    loggin.ILogger
+	scenarios Scenarios
+	options   ScenarioOptions
+	random    *rand.Rand
+	decided   map[int64]struct{}
+	pending   []scenarioDecision
+	now       func() time.Time
+}
+
+// > Эта функция создает новый экземпляр структуры ScenarioWorker и возвращает указатель на нее.
+// Возвращает ошибку, если период опроса или размер пачки не больше нуля.
+func NewScenarioWorker(repo PaymentRepository, u PaymentUseCase, logger loggin.ILogger, scenarios Scenarios, options ScenarioOptions) (*ScenarioWorker, error) {
+	if options.Interval <= 0 {
+		return nil, fmt.Errorf("payment-NewScenarioWorker, interval %s must be positive", options.Interval)
+	}
+
+	if options.BatchSize <= 0 {
+		return nil, fmt.Errorf("payment-NewScenarioWorker, batch size %d must be positive", options.BatchSize)
+	}
+
+	seed := scenarios.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &ScenarioWorker{
+		repo:      repo,
+		UseCase:   u,
+		logger:    logger,
+		scenarios: scenarios,
+		options:   options,
+		random:    rand.New(rand.NewSource(seed)),
+		decided:   make(map[int64]struct{}),
+		pending:   make([]scenarioDecision, 0),
+		now:       time.Now,
+	}, nil
+}
+
+// Запускает опрос новых платежей и блокируется до отмены контекста.
+func (w *ScenarioWorker) Run(ctx context.Context) {
+	// Варианты использования и репозиторий пишут в журнал через регистратор из контекста.
+	ctx = loggin.NewContext(ctx, w.logger)
+
+	ticker := time.NewTicker(w.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Evaluate(ctx); err != nil {
+				w.logger.Error(err)
+			}
+		}
+	}
+}
+
+// Принимает решения по новым платежам, по которым их еще не принимали, и применяет решения, время
+// которых наступило. Платежи читаются страницами по ID с начала при каждом опросе.
+func (w *ScenarioWorker) Evaluate(ctx context.Context) error {
+	ctx = WithActor(ctx, ActorScenario)
+
+	seen := make(map[int64]struct{}, len(w.decided))
+
+	var after int64
+	for {
+		values, err := w.repo.GetPaymentsByStatus(
+			ctx,
+			StatusNew,
+			after,
+			w.options.BatchSize,
+		)
+		if err != nil {
+			return err
+		}
+
+		if len(values) == 0 {
+			break
+		}
+
+		for _, value := range values {
+			after = value.ID
+			seen[value.ID] = struct{}{}
+
+			if _, ok := w.decided[value.ID]; ok {
+				continue
+			}
+			w.decided[value.ID] = struct{}{}
+
+			if decision, ok := w.decide(value); ok {
+				w.pending = append(w.pending, decision)
+			}
+		}
+
+		if len(values) < w.options.BatchSize {
+			break
+		}
+	}
+
+	// Платеж, который больше не в статусе new, снова новым не станет.
+	for id := range w.decided {
+		if _, ok := seen[id]; !ok {
+			delete(w.decided, id)
+		}
+	}
+
+	return w.apply(ctx)
+}
+
+// Проверяет правила по порядку и возвращает решение первого сработавшего. Правило с вероятностью
+// бросает жребий только для подходящего платежа, при промахе проверяются следующие правила.
+func (w *ScenarioWorker) decide(value payment) (scenarioDecision, bool) {
+	for _, rule := range w.scenarios.Rules {
+		if !rule.Match.matches(value) {
+			continue
+		}
+
+		if rule.Probability > 0 && w.random.Float64() >= rule.Probability {
+			continue
+		}
+
+		reason := rule.Reason
+		if reason == "" {
+			reason = "scenario: " + rule.Name
+		}
+
+		return scenarioDecision{
+			PaymentID: value.ID,
+			Status:    rule.Status,
+			Reason:    reason,
+			Due:       scenarioCreatedAt(value, w.now()).Add(time.Duration(rule.After) * time.Second),
+		}, true
+	}
+
+	return scenarioDecision{}, false
+}
+
+// Применяет решения, время которых наступило. Решение отбрасывается, если платеж уже перевели в другой
+// статус или удалили, и остается в очереди при любой другой ошибке.
+func (w *ScenarioWorker) apply(ctx context.Context) error {
+	sort.SliceStable(w.pending, func(i, j int) bool {
+		return w.pending[i].Due.Before(w.pending[j].Due)
+	})
+
+	now := w.now()
+	remaining := w.pending[:0]
+
+	var failed error
+	for i, decision := range w.pending {
+		if decision.Due.After(now) {
+			remaining = append(remaining, w.pending[i:]...)
+			break
+		}
+
+		err := w.UseCase.UpdateStatus(
+			ctx,
+			PaymentStatus{
+				ID:     decision.PaymentID,
+				Status: decision.Status,
+				Reason: decision.Reason,
+			},
+		)
+
+		var transitionErr *TransitionError
+		switch {
+		case err == nil, errors.As(err, &transitionErr), errors.Is(err, ErrPaymentNotFound):
+		default:
+			failed = err
+			remaining = append(remaining, decision)
+		}
+	}
+
+	w.pending = remaining
+
+	return failed
+}
+
+// Возвращает время создания платежа или now, если его не удалось разобрать.
+func scenarioCreatedAt(value payment, now time.Time) time.Time {
+	createdAt, err := time.Parse(time.RFC3339Nano, value.CreatedAt)
+	if err != nil {
+		return now
+	}
+
+	return createdAt
+}
@@ -0,0 +1,234 @@
+package payment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет, что правила из config/scenarios.yml читаются и проходят проверку.
+func TestLoadScenarios(t *testing.T) {
+	t.Parallel()
+
+	scenarios, err := LoadScenarios("../../config/scenarios.yml")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, scenarios.Rules)
+}
+
+// Он проверяет отклонение правил, которые не могут перевести новый платеж.
+func TestScenariosValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input ScenarioRule
+		err   bool
+	}{
+		{
+			name:  "Valid rule",
+			input: ScenarioRule{Name: "ok", Status: StatusSuccess, Probability: 0.5, After: 10},
+		},
+		{
+			name:  "Missing name",
+			input: ScenarioRule{Status: StatusSuccess},
+			err:   true,
+		},
+		{
+			name:  "Status new",
+			input: ScenarioRule{Name: "new", Status: StatusNew},
+			err:   true,
+		},
+		{
+			name:  "Unknown status",
+			input: ScenarioRule{Name: "unknown", Status: "unknown"},
+			err:   true,
+		},
+		{
+			name:  "Probability above one",
+			input: ScenarioRule{Name: "probability", Status: StatusError, Probability: 1.5},
+			err:   true,
+		},
+		{
+			name:  "Negative delay",
+			input: ScenarioRule{Name: "after", Status: StatusSuccess, After: -1},
+			err:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		err := Scenarios{Rules: []ScenarioRule{tt.input}}.Validate()
+
+		if tt.err {
+			assert.Error(t, err, tt.name)
+		} else {
+			assert.NoError(t, err, tt.name)
+		}
+	}
+}
+
+// Он проверяет, что фоновый процесс применяет первое подходящее правило, соблюдает задержку и
+// записывает переход от имени сценария.
+func TestScenarioWorkerEvaluate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, 7, 15, 12, 0, 0, 0, time.UTC)
+
+	repo := NewMemoryPaymentRepository()
+	repo.now = func() time.Time { return now }
+
+	usc := NewPaymentUseCase(repo, Options{})
+
+	worker, err := NewScenarioWorker(
+		repo,
+		usc,
+		nil,
+		Scenarios{
+			Seed: 1,
+			Rules: []ScenarioRule{
+				{Name: "cents", Match: ScenarioMatch{AmountSuffix: ".13"}, Status: StatusFailure},
+				{Name: "qa", Match: ScenarioMatch{Emails: []string{"qa@mail.ru"}}, Status: StatusSuccess, Reason: "qa"},
+				{Name: "never", Match: ScenarioMatch{Currency: "rub"}, Probability: 0.000001, Status: StatusError},
+				{Name: "delayed", Match: ScenarioMatch{Currency: "eur"}, After: 30, Status: StatusSuccess},
+			},
+		},
+		ScenarioOptions{Interval: time.Second, BatchSize: 2},
+	)
+	assert.NoError(t, err)
+	worker.now = func() time.Time { return now }
+
+	ctx := context.TODO()
+
+	inputs := []PaymentInput{
+		{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("10.13"), Currency: "usd"},
+		{UserID: 1, UserEmail: "qa@mail.ru", Amount: mustDecimal("5"), Currency: "usd"},
+		{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("7"), Currency: "rub"},
+		{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("7"), Currency: "eur"},
+	}
+	for _, input := range inputs {
+		_, err := repo.CreatePayment(ctx, input)
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, worker.Evaluate(ctx))
+
+	expect := []string{StatusFailure, StatusSuccess, StatusNew, StatusNew}
+	for i, status := range expect {
+		got, err := repo.GetStatus(ctx, int64(i+1))
+		assert.NoError(t, err)
+		assert.Equal(t, status, got)
+	}
+
+	history, err := repo.GetHistory(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "qa", history[1].Reason)
+	assert.Equal(t, ActorScenario, history[1].Actor)
+
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, worker.Evaluate(ctx))
+
+	got, err := repo.GetStatus(ctx, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSuccess, got)
+	assert.Empty(t, worker.pending)
+}
+
+// Он проверяет, что платеж с меньшим ID, который появился после опроса, получает решение, решение по
+// платежу без сработавшего правила не принимается повторно, а перезапущенный процесс принимает
+// отложенные решения заново.
+func TestScenarioWorkerLateCommit(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, 7, 15, 12, 0, 0, 0, time.UTC)
+
+	repo := NewMemoryPaymentRepository()
+	repo.now = func() time.Time { return now }
+
+	usc := NewPaymentUseCase(repo, Options{})
+
+	scenarios := Scenarios{
+		Seed: 1,
+		Rules: []ScenarioRule{
+			{Name: "cents", Match: ScenarioMatch{AmountSuffix: ".13"}, Status: StatusFailure},
+			{Name: "delayed", Match: ScenarioMatch{Currency: "eur"}, After: 30, Status: StatusSuccess},
+		},
+	}
+
+	newWorker := func() *ScenarioWorker {
+		worker, err := NewScenarioWorker(repo, usc, nil, scenarios, ScenarioOptions{Interval: time.Second, BatchSize: 1})
+		assert.NoError(t, err)
+		worker.now = func() time.Time { return now }
+
+		return worker
+	}
+
+	ctx := context.TODO()
+
+	inputs := []PaymentInput{
+		{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("10.13"), Currency: "usd"},
+		{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("7"), Currency: "usd"},
+		{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("7"), Currency: "eur"},
+	}
+	for _, input := range inputs {
+		_, err := repo.CreatePayment(ctx, input)
+		assert.NoError(t, err)
+	}
+
+	// Транзакция платежа 1 еще не зафиксирована, когда процесс читает платежи 2 и 3.
+	repo.mu.Lock()
+	late := repo.payments[1]
+	delete(repo.payments, 1)
+	repo.mu.Unlock()
+
+	worker := newWorker()
+	assert.NoError(t, worker.Evaluate(ctx))
+	assert.Equal(t, map[int64]struct{}{2: {}, 3: {}}, worker.decided)
+	assert.Len(t, worker.pending, 1)
+
+	repo.mu.Lock()
+	repo.payments[1] = late
+	repo.mu.Unlock()
+
+	assert.NoError(t, worker.Evaluate(ctx))
+
+	got, err := repo.GetStatus(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusFailure, got)
+	assert.Len(t, worker.pending, 1)
+
+	// Перезапуск: отложенное решение по платежу 3 принимается заново и применяется в свой срок.
+	worker = newWorker()
+	assert.NoError(t, worker.Evaluate(ctx))
+	assert.Equal(t, map[int64]struct{}{2: {}, 3: {}}, worker.decided)
+
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, worker.Evaluate(ctx))
+
+	got, err = repo.GetStatus(ctx, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSuccess, got)
+	assert.Empty(t, worker.pending)
+
+	assert.NoError(t, worker.Evaluate(ctx))
+	assert.Equal(t, map[int64]struct{}{2: {}}, worker.decided)
+}
+
+// Он проверяет, что процесс не создается с нулевым периодом опроса или размером пачки.
+func TestNewScenarioWorkerOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options ScenarioOptions
+	}{
+		{name: "Zero interval", options: ScenarioOptions{BatchSize: 100}},
+		{name: "Zero batch size", options: ScenarioOptions{Interval: time.Second}},
+		{name: "Negative batch size", options: ScenarioOptions{Interval: time.Second, BatchSize: -1}},
+	}
+
+	for _, tt := range tests {
+		_, err := NewScenarioWorker(nil, nil, nil, Scenarios{}, tt.options)
+		assert.Error(t, err, tt.name)
+	}
+}
@@ -0,0 +1,129 @@
+package payment
+
+import "fmt"
+
+// ErrUnknownStatus возвращается, когда запрошенный статус не входит в список статусов платежа.
+var ErrUnknownStatus = newError(ErrValidation, CodeUnknownStatus, "unknown status")
+
+// TransitionError — это ошибка, которая возвращается, когда переход между статусами запрещен
+// машиной состояний.
+// @property {string} Current - Текущий статус платежа.
+// @property {string} Requested - Статус, в который пытались перевести платеж.
+type TransitionError struct {
+	Current   string
+	Requested string
+}
+
+// Возвращает текст ошибки с текущим и запрошенным статусом.
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("invalid status transition from %s to %s", e.Current, e.Requested)
+}
+
+// Относит запрещенный переход к ErrTerminalStatus, если текущий статус терминальный, и к ErrConflict
+// в остальных случаях.
+func (e *TransitionError) Is(target error) bool {
+	if paymentStates.IsTerminal(e.Current) {
+		return target == ErrTerminalStatus
+	}
+
+	return target == ErrConflict
+}
+
+// StateMachine — это таблица допустимых переходов между статусами платежа.
+// @property {[]string} statuses - Все известные статусы в порядке их объявления.
+// @property transitions - Для каждого статуса набор статусов, в которые из него можно перейти.
+type StateMachine struct {
+	statuses    []string
+	transitions map[string]map[string]struct{}
+}
+
+// > Эта функция создает машину состояний платежа с таблицей переходов эмулятора.
+//
+// new        -> authorized, error, success, failure, canceled
+// authorized -> success, failure, canceled
+// error      -> failure, canceled
+// success, failure, canceled - терминальные статусы.
+func NewStateMachine() *StateMachine {
+	s := &StateMachine{
+		statuses: []string{
+			StatusNew,
+			StatusAuthorized,
+			StatusError,
+			StatusSuccess,
+			StatusFailure,
+			StatusCanceled,
+		},
+		transitions: make(map[string]map[string]struct{}),
+	}
+
+	s.allow(StatusNew, StatusAuthorized, StatusError, StatusSuccess, StatusFailure, StatusCanceled)
+	s.allow(StatusAuthorized, StatusSuccess, StatusFailure, StatusCanceled)
+	s.allow(StatusError, StatusFailure, StatusCanceled)
+
+	return s
+}
+
+// Добавляет в таблицу переходы из статуса from во все статусы to.
+func (s *StateMachine) allow(from string, to ...string) {
+	if _, ok := s.transitions[from]; !ok {
+		s.transitions[from] = make(map[string]struct{})
+	}
+
+	for _, status := range to {
+		s.transitions[from][status] = struct{}{}
+	}
+}
+
+// Возвращает true, если статус известен машине состояний.
+func (s *StateMachine) IsKnown(status string) bool {
+	for _, known := range s.statuses {
+		if known == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Возвращает true, если из статуса нельзя перейти ни в какой другой.
+func (s *StateMachine) IsTerminal(status string) bool {
+	return len(s.transitions[status]) == 0
+}
+
+// Возвращает true, если переход из статуса from в статус to разрешен.
+func (s *StateMachine) CanTransition(from, to string) bool {
+	_, ok := s.transitions[from][to]
+
+	return ok
+}
+
+// Проверяет переход и возвращает ErrUnknownStatus или *TransitionError, если он запрещен.
+func (s *StateMachine) Transition(from, to string) error {
+	if !s.IsKnown(to) {
+		return ErrUnknownStatus
+	}
+
+	if !s.CanTransition(from, to) {
+		return &TransitionError{
+			Current:   from,
+			Requested: to,
+		}
+	}
+
+	return nil
+}
+
+// Возвращает статусы, из которых разрешен переход в статус to, в порядке их объявления.
+func (s *StateMachine) Sources(to string) []string {
+	sources := make([]string, 0, len(s.statuses))
+	for _, from := range s.statuses {
+		if s.CanTransition(from, to) {
+			sources = append(sources, from)
+		}
+	}
+
+	return sources
+}
+
+// Машина состояний, которую используют варианты использования и репозиторий.
+var paymentStates = NewStateMachine()
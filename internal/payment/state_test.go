@@ -0,0 +1,93 @@
+package payment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет таблицу переходов машины состояний платежа.
+func TestStateMachineTransition(t *testing.T) {
+	t.Parallel()
+
+	states := NewStateMachine()
+
+	tests := []struct {
+		name string
+		from string
+		to   string
+		err  error
+	}{
+		{
+			name: "New to success",
+			from: StatusNew,
+			to:   StatusSuccess,
+		},
+		{
+			name: "New to authorized",
+			from: StatusNew,
+			to:   StatusAuthorized,
+		},
+		{
+			name: "Authorized to success",
+			from: StatusAuthorized,
+			to:   StatusSuccess,
+		},
+		{
+			name: "Authorized to new",
+			from: StatusAuthorized,
+			to:   StatusNew,
+			err:  &TransitionError{Current: StatusAuthorized, Requested: StatusNew},
+		},
+		{
+			name: "Error to canceled",
+			from: StatusError,
+			to:   StatusCanceled,
+		},
+		{
+			name: "Canceled to success",
+			from: StatusCanceled,
+			to:   StatusSuccess,
+			err:  &TransitionError{Current: StatusCanceled, Requested: StatusSuccess},
+		},
+		{
+			name: "Error to new",
+			from: StatusError,
+			to:   StatusNew,
+			err:  &TransitionError{Current: StatusError, Requested: StatusNew},
+		},
+		{
+			name: "Success to canceled",
+			from: StatusSuccess,
+			to:   StatusCanceled,
+			err:  &TransitionError{Current: StatusSuccess, Requested: StatusCanceled},
+		},
+		{
+			name: "Unknown status",
+			from: StatusNew,
+			to:   "unknown",
+			err:  ErrUnknownStatus,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := states.Transition(tt.from, tt.to)
+
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+// Он проверяет список статусов, из которых разрешен переход.
+func TestStateMachineSources(t *testing.T) {
+	t.Parallel()
+
+	states := NewStateMachine()
+
+	assert.Equal(t, []string{StatusNew, StatusAuthorized}, states.Sources(StatusSuccess))
+	assert.Equal(t, []string{StatusNew, StatusAuthorized, StatusError}, states.Sources(StatusCanceled))
+	assert.Empty(t, states.Sources(StatusNew))
+	assert.True(t, states.IsTerminal(StatusFailure))
+	assert.False(t, states.IsTerminal(StatusError))
+}
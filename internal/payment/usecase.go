@@ -3,20 +3,25 @@ package payment
 import (
 	"context"
 	"fmt"
-	"sync"
+	"time"
 )
 
 // UseCase — это структура с полем repo типа PaymentRepository.
 // @property {PaymentRepository} repo - Это репозиторий, который будет использоваться для хранения
 // платежа.
+// @property {Options} options - Это настройки варианта использования.
+// @property notifiers - Получатели событий платежей.
 type UseCase struct {
-	repo PaymentRepository
+	repo      PaymentRepository
+	options   Options
+	notifiers []Notifier
 }
 
 // > Эта функция создает новый экземпляр структуры UseCase и возвращает указатель на нее.
-func NewPaymentUseCase(repo PaymentRepository) *UseCase {
+func NewPaymentUseCase(repo PaymentRepository, options Options) *UseCase {
 	return &UseCase{
-		repo: repo,
+		repo:    repo,
+		options: options,
 	}
 }
 
@@ -27,70 +32,107 @@ func (u *UseCase) CreatePayment(ctx context.Context, input PaymentInput) (int64,
 		input,
 	)
 	if err != nil {
-		wg := &sync.WaitGroup{}
-
-		wg.Add(1)
-		go func() {
-			_ = u.UpdateStatus(
-				ctx,
-				PaymentStatus{
-					ID:     PaymentID,
-					Status: StatusError,
-				},
-			)
-
-			wg.Done()
-		}()
-		wg.Wait()
-
 		return 0, err
 	}
 
+	u.notify(ctx, PaymentEvent{
+		Type:      EventPaymentCreated,
+		PaymentID: PaymentID,
+		NewStatus: StatusNew,
+	})
+
 	return PaymentID, nil
 }
 
 // Эта функция используется для обновления статуса платежа.
 func (u *UseCase) UpdateStatus(ctx context.Context, input PaymentStatus) error {
-	ErrorExeption := make(chan error)
-	uCtx, cancel := context.WithCancel(ctx)
+	err := u.transition(ctx, input, input.Status, nil, func(ctx context.Context) (int64, error) {
+		return u.repo.UpdateStatus(
+			ctx,
+			input,
+		)
+	})
+	if err != nil {
+		return fmt.Errorf("payment-UseCase-UpdateStatus, %w", err)
+	}
+
+	return nil
+}
 
-	go func() {
-		status, err := u.repo.GetStatus(
-			uCtx,
+// Переводит платеж input.ID в статус to в одной транзакции: блокирует платеж, проверяет его версию,
+// условие check (если задано) и переход по машине состояний и выполняет apply с контекстом
+// транзакции. Ненулевая версия input.Version должна совпадать с версией платежа, иначе возвращается
+// ErrVersionMismatch; запрещенный переход возвращает *TransitionError с текущим статусом.
+// Получатели событий уведомляются после фиксации транзакции.
+func (u *UseCase) transition(ctx context.Context, input PaymentStatus, to string, check func(current payment) error, apply func(ctx context.Context) (int64, error)) error {
+	var from string
+
+	err := u.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		current, err := u.repo.LockPayment(
+			ctx,
 			input.ID,
 		)
 		if err != nil {
-			return
+			return err
 		}
 
-		if status == StatusSuccess || status == StatusFailure {
-			ErrorExeption <- fmt.Errorf("payment-UseCase-UpdateStatus-GetStatus, terminal status %s", status)
+		if input.Version != 0 && current.Version != input.Version {
+			return ErrVersionMismatch
 		}
-	}()
 
-	go func() {
-		r, err := u.repo.UpdateStatus(
-			uCtx,
-			input,
-		)
+		if check != nil {
+			if err := check(current); err != nil {
+				return err
+			}
+		}
+
+		if err := paymentStates.Transition(current.Status, to); err != nil {
+			return err
+		}
+
+		rows, err := apply(ctx)
 		if err != nil {
-			ErrorExeption <- err
-			return
+			return err
 		}
 
-		if err := checkTerminalStatusRow(r); err != nil {
-			ErrorExeption <- fmt.Errorf("payment-UseCase-UpdateStatus, %s", err.Error())
+		// Платеж заблокирован, поэтому его может изменить только запрос, который обходит транзакции,
+		// например списание в памяти.
+		if rows == 0 {
+			current, err = u.repo.GetPayment(
+				ctx,
+				input.ID,
+			)
+			if err != nil {
+				return err
+			}
+
+			if input.Version != 0 && current.Version != input.Version {
+				return ErrVersionMismatch
+			}
+
+			return &TransitionError{
+				Current:   current.Status,
+				Requested: to,
+			}
 		}
 
-		cancel()
-	}()
+		from = current.Status
 
-	select {
-	case <-uCtx.Done():
 		return nil
-	case err := <-ErrorExeption:
+	})
+	if err != nil {
 		return err
 	}
+
+	u.notify(ctx, PaymentEvent{
+		Type:      EventPaymentStatusChanged,
+		PaymentID: input.ID,
+		OldStatus: from,
+		NewStatus: to,
+		Reason:    input.Reason,
+	})
+
+	return nil
 }
 
 // Эта функция используется для получения статуса платежа.
@@ -101,54 +143,192 @@ func (u *UseCase) GetStatus(ctx context.Context, PaymentID int64) (string, error
 	)
 }
 
-// Эта функция используется для получения всех платежей для пользователя.
-func (u *UseCase) GetPayments(ctx context.Context, input PaymentUser) ([]payment, error) {
-	return u.repo.GetPayments(
+// Эта функция используется для получения платежа по его ID.
+func (u *UseCase) GetPayment(ctx context.Context, PaymentID int64) (payment, error) {
+	return u.repo.GetPayment(
+		ctx,
+		PaymentID,
+	)
+}
+
+// Эта функция используется для получения страницы платежей пользователя. Если платежей больше, чем
+// Limit, возвращается курсор следующей страницы.
+func (u *UseCase) GetPayments(ctx context.Context, input PaymentUser) (PaymentsData, error) {
+	if input.Limit <= 0 {
+		input.Limit = DefaultPageLimit
+	}
+
+	if input.Sort == "" {
+		input.Sort = SortAsc
+	}
+
+	data, err := u.repo.GetPayments(
 		ctx,
 		input,
 	)
+	if err != nil {
+		return PaymentsData{}, err
+	}
+
+	output := PaymentsData{
+		Data: data,
+	}
+
+	if len(data) > input.Limit {
+		output.Data = data[:input.Limit]
+		output.NextCursor = encodeCursor(output.Data[input.Limit-1].ID, input.Sort)
+	}
+
+	return output, nil
 }
 
 // Эта функция используется для отмены платежа.
-func (u *UseCase) CancelPayment(ctx context.Context, PaymentID int64) error {
-	ErrorExeption := make(chan error)
-	dCtx, cancel := context.WithCancel(ctx)
-
-	go func() {
-		status, err := u.repo.GetStatus(
-			dCtx,
-			PaymentID,
+func (u *UseCase) CancelPayment(ctx context.Context, input PaymentStatus) error {
+	err := u.transition(ctx, input, StatusCanceled, nil, func(ctx context.Context) (int64, error) {
+		return u.repo.CancelPayment(
+			ctx,
+			input,
 		)
-		if err != nil {
-			return
-		}
+	})
+	if err != nil {
+		return fmt.Errorf("payment-UseCase-CancelPayment, %w", err)
+	}
 
-		if status == StatusSuccess || status == StatusFailure {
-			ErrorExeption <- fmt.Errorf("payment-UseCase-CancelPayment-GetStatus, terminal status %s", status)
-		}
-	}()
+	return nil
+}
+
+// Возвращает ErrNotAuthorized, если платеж не авторизован. Это условие списания и отмены авторизации.
+func authorized(current payment) error {
+	if current.Status != StatusAuthorized {
+		return ErrNotAuthorized
+	}
+
+	return nil
+}
 
-	go func() {
-		r, err := u.repo.CancelPayment(
-			dCtx,
-			PaymentID,
+// Эта функция используется для списания авторизованного платежа. Платеж переходит в «success», а
+// списанная сумма может быть меньше авторизованной. Возвращает ErrNotAuthorized, если платеж не
+// авторизован.
+func (u *UseCase) CapturePayment(ctx context.Context, input CaptureInput) (CaptureData, error) {
+	status := PaymentStatus{
+		ID:      input.PaymentID,
+		Reason:  input.Reason,
+		Version: input.Version,
+	}
+
+	var captured Decimal
+	err := u.transition(ctx, status, StatusSuccess, authorized, func(ctx context.Context) (int64, error) {
+		var err error
+		captured, err = u.repo.CapturePayment(
+			ctx,
+			input,
 		)
 		if err != nil {
-			ErrorExeption <- err
-			return
+			return 0, err
 		}
 
-		if err := checkTerminalStatusRow(r); err != nil {
-			ErrorExeption <- fmt.Errorf("payment-UseCase-deletePayment, %s", err.Error())
-		}
+		return 1, nil
+	})
+	if err != nil {
+		return CaptureData{}, fmt.Errorf("payment-UseCase-CapturePayment, %w", err)
+	}
 
-		cancel()
-	}()
+	return CaptureData{
+		ID:             input.PaymentID,
+		Status:         StatusSuccess,
+		CapturedAmount: captured,
+	}, nil
+}
 
-	select {
-	case <-dCtx.Done():
-		return nil
-	case err := <-ErrorExeption:
-		return err
+// Эта функция используется для отмены авторизации платежа. Возвращает ErrNotAuthorized, если платеж
+// не авторизован.
+func (u *UseCase) VoidPayment(ctx context.Context, input PaymentStatus) error {
+	err := u.transition(ctx, input, StatusCanceled, authorized, func(ctx context.Context) (int64, error) {
+		return u.repo.VoidPayment(
+			ctx,
+			input,
+		)
+	})
+	if err != nil {
+		return fmt.Errorf("payment-UseCase-VoidPayment, %w", err)
 	}
+
+	return nil
+}
+
+// Эта функция используется для получения сохраненного ответа по Idempotency-Key.
+func (u *UseCase) GetIdempotencyKey(ctx context.Context, key string) (IdempotencyKey, bool, error) {
+	return u.repo.GetIdempotencyKey(
+		ctx,
+		key,
+	)
+}
+
+// Эта функция используется для резервирования Idempotency-Key за выполняющимся запросом на время
+// IdempotencyLockTimeout. Если процесс упадет до ответа, ключ освободится после этого времени.
+func (u *UseCase) ReserveIdempotencyKey(ctx context.Context, input IdempotencyKey) (bool, error) {
+	input.ExpiresAt = time.Now().Add(IdempotencyLockTimeout)
+
+	return u.repo.ReserveIdempotencyKey(
+		ctx,
+		input,
+	)
+}
+
+// Эта функция используется для снятия резерва Idempotency-Key, ответ на который не сохраняется.
+func (u *UseCase) ReleaseIdempotencyKey(ctx context.Context, input IdempotencyKey) error {
+	return u.repo.ReleaseIdempotencyKey(
+		ctx,
+		input,
+	)
+}
+
+// Эта функция используется для сохранения ответа по Idempotency-Key на время IdempotencyTTL.
+func (u *UseCase) SaveIdempotencyKey(ctx context.Context, input IdempotencyKey) error {
+	input.ExpiresAt = time.Now().Add(u.options.IdempotencyTTL)
+
+	return u.repo.SaveIdempotencyKey(
+		ctx,
+		input,
+	)
+}
+
+// Эта функция используется для создания возврата платежа.
+func (u *UseCase) CreateRefund(ctx context.Context, input RefundInput) (refund, error) {
+	return u.repo.CreateRefund(
+		ctx,
+		input,
+	)
+}
+
+// Эта функция используется для получения всех возвратов платежа.
+func (u *UseCase) GetRefunds(ctx context.Context, PaymentID int64) ([]refund, error) {
+	_, err := u.repo.GetStatus(
+		ctx,
+		PaymentID,
+	)
+	if err != nil {
+		return []refund{}, err
+	}
+
+	return u.repo.GetRefunds(
+		ctx,
+		PaymentID,
+	)
+}
+
+// Эта функция используется для получения истории переходов статуса платежа.
+func (u *UseCase) GetHistory(ctx context.Context, PaymentID int64) ([]statusChange, error) {
+	_, err := u.repo.GetStatus(
+		ctx,
+		PaymentID,
+	)
+	if err != nil {
+		return []statusChange{}, err
+	}
+
+	return u.repo.GetHistory(
+		ctx,
+		PaymentID,
+	)
 }
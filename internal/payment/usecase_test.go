@@ -0,0 +1,175 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingNotifier — это получатель, который считает события смены статуса по платежам.
+type countingNotifier struct {
+	mu      sync.Mutex
+	changes map[int64]int
+}
+
+// Он увеличивает счетчик смен статуса платежа.
+func (n *countingNotifier) Notify(ctx context.Context, event PaymentEvent) {
+	if event.Type != EventPaymentStatusChanged {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.changes[event.PaymentID]++
+}
+
+// Он проверяет, что смена статуса возвращает точную ошибку для неизвестного платежа, терминального
+// статуса, запрещенного перехода и устаревшей версии.
+func TestUseCaseTransitionErrors(t *testing.T) {
+	t.Parallel()
+
+	repo := NewMemoryPaymentRepository()
+	usc := NewPaymentUseCase(repo, Options{})
+	ctx := context.TODO()
+
+	for i := 0; i < 2; i++ {
+		_, err := repo.CreatePayment(ctx, PaymentInput{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("10"), Currency: "usd"})
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, usc.CancelPayment(ctx, PaymentStatus{ID: 1}))
+
+	tests := []struct {
+		name  string
+		apply func() error
+		err   error
+	}{
+		{
+			name:  "Not found",
+			apply: func() error { return usc.UpdateStatus(ctx, PaymentStatus{ID: 100, Status: StatusSuccess}) },
+			err:   ErrPaymentNotFound,
+		},
+		{
+			name:  "Terminal status",
+			apply: func() error { return usc.CancelPayment(ctx, PaymentStatus{ID: 1}) },
+			err:   ErrTerminalStatus,
+		},
+		{
+			name:  "Invalid transition",
+			apply: func() error { return usc.UpdateStatus(ctx, PaymentStatus{ID: 2, Status: StatusNew}) },
+			err:   ErrConflict,
+		},
+		{
+			name:  "Stale version",
+			apply: func() error { return usc.UpdateStatus(ctx, PaymentStatus{ID: 2, Status: StatusSuccess, Version: 5}) },
+			err:   ErrVersionMismatch,
+		},
+		{
+			name:  "Void of a payment that is not authorized",
+			apply: func() error { return usc.VoidPayment(ctx, PaymentStatus{ID: 1}) },
+			err:   ErrNotAuthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		assert.ErrorIs(t, tt.apply(), tt.err, tt.name)
+	}
+
+	status, err := usc.GetStatus(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusNew, status)
+}
+
+// Он запускает параллельные смены статуса одних и тех же платежей и проверяет, что каждый платеж
+// переходит в терминальный статус ровно один раз, остальные запросы получают ошибку перехода, а после
+// завершения не остается горутин.
+func TestUseCaseConcurrentTransitions(t *testing.T) {
+	const (
+		paymentsCount = 20
+		workers       = 8
+	)
+
+	repo := NewMemoryPaymentRepository()
+	usc := NewPaymentUseCase(repo, Options{})
+
+	notifier := &countingNotifier{changes: make(map[int64]int)}
+	usc.Subscribe(notifier)
+
+	ctx := context.TODO()
+
+	for i := 0; i < paymentsCount; i++ {
+		_, err := repo.CreatePayment(ctx, PaymentInput{UserID: 1, UserEmail: "user@mail.ru", Amount: mustDecimal("10"), Currency: "usd"})
+		assert.NoError(t, err)
+	}
+
+	before := runtime.NumGoroutine()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded = make(map[int64]int)
+		failed    []error
+	)
+
+	for id := int64(1); id <= paymentsCount; id++ {
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(id int64, i int) {
+				defer wg.Done()
+
+				var err error
+				switch i % 4 {
+				case 0:
+					err = usc.CancelPayment(ctx, PaymentStatus{ID: id, Reason: fmt.Sprintf("worker %d", i)})
+				case 1:
+					err = usc.UpdateStatus(ctx, PaymentStatus{ID: id, Status: StatusSuccess})
+				case 2:
+					err = usc.UpdateStatus(ctx, PaymentStatus{ID: id, Status: StatusFailure})
+				default:
+					err = usc.UpdateStatus(ctx, PaymentStatus{ID: id, Status: StatusCanceled, Version: 1})
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				if err == nil {
+					succeeded[id]++
+					return
+				}
+
+				var transitionErr *TransitionError
+				if !errors.As(err, &transitionErr) && !errors.Is(err, ErrVersionMismatch) {
+					failed = append(failed, err)
+				}
+			}(id, i)
+		}
+	}
+	wg.Wait()
+
+	assert.Empty(t, failed)
+
+	for id := int64(1); id <= paymentsCount; id++ {
+		assert.Equal(t, 1, succeeded[id], id)
+		assert.Equal(t, 1, notifier.changes[id], id)
+
+		history, err := usc.GetHistory(ctx, id)
+		assert.NoError(t, err)
+		assert.Len(t, history, 2, id)
+		assert.True(t, paymentStates.IsTerminal(history[1].ToStatus), id)
+	}
+
+	// Горутины теста завершаются не мгновенно после wg.Done, поэтому их число проверяется с ожиданием.
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
@@ -1,12 +1,12 @@
 package payment
 
 import (
-	"errors"
 	"net/http"
-	"net/mail"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/onlycodergod/payment-api-emulator/internal/currency"
 )
 
 // Он принимает http-запрос, получает идентификатор из запроса, преобразует его в int64 и возвращает.
@@ -30,20 +30,121 @@ func ConverteIDtoI64(id string) (int64, error) {
 	return int64(convertedID), nil
 }
 
-// Функция называется checkTerminalStatusRow и принимает один аргумент, строку, которая имеет тип
-// int64. Функция возвращает ошибку
-func checkTerminalStatusRow(row int64) error {
-	if row == 0 {
-		return errors.New("terminal status")
+// Возвращает список плейсхолдеров вида «$3, $4, $5» для n аргументов, начиная с номера start.
+func placeholders(start, n int) string {
+	list := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		list = append(list, "$"+strconv.Itoa(start+i))
 	}
 
-	return nil
+	return strings.Join(list, ", ")
 }
 
-// Возвращает true, если данная строка является действительным адресом электронной почты, и false в
-// противном случае.
-func isEmail(address string) bool {
-	_, err := mail.ParseAddress(address)
+// Возвращает сумму возврата по списанной сумме, уже возвращенной сумме, статусу и валюте платежа.
+// Нулевая запрошенная сумма означает возврат всего остатка.
+func refundAmount(captured, refunded Decimal, status string, cur currency.Currency, requested Decimal) (Decimal, error) {
+	if status != StatusSuccess {
+		return Decimal{}, ErrRefundNotAllowed
+	}
+
+	remaining := captured.Sub(refunded)
+	if requested.IsZero() {
+		if remaining.Sign() <= 0 {
+			return Decimal{}, ErrRefundExceedsAmount
+		}
+
+		return remaining, nil
+	}
+
+	if _, err := NewMoney(requested, cur); err != nil {
+		return Decimal{}, err
+	}
+
+	if requested.Cmp(remaining) > 0 {
+		return Decimal{}, ErrRefundExceedsAmount
+	}
+
+	return requested, nil
+}
+
+// Возвращает true, если value есть в списке list.
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Обрезает строку до n байт.
+func truncate(value string, n int) string {
+	if len(value) > n {
+		return value[:n]
+	}
+
+	return value
+}
+
+// Возвращает сумму списания по авторизованной сумме, статусу и валюте платежа. Нулевая запрошенная
+// сумма означает списание всей авторизованной суммы.
+func captureAmount(amount Decimal, status string, cur currency.Currency, requested Decimal) (Decimal, error) {
+	if status != StatusAuthorized {
+		return Decimal{}, ErrNotAuthorized
+	}
+
+	if requested.IsZero() {
+		return amount, nil
+	}
+
+	if _, err := NewMoney(requested, cur); err != nil {
+		return Decimal{}, err
+	}
+
+	if requested.Cmp(amount) > 0 {
+		return Decimal{}, ErrCaptureExceedsAmount
+	}
+
+	return requested, nil
+}
+
+// Возвращает ETag платежа — его версию, которая увеличивается при каждом изменении платежа, поэтому
+// совпадение тегов означает, что платеж не изменился.
+func paymentETag(value payment) string {
+	return `"` + strconv.FormatInt(value.Version, 10) + `"`
+}
+
+// Возвращает версию платежа из заголовка If-Match. Пустой заголовок и «*» дают ноль — версия не
+// проверяется. Слабый или некорректный тег не может совпасть с версией платежа, поэтому для него
+// возвращается ErrVersionMismatch.
+func parseIfMatch(header string) (int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return 0, ErrVersionMismatch
+	}
+
+	version, err := strconv.ParseInt(header[1:len(header)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, ErrVersionMismatch
+	}
+
+	return version, nil
+}
+
+// Возвращает true, если заголовок If-None-Match содержит тег etag или «*». Слабые теги сравниваются
+// без префикса «W/».
+func matchETag(header, etag string) bool {
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+		if value == "*" || value == etag {
+			return true
+		}
+	}
 
-	return err == nil
+	return false
 }
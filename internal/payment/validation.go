@@ -0,0 +1,120 @@
+package payment
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/onlycodergod/payment-api-emulator/pkg/validator"
+)
+
+// Максимальный размер тела запроса в байтах.
+const MaxBodySize = 1 << 20
+
+// Проверка входных данных платежей. Кроме встроенных правил знает правило status: статус известен
+// машине состояний платежа.
+var validate = validator.New().Register("status", knownStatus)
+
+// Правило status: строка - известный статус платежа.
+func knownStatus(value reflect.Value, param string) string {
+	if !paymentStates.IsKnown(value.String()) {
+		return UnknownValue
+	}
+
+	return ""
+}
+
+// Проверяет структуру по тегам validate и возвращает ошибку проверки со всеми полями или nil.
+func validateStruct(input interface{}) error {
+	return validationError(validate.Struct(input))
+}
+
+// Преобразует ошибки пакета validator в ошибку проверки входных данных.
+func validationError(err error) error {
+	var errs validator.Errors
+	if errors.As(err, &errs) {
+		return invalidFields(errs)
+	}
+
+	return err
+}
+
+// Читает тело запроса в dst. Тело больше MaxBodySize, неизвестные поля и данные после JSON-объекта
+// отклоняются. Пустое тело допускается, только если optional.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}, optional bool) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodySize))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		if err == io.EOF && optional {
+			return nil
+		}
+
+		return bodyError(err)
+	}
+
+	if err := decoder.Decode(&struct{}{}); err != io.EOF {
+		if err != nil && isBodyTooLarge(err) {
+			return errBodyTooLarge
+		}
+
+		return errInvalidBody
+	}
+
+	return nil
+}
+
+// Возвращает ошибку ответа для ошибки чтения тела запроса: 413 для слишком большого тела, 400 с полем
+// для неизвестного поля, 422 с полем для значения неверного типа и 400 для некорректного JSON.
+func bodyError(err error) error {
+	if isBodyTooLarge(err) {
+		return errBodyTooLarge
+	}
+
+	if field := strings.TrimPrefix(err.Error(), "json: unknown field "); field != err.Error() {
+		field = strings.Trim(field, `"`)
+
+		return &Error{
+			Kind:    ErrBadRequest,
+			Code:    CodeUnknownField,
+			Message: UnknownField + " " + field,
+			Details: map[string]interface{}{
+				"field": field,
+			},
+		}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return invalidFields(validator.Errors{{Field: typeErr.Field, Message: typeMessage(typeErr.Type)}})
+	}
+
+	return errInvalidBody
+}
+
+// Возвращает описание ошибки для значения JSON, которое не подходит к типу поля.
+func typeMessage(value reflect.Type) string {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return MustBeInteger
+	case reflect.Float32, reflect.Float64:
+		return MustBeDecimal
+	case reflect.String:
+		return "must be a string"
+	case reflect.Bool:
+		return "must be a boolean"
+	case reflect.Slice, reflect.Array:
+		return "must be an array"
+	default:
+		return "must be an object"
+	}
+}
+
+// Возвращает true, если чтение тела прервано ограничением http.MaxBytesReader.
+func isBodyTooLarge(err error) bool {
+	return err != nil && err.Error() == "http: request body too large"
+}
@@ -0,0 +1,34 @@
+package webhook
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventIDHeader   = "X-Webhook-Event-ID"
+	EventTypeHeader = "X-Webhook-Event"
+)
+
+const InternalServerError = "internal server error"
+
+const (
+	InvalidBodyData  = "invalid body data"
+	InvalidBodyURL   = "invalid body url"
+	InvalidQueryID   = "invalid query id"
+	EndpointNotFound = "webhook endpoint not found"
+	DeliveryNotFound = "webhook delivery not found"
+)
+
+// Машиночитаемые коды ошибок в поле «code» ответа.
+const (
+	CodeInternalError    = "internal_error"
+	CodeInvalidBody      = "invalid_body"
+	CodeInvalidID        = "invalid_id"
+	CodeInvalidField     = "invalid_field"
+	CodeEndpointNotFound = "endpoint_not_found"
+	CodeDeliveryNotFound = "delivery_not_found"
+)
@@ -0,0 +1,42 @@
+package webhook
+
+import (
+	"context"
+	"time"
+)
+
+// WebhookRepository — это интерфейс хранилища эндпоинтов и журнала доставок.
+// @property CreateEndpoint - Регистрирует эндпоинт.
+// @property GetEndpoints - Возвращает все эндпоинты.
+// @property DeleteEndpoint - Удаляет эндпоинт вместе с его доставками.
+// @property CreateDeliveries - Создает доставку события на каждый включенный эндпоинт, у которого еще
+// нет доставки этого события.
+// @property GetDeliveries - Возвращает журнал доставок эндпоинта.
+// @property ClaimDueDeliveries - Занимает доставки, время попытки которых наступило, до until и
+// возвращает их.
+// @property UpdateDelivery - Сохраняет результат попытки доставки.
+// @property RedeliverDelivery - Ставит доставку в очередь на немедленную повторную отправку.
+type WebhookRepository interface {
+	CreateEndpoint(ctx context.Context, input EndpointInput) (endpoint, error)
+	GetEndpoints(ctx context.Context) ([]endpoint, error)
+	DeleteEndpoint(ctx context.Context, EndpointID int64) (int64, error)
+	CreateDeliveries(ctx context.Context, input DeliveryInput) (int64, error)
+	GetDeliveries(ctx context.Context, EndpointID int64) ([]delivery, error)
+	ClaimDueDeliveries(ctx context.Context, limit int, until time.Time) ([]delivery, error)
+	UpdateDelivery(ctx context.Context, input delivery) error
+	RedeliverDelivery(ctx context.Context, DeliveryID int64) (int64, error)
+}
+
+// WebhookUseCase — это интерфейс с методами управления эндпоинтами и доставками.
+// @property CreateEndpoint - Регистрирует эндпоинт и генерирует секрет, если он не передан.
+// @property GetEndpoints - Возвращает все эндпоинты.
+// @property {error} DeleteEndpoint - Удаляет эндпоинт.
+// @property GetDeliveries - Возвращает журнал доставок эндпоинта.
+// @property {error} Redeliver - Повторно отправляет доставку.
+type WebhookUseCase interface {
+	CreateEndpoint(ctx context.Context, input EndpointInput) (endpoint, error)
+	GetEndpoints(ctx context.Context) ([]endpoint, error)
+	DeleteEndpoint(ctx context.Context, EndpointID int64) error
+	GetDeliveries(ctx context.Context, EndpointID int64) ([]delivery, error)
+	Redeliver(ctx context.Context, DeliveryID int64) error
+}
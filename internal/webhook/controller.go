@@ -0,0 +1,213 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+	"github.com/onlycodergod/payment-api-emulator/internal/payment"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/apierror"
+	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
+)
+
+// > Тип контроллера — это структура с интерфейсом WebhookUseCase и интерфейсом регистратора.
+// @property {WebhookUseCase} UseCase - Это интерфейс управления эндпоинтами и доставками.
+// @property logger - Это регистратор, который будет использоваться для регистрации ошибок.
+type controller struct {
+	UseCase WebhookUseCase
+	logger  loggin.ILogger
+}
+
+// > Эта функция создает новый экземпляр структуры контроллера и возвращает указатель на него
+func NewWebhookController(l loggin.ILogger, u WebhookUseCase) *controller {
+	return &controller{
+		logger:  l,
+		UseCase: u,
+	}
+}
+
+// Это константа, определяющая маршрут.
+const (
+	CreateEndpoint            = "/webhooks"
+	GetEndpoints              = "/webhooks"
+	DeleteEndpointByID        = "/webhooks/{id}"
+	GetDeliveriesByEndpointID = "/webhooks/{id}/deliveries"
+	RedeliverByDeliveryID     = "/webhooks/deliveries/{id}/redeliver"
+)
+
+// Эта функция регистрирует обработчики маршрутов webhook.
+func (c *controller) Register(router *mux.Router) *mux.Router {
+	router.HandleFunc(CreateEndpoint, c.CreateEndpoint).Methods(http.MethodPost).Name("CreateEndpoint")
+	router.HandleFunc(GetEndpoints, c.GetEndpoints).Methods(http.MethodGet).Name("GetEndpoints")
+	router.HandleFunc(DeleteEndpointByID, c.DeleteEndpoint).Methods(http.MethodDelete).Name("DeleteEndpointByID")
+	router.HandleFunc(GetDeliveriesByEndpointID, c.GetDeliveries).Methods(http.MethodGet).Name("GetDeliveriesByEndpointID")
+	router.HandleFunc(RedeliverByDeliveryID, c.Redeliver).Methods(http.MethodPost).Name("RedeliverByDeliveryID")
+	return router
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/webhooks` методом `POST`.
+func (c *controller) CreateEndpoint(w http.ResponseWriter, r *http.Request) {
+	var input EndpointInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.Body{
+			Code:    CodeInvalidBody,
+			Message: InvalidBodyData,
+		})
+		return
+	}
+
+	if ok := isURL(input.URL); !ok {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.Body{
+			Code:    CodeInvalidField,
+			Message: InvalidBodyURL,
+			Details: map[string]interface{}{
+				"field": "url",
+			},
+		})
+		return
+	}
+
+	data, err := c.UseCase.CreateEndpoint(
+		r.Context(),
+		input,
+	)
+	if err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(data)
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/webhooks` методом `GET`.
+func (c *controller) GetEndpoints(w http.ResponseWriter, r *http.Request) {
+	data, err := c.UseCase.GetEndpoints(r.Context())
+	if err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(
+		EndpointsData{
+			Data: data,
+		},
+	)
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/webhooks/{id}` методом `DELETE`.
+func (c *controller) DeleteEndpoint(w http.ResponseWriter, r *http.Request) {
+	EndpointID, err := payment.GetQueryId(r)
+	if err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.Body{
+			Code:    CodeInvalidID,
+			Message: InvalidQueryID,
+		})
+		return
+	}
+
+	err = c.UseCase.DeleteEndpoint(
+		r.Context(),
+		EndpointID,
+	)
+	if err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/webhooks/{id}/deliveries` методом `GET`.
+func (c *controller) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	EndpointID, err := payment.GetQueryId(r)
+	if err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.Body{
+			Code:    CodeInvalidID,
+			Message: InvalidQueryID,
+		})
+		return
+	}
+
+	data, err := c.UseCase.GetDeliveries(
+		r.Context(),
+		EndpointID,
+	)
+	if err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(
+		DeliveriesData{
+			Data: data,
+		},
+	)
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/webhooks/deliveries/{id}/redeliver` методом `POST`.
+func (c *controller) Redeliver(w http.ResponseWriter, r *http.Request) {
+	DeliveryID, err := payment.GetQueryId(r)
+	if err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.Body{
+			Code:    CodeInvalidID,
+			Message: InvalidQueryID,
+		})
+		return
+	}
+
+	err = c.UseCase.Redeliver(
+		r.Context(),
+		DeliveryID,
+	)
+	if err != nil {
+		c.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Отвечает клиенту на ошибку варианта использования.
+func (c *controller) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrEndpointNotFound):
+		apierror.Write(w, r, http.StatusNotFound, apierror.Body{
+			Code:    CodeEndpointNotFound,
+			Message: EndpointNotFound,
+		})
+	case errors.Is(err, ErrDeliveryNotFound):
+		apierror.Write(w, r, http.StatusNotFound, apierror.Body{
+			Code:    CodeDeliveryNotFound,
+			Message: DeliveryNotFound,
+		})
+	default:
+		c.logger.WithContext(r.Context()).Error(err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Body{
+			Code:    CodeInternalError,
+			Message: InternalServerError,
+		})
+	}
+}
+
+// Возвращает true, если строка является абсолютным http или https адресом.
+func isURL(address string) bool {
+	u, err := url.ParseRequestURI(address)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,192 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/onlycodergod/payment-api-emulator/internal/outbox"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/apierror"
+	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет проверку адреса эндпоинта и генерацию секрета при регистрации.
+func TestControllerCreateEndpoint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		body  string
+		code  int
+		error string
+	}{
+		{
+			name: "Create endpoint",
+			body: `{"url": "https://merchant.example/hook"}`,
+			code: http.StatusCreated,
+		},
+		{
+			name:  "Invalid body",
+			body:  `{`,
+			code:  http.StatusBadRequest,
+			error: CodeInvalidBody,
+		},
+		{
+			name:  "Empty url",
+			body:  `{"url": ""}`,
+			code:  http.StatusBadRequest,
+			error: CodeInvalidField,
+		},
+		{
+			name:  "Relative url",
+			body:  `{"url": "/hook"}`,
+			code:  http.StatusBadRequest,
+			error: CodeInvalidField,
+		},
+		{
+			name:  "Unsupported scheme",
+			body:  `{"url": "ftp://merchant.example/hook"}`,
+			code:  http.StatusBadRequest,
+			error: CodeInvalidField,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			usc := NewWebhookUseCase(NewMemoryWebhookRepository())
+			router := NewWebhookController(loggin.NewLogger(false), usc).Register(mux.NewRouter())
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, CreateEndpoint, strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.code, w.Code)
+
+			if tt.error != "" {
+				var got apierror.Body
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+				assert.Equal(t, tt.error, got.Code)
+				return
+			}
+
+			var got endpoint
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+			assert.Equal(t, "https://merchant.example/hook", got.URL)
+			assert.True(t, strings.HasPrefix(got.Secret, "whsec_"))
+			assert.True(t, got.Enabled)
+		})
+	}
+}
+
+// Он проверяет ответы на неверный ID, неизвестные эндпоинты и доставки, журнал доставок и ручную
+// повторную отправку.
+func TestControllerDeliveries(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	repo := NewMemoryWebhookRepository()
+	usc := NewWebhookUseCase(repo)
+	router := NewWebhookController(loggin.NewLogger(false), usc).Register(mux.NewRouter())
+
+	created, err := usc.CreateEndpoint(ctx, EndpointInput{URL: "https://merchant.example/hook"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, usc.Publish(ctx, []outbox.Event{{ID: 1, PaymentID: 1, NewStatus: "new"}}))
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		code   int
+		error  string
+	}{
+		{
+			name:   "Deliveries",
+			method: http.MethodGet,
+			target: "/webhooks/1/deliveries",
+			code:   http.StatusOK,
+		},
+		{
+			name:   "Deliveries with invalid id",
+			method: http.MethodGet,
+			target: "/webhooks/abc/deliveries",
+			code:   http.StatusBadRequest,
+			error:  CodeInvalidID,
+		},
+		{
+			name:   "Redeliver",
+			method: http.MethodPost,
+			target: "/webhooks/deliveries/1/redeliver",
+			code:   http.StatusAccepted,
+		},
+		{
+			name:   "Redeliver unknown delivery",
+			method: http.MethodPost,
+			target: "/webhooks/deliveries/100/redeliver",
+			code:   http.StatusNotFound,
+			error:  CodeDeliveryNotFound,
+		},
+		{
+			name:   "Redeliver with invalid id",
+			method: http.MethodPost,
+			target: "/webhooks/deliveries/abc/redeliver",
+			code:   http.StatusBadRequest,
+			error:  CodeInvalidID,
+		},
+		{
+			name:   "Delete unknown endpoint",
+			method: http.MethodDelete,
+			target: "/webhooks/100",
+			code:   http.StatusNotFound,
+			error:  CodeEndpointNotFound,
+		},
+		{
+			name:   "Delete with invalid id",
+			method: http.MethodDelete,
+			target: "/webhooks/abc",
+			code:   http.StatusBadRequest,
+			error:  CodeInvalidID,
+		},
+		{
+			name:   "Delete endpoint",
+			method: http.MethodDelete,
+			target: "/webhooks/1",
+			code:   http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+
+		assert.Equal(t, tt.code, w.Code, tt.name)
+
+		if tt.error != "" {
+			var got apierror.Body
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&got), tt.name)
+			assert.Equal(t, tt.error, got.Code, tt.name)
+		}
+
+		if tt.name == "Deliveries" {
+			var got DeliveriesData
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+			assert.Len(t, got.Data, 1)
+			assert.Equal(t, created.ID, got.Data[0].EndpointID)
+			assert.Equal(t, "evt_1", got.Data[0].EventID)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, GetEndpoints, nil))
+
+	var got EndpointsData
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Empty(t, got.Data)
+}
@@ -0,0 +1,182 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
+)
+
+// DispatcherOptions — это структура с настройками фоновой отправки событий.
+// @property {time.Duration} Interval - Период опроса очереди доставок.
+// @property {time.Duration} Timeout - Тайм-аут одного запроса к эндпоинту.
+// @property {time.Duration} BaseBackoff - Задержка перед второй попыткой, каждая следующая вдвое больше.
+// @property {time.Duration} MaxBackoff - Максимальная задержка между попытками.
+// @property {int} MaxAttempts - Количество попыток, после которого доставка считается неудачной.
+// @property {int} BatchSize - Сколько доставок отправляется за один опрос.
+type DispatcherOptions struct {
+	Interval    time.Duration
+	Timeout     time.Duration
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	MaxAttempts int
+	BatchSize   int
+}
+
+// Dispatcher — это фоновый процесс, который отправляет подписанные события на эндпоинты и повторяет
+// неудачные отправки с экспоненциальной задержкой.
+// @property {WebhookRepository} repo - Это хранилище журнала доставок.
+// @property client - HTTP-клиент для запросов к эндпоинтам.
+// @property logger - Это регистратор ошибок отправки.
+// @property {DispatcherOptions} options - Это настройки отправки.
+// @property now - Источник текущего времени.
+type Dispatcher struct {
+	repo    WebhookRepository
+	client  *http.Client
+	logger  loggin.ILogger
+	options DispatcherOptions
+	now     func() time.Time
+}
+
+// > Эта функция создает новый экземпляр структуры Dispatcher и возвращает указатель на нее.
+// Возвращает ошибку, если период опроса или размер пачки не больше нуля.
+func NewDispatcher(repo WebhookRepository, logger loggin.ILogger, options DispatcherOptions) (*Dispatcher, error) {
+	if options.Interval <= 0 {
+		return nil, fmt.Errorf("webhook-NewDispatcher, interval %s must be positive", options.Interval)
+	}
+
+	if options.BatchSize <= 0 {
+		return nil, fmt.Errorf("webhook-NewDispatcher, batch size %d must be positive", options.BatchSize)
+	}
+
+	return &Dispatcher{
+		repo: repo,
+		client: &http.Client{
+			Timeout: options.Timeout,
+		},
+		logger:  logger,
+		options: options,
+		now:     time.Now,
+	}, nil
+}
+
+// Запускает опрос очереди доставок и блокируется до отмены контекста.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.Dispatch(ctx)
+		}
+	}
+}
+
+// Занимает доставки, время попытки которых наступило, отправляет их и сохраняет результаты.
+func (d *Dispatcher) Dispatch(ctx context.Context) {
+	due, err := d.repo.ClaimDueDeliveries(
+		ctx,
+		d.options.BatchSize,
+		d.now().Add(d.lease()),
+	)
+	if err != nil {
+		d.logger.Error(err)
+		return
+	}
+
+	for _, value := range due {
+		result := d.deliver(ctx, value)
+
+		if err := d.repo.UpdateDelivery(ctx, result); err != nil {
+			d.logger.Error(err)
+		}
+	}
+}
+
+// Делает одну попытку доставки и возвращает доставку с ее результатом.
+func (d *Dispatcher) deliver(ctx context.Context, value delivery) delivery {
+	value.Attempts++
+	value.ResponseCode = 0
+	value.LastError = ""
+
+	code, err := d.post(ctx, value)
+	value.ResponseCode = code
+
+	if err == nil {
+		value.Status = DeliverySucceeded
+		return value
+	}
+
+	value.LastError = err.Error()
+
+	if value.Attempts >= d.options.MaxAttempts {
+		value.Status = DeliveryFailed
+		return value
+	}
+
+	value.Status = DeliveryPending
+	value.NextAttemptAt = d.now().Add(d.backoff(value.Attempts))
+
+	return value
+}
+
+// Отправляет подписанное событие на эндпоинт. Ответ с кодом не из диапазона 2xx считается ошибкой.
+func (d *Dispatcher) post(ctx context.Context, value delivery) (int, error) {
+	timestamp := d.now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, value.URL, bytes.NewReader(value.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIDHeader, value.EventID)
+	req.Header.Set(EventTypeHeader, value.EventType)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(value.Secret, timestamp, value.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Возвращает время, на которое занимается пачка доставок: доставки отправляются по очереди, и каждая
+// может ждать ответа до Timeout. Пока оно не истекло, пачку не отправит другой диспетчер.
+func (d *Dispatcher) lease() time.Duration {
+	return d.options.Timeout*time.Duration(d.options.BatchSize) + d.options.Interval
+}
+
+// Возвращает задержку перед следующей попыткой: BaseBackoff * 2^(attempts-1), но не больше MaxBackoff.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.options.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.options.MaxBackoff {
+			return d.options.MaxBackoff
+		}
+	}
+
+	if delay > d.options.MaxBackoff {
+		return d.options.MaxBackoff
+	}
+
+	return delay
+}
@@ -0,0 +1,142 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/onlycodergod/payment-api-emulator/internal/outbox"
+	"github.com/onlycodergod/payment-api-emulator/internal/payment"
+	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
+	"github.com/stretchr/testify/assert"
+)
+
+// Он отправляет событие на тестовый эндпоинт, который сначала отвечает ошибкой, и проверяет
+// подпись, повторную попытку и журнал доставок.
+func TestDispatcherDeliver(t *testing.T) {
+	t.Parallel()
+
+	var calls int32
+	var verified int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+
+		if Verify("secret", timestamp, body, r.Header.Get(SignatureHeader)) {
+			atomic.AddInt32(&verified, 1)
+		}
+
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	ctx := context.TODO()
+	logger := loggin.NewLogger(false)
+	repo := NewMemoryWebhookRepository()
+
+	now := time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+
+	usecase := NewWebhookUseCase(repo)
+	created, err := usecase.CreateEndpoint(ctx, EndpointInput{URL: srv.URL, Secret: "secret"})
+	assert.NoError(t, err)
+
+	// Повторно опубликованное событие outbox не создает вторую доставку.
+	events := []outbox.Event{
+		{ID: 1, PaymentID: 1, OldStatus: payment.StatusNew, NewStatus: payment.StatusSuccess, CreatedAt: now},
+	}
+	assert.NoError(t, usecase.Publish(ctx, events))
+	assert.NoError(t, usecase.Publish(ctx, events))
+
+	dispatcher, err := NewDispatcher(repo, logger, DispatcherOptions{
+		Interval:    time.Second,
+		Timeout:     time.Second,
+		BaseBackoff: 10 * time.Second,
+		MaxBackoff:  time.Minute,
+		MaxAttempts: 3,
+		BatchSize:   10,
+	})
+	assert.NoError(t, err)
+	dispatcher.now = repo.now
+
+	// Первая попытка неудачна, следующая назначена через BaseBackoff.
+	dispatcher.Dispatch(ctx)
+
+	data, err := usecase.GetDeliveries(ctx, created.ID)
+	assert.NoError(t, err)
+	assert.Len(t, data, 1)
+	assert.Equal(t, "evt_1", data[0].EventID)
+	assert.Equal(t, payment.EventPaymentStatusChanged, data[0].EventType)
+	assert.Equal(t, DeliveryPending, data[0].Status)
+	assert.Equal(t, 1, data[0].Attempts)
+	assert.Equal(t, http.StatusInternalServerError, data[0].ResponseCode)
+	assert.Equal(t, now.Add(10*time.Second), data[0].NextAttemptAt)
+
+	// До наступления времени попытки доставка не отправляется.
+	dispatcher.Dispatch(ctx)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	now = now.Add(10 * time.Second)
+	dispatcher.Dispatch(ctx)
+
+	data, err = usecase.GetDeliveries(ctx, created.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, DeliverySucceeded, data[0].Status)
+	assert.Equal(t, 2, data[0].Attempts)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&verified))
+
+	// Ручная повторная отправка.
+	assert.NoError(t, usecase.Redeliver(ctx, data[0].ID))
+	dispatcher.Dispatch(ctx)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	assert.ErrorIs(t, usecase.Redeliver(ctx, 100), ErrDeliveryNotFound)
+}
+
+// Он проверяет экспоненциальную задержку между попытками.
+func TestDispatcherBackoff(t *testing.T) {
+	t.Parallel()
+
+	dispatcher, err := NewDispatcher(nil, nil, DispatcherOptions{
+		Interval:    time.Second,
+		BaseBackoff: time.Second,
+		MaxBackoff:  10 * time.Second,
+		BatchSize:   10,
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, time.Second, dispatcher.backoff(1))
+	assert.Equal(t, 2*time.Second, dispatcher.backoff(2))
+	assert.Equal(t, 8*time.Second, dispatcher.backoff(4))
+	assert.Equal(t, 10*time.Second, dispatcher.backoff(5))
+	assert.Equal(t, 10*time.Second, dispatcher.backoff(50))
+}
+
+// Он проверяет, что диспетчер не создается с нулевым периодом опроса или размером пачки.
+func TestNewDispatcherOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options DispatcherOptions
+	}{
+		{name: "Zero interval", options: DispatcherOptions{BatchSize: 100}},
+		{name: "Zero batch size", options: DispatcherOptions{Interval: time.Second}},
+	}
+
+	for _, tt := range tests {
+		_, err := NewDispatcher(nil, nil, tt.options)
+		assert.Error(t, err, tt.name)
+	}
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/onlycodergod/payment-api-emulator/internal/payment"
+)
+
+// Это структура, содержащая поля, используемые для представления webhook-эндпоинта мерчанта.
+// @property {int64} ID - Уникальный идентификатор эндпоинта.
+// @property {string} URL - Адрес, на который отправляются события.
+// @property {string} Secret - Ключ подписи HMAC-SHA256. Возвращается только при создании.
+// @property {bool} Enabled - Получает ли эндпоинт новые события.
+// @property {string} CreatedAt - Дата и время создания эндпоинта.
+type endpoint struct {
+	ID        int64  `json:"id" db:"id"`
+	URL       string `json:"url" db:"url"`
+	Secret    string `json:"secret,omitempty" db:"secret"`
+	Enabled   bool   `json:"enabled" db:"enabled"`
+	CreatedAt string `json:"created_at" db:"created_at"`
+}
+
+// EndpointInput — это структура с данными для регистрации эндпоинта.
+// @property {string} URL - Адрес, на который отправляются события.
+// @property {string} Secret - Ключ подписи. Если не указан, генерируется автоматически.
+type EndpointInput struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"`
+}
+
+// EndpointsData — это структура, содержащая фрагмент структур эндпоинтов.
+// @property {[]endpoint} Data - Это массив эндпоинтов.
+type EndpointsData struct {
+	Data []endpoint `json:"data"`
+}
+
+// Это структура, содержащая поля, используемые для представления доставки события на эндпоинт.
+// @property {int64} ID - Уникальный идентификатор доставки.
+// @property {int64} EndpointID - ID эндпоинта.
+// @property {string} EventID - ID события.
+// @property {string} EventType - Тип события.
+// @property {json.RawMessage} Payload - Тело события, которое подписывается и отправляется.
+// @property {string} Status - Статус доставки: «pending», «succeeded» или «failed».
+// @property {int} Attempts - Количество сделанных попыток.
+// @property {time.Time} NextAttemptAt - Время следующей попытки.
+// @property {string} LastError - Ошибка последней попытки.
+// @property {int} ResponseCode - HTTP-код ответа последней попытки.
+// @property {string} CreatedAt - Дата и время создания доставки.
+// @property {string} UpdatedAt - Дата и время последнего обновления доставки.
+// @property {string} URL - Адрес эндпоинта, заполняется только для диспетчера.
+// @property {string} Secret - Ключ подписи эндпоинта, заполняется только для диспетчера.
+type delivery struct {
+	ID            int64           `json:"id" db:"id"`
+	EndpointID    int64           `json:"endpoint_id" db:"endpoint_id"`
+	EventID       string          `json:"event_id" db:"event_id"`
+	EventType     string          `json:"event_type" db:"event_type"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	Status        string          `json:"status" db:"status"`
+	Attempts      int             `json:"attempts" db:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty" db:"last_error"`
+	ResponseCode  int             `json:"response_code,omitempty" db:"response_code"`
+	CreatedAt     string          `json:"created_at" db:"created_at"`
+	UpdatedAt     string          `json:"updated_at" db:"updated_at"`
+	URL           string          `json:"-" db:"url"`
+	Secret        string          `json:"-" db:"secret"`
+}
+
+// DeliveriesData — это структура, содержащая фрагмент структур доставок.
+// @property {[]delivery} Data - Это массив доставок.
+type DeliveriesData struct {
+	Data []delivery `json:"data"`
+}
+
+// DeliveryInput — это событие, которое нужно доставить на все включенные эндпоинты.
+// @property {string} EventID - ID события.
+// @property {string} EventType - Тип события.
+// @property {[]byte} Payload - Тело события.
+type DeliveryInput struct {
+	EventID   string
+	EventType string
+	Payload   []byte
+}
+
+// Это тело события, которое получает мерчант.
+// @property {string} ID - ID события.
+// @property {string} Type - Тип события.
+// @property {time.Time} CreatedAt - Момент изменения платежа.
+// @property {payment.PaymentEvent} Data - Изменение платежа.
+type event struct {
+	ID        string               `json:"id"`
+	Type      string               `json:"type"`
+	CreatedAt time.Time            `json:"created_at"`
+	Data      payment.PaymentEvent `json:"data"`
+}
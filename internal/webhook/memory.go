@@ -0,0 +1,233 @@
+package webhook
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryRepository — это потокобезопасная реализация WebhookRepository в памяти.
+// @property mu - Мьютекс, который защищает все поля репозитория.
+// @property {int64} lastEndpointID - Последний выданный идентификатор эндпоинта.
+// @property {int64} lastDeliveryID - Последний выданный идентификатор доставки.
+// @property endpoints - Эндпоинты по идентификатору.
+// @property deliveries - Доставки по идентификатору.
+// @property now - Источник текущего времени.
+type memoryRepository struct {
+	mu             sync.RWMutex
+	lastEndpointID int64
+	lastDeliveryID int64
+	endpoints      map[int64]endpoint
+	deliveries     map[int64]delivery
+	now            func() time.Time
+}
+
+// Он создает новый пустой репозиторий в памяти и возвращает указатель на него.
+func NewMemoryWebhookRepository() *memoryRepository {
+	return &memoryRepository{
+		endpoints:  make(map[int64]endpoint),
+		deliveries: make(map[int64]delivery),
+		now:        time.Now,
+	}
+}
+
+// Возвращает текущее время в формате, в котором Postgres отдает TIMESTAMP WITH TIME ZONE.
+func (r *memoryRepository) timestamp() string {
+	return r.now().UTC().Format(time.RFC3339Nano)
+}
+
+// Регистрация нового эндпоинта.
+func (r *memoryRepository) CreateEndpoint(ctx context.Context, input EndpointInput) (endpoint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastEndpointID++
+
+	output := endpoint{
+		ID:        r.lastEndpointID,
+		URL:       input.URL,
+		Secret:    input.Secret,
+		Enabled:   true,
+		CreatedAt: r.timestamp(),
+	}
+	r.endpoints[output.ID] = output
+
+	return output, nil
+}
+
+// Функция, которая возвращает срез всех эндпоинтов без секретов.
+func (r *memoryRepository) GetEndpoints(ctx context.Context) ([]endpoint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	output := make([]endpoint, 0, len(r.endpoints))
+	for _, value := range r.endpoints {
+		value.Secret = ""
+		output = append(output, value)
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].ID < output[j].ID
+	})
+
+	return output, nil
+}
+
+// Удаление эндпоинта вместе с его доставками.
+func (r *memoryRepository) DeleteEndpoint(ctx context.Context, EndpointID int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.endpoints[EndpointID]; !ok {
+		return 0, nil
+	}
+
+	delete(r.endpoints, EndpointID)
+	for id, value := range r.deliveries {
+		if value.EndpointID == EndpointID {
+			delete(r.deliveries, id)
+		}
+	}
+
+	return 1, nil
+}
+
+// Создание доставки события на каждый включенный эндпоинт, у которого ее еще нет.
+func (r *memoryRepository) CreateDeliveries(ctx context.Context, input DeliveryInput) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+
+	delivered := make(map[int64]struct{})
+	for _, value := range r.deliveries {
+		if value.EventID == input.EventID {
+			delivered[value.EndpointID] = struct{}{}
+		}
+	}
+
+	var count int64
+	for _, value := range r.endpoints {
+		if _, ok := delivered[value.ID]; !value.Enabled || ok {
+			continue
+		}
+
+		r.lastDeliveryID++
+		count++
+
+		r.deliveries[r.lastDeliveryID] = delivery{
+			ID:            r.lastDeliveryID,
+			EndpointID:    value.ID,
+			EventID:       input.EventID,
+			EventType:     input.EventType,
+			Payload:       append([]byte(nil), input.Payload...),
+			Status:        DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     r.timestamp(),
+			UpdatedAt:     r.timestamp(),
+		}
+	}
+
+	return count, nil
+}
+
+// Функция, которая возвращает журнал доставок эндпоинта.
+func (r *memoryRepository) GetDeliveries(ctx context.Context, EndpointID int64) ([]delivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	output := make([]delivery, 0)
+	for _, value := range r.deliveries {
+		if value.EndpointID == EndpointID {
+			output = append(output, value)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].ID < output[j].ID
+	})
+
+	return output, nil
+}
+
+// Функция, которая занимает ожидающие доставки, время попытки которых наступило, переносит время их
+// попытки на until и возвращает их вместе с адресом и секретом эндпоинта.
+func (r *memoryRepository) ClaimDueDeliveries(ctx context.Context, limit int, until time.Time) ([]delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+
+	output := make([]delivery, 0)
+	for _, value := range r.deliveries {
+		if value.Status != DeliveryPending || value.NextAttemptAt.After(now) {
+			continue
+		}
+
+		value.URL = r.endpoints[value.EndpointID].URL
+		value.Secret = r.endpoints[value.EndpointID].Secret
+		output = append(output, value)
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		if output[i].NextAttemptAt.Equal(output[j].NextAttemptAt) {
+			return output[i].ID < output[j].ID
+		}
+
+		return output[i].NextAttemptAt.Before(output[j].NextAttemptAt)
+	})
+
+	if len(output) > limit {
+		output = output[:limit]
+	}
+
+	for i := range output {
+		output[i].NextAttemptAt = until
+
+		value := r.deliveries[output[i].ID]
+		value.NextAttemptAt = until
+		r.deliveries[value.ID] = value
+	}
+
+	return output, nil
+}
+
+// Сохранение результата попытки доставки.
+func (r *memoryRepository) UpdateDelivery(ctx context.Context, input delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	value, ok := r.deliveries[input.ID]
+	if !ok {
+		return nil
+	}
+
+	value.Status = input.Status
+	value.Attempts = input.Attempts
+	value.NextAttemptAt = input.NextAttemptAt
+	value.LastError = input.LastError
+	value.ResponseCode = input.ResponseCode
+	value.UpdatedAt = r.timestamp()
+	r.deliveries[input.ID] = value
+
+	return nil
+}
+
+// Постановка доставки в очередь на немедленную повторную отправку.
+func (r *memoryRepository) RedeliverDelivery(ctx context.Context, DeliveryID int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	value, ok := r.deliveries[DeliveryID]
+	if !ok {
+		return 0, nil
+	}
+
+	value.Status = DeliveryPending
+	value.NextAttemptAt = r.now()
+	value.UpdatedAt = r.timestamp()
+	r.deliveries[DeliveryID] = value
+
+	return 1, nil
+}
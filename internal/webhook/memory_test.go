@@ -0,0 +1,187 @@
+package webhook
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет, что репозиторий в памяти создает доставку на каждый включенный эндпоинт один раз и
+// удаляет доставки вместе с эндпоинтом.
+func TestMemoryCreateDeliveries(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	r := NewMemoryWebhookRepository()
+
+	first, err := r.CreateEndpoint(ctx, EndpointInput{URL: "https://merchant.example/hook", Secret: "secret"})
+	assert.NoError(t, err)
+
+	second, err := r.CreateEndpoint(ctx, EndpointInput{URL: "https://other.example/hook", Secret: "other"})
+	assert.NoError(t, err)
+
+	disabled, err := r.CreateEndpoint(ctx, EndpointInput{URL: "https://disabled.example/hook", Secret: "disabled"})
+	assert.NoError(t, err)
+
+	value := r.endpoints[disabled.ID]
+	value.Enabled = false
+	r.endpoints[disabled.ID] = value
+
+	tests := []struct {
+		name   string
+		input  DeliveryInput
+		expect int64
+	}{
+		{
+			name:   "Fan out to enabled endpoints",
+			input:  DeliveryInput{EventID: "evt_1", EventType: "payment.created", Payload: []byte(`{}`)},
+			expect: 2,
+		},
+		{
+			name:   "Republished event",
+			input:  DeliveryInput{EventID: "evt_1", EventType: "payment.created", Payload: []byte(`{}`)},
+			expect: 0,
+		},
+		{
+			name:   "Next event",
+			input:  DeliveryInput{EventID: "evt_2", EventType: "payment.status_changed", Payload: []byte(`{}`)},
+			expect: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := r.CreateDeliveries(ctx, tt.input)
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.expect, got, tt.name)
+	}
+
+	data, err := r.GetDeliveries(ctx, first.ID)
+	assert.NoError(t, err)
+	assert.Len(t, data, 2)
+
+	data, err = r.GetDeliveries(ctx, disabled.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+
+	rows, err := r.DeleteEndpoint(ctx, second.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rows)
+
+	data, err = r.GetDeliveries(ctx, second.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+
+	rows, err = r.DeleteEndpoint(ctx, second.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), rows)
+
+	endpoints, err := r.GetEndpoints(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, endpoints, 2)
+	assert.Empty(t, endpoints[0].Secret)
+}
+
+// Он проверяет, что занятая доставка не достается другому диспетчеру до истечения срока, а после
+// него отправляется повторно.
+func TestMemoryClaimDueDeliveries(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	now := time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	r := NewMemoryWebhookRepository()
+	r.now = func() time.Time { return now }
+
+	_, err := r.CreateEndpoint(ctx, EndpointInput{URL: "https://merchant.example/hook", Secret: "secret"})
+	assert.NoError(t, err)
+
+	for _, id := range []string{"evt_1", "evt_2", "evt_3"} {
+		_, err := r.CreateDeliveries(ctx, DeliveryInput{EventID: id, EventType: "payment.created", Payload: []byte(`{}`)})
+		assert.NoError(t, err)
+	}
+
+	until := now.Add(time.Minute)
+
+	var mu sync.Mutex
+	claimed := make(map[int64]int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			data, err := r.ClaimDueDeliveries(ctx, 2, until)
+			assert.NoError(t, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			for _, value := range data {
+				claimed[value.ID]++
+				assert.Equal(t, until, value.NextAttemptAt)
+				assert.Equal(t, "https://merchant.example/hook", value.URL)
+				assert.Equal(t, "secret", value.Secret)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, map[int64]int{1: 1, 2: 1, 3: 1}, claimed)
+
+	now = until
+	data, err := r.ClaimDueDeliveries(ctx, 10, now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Len(t, data, 3)
+	assert.Equal(t, int64(1), data[0].ID)
+}
+
+// Он проверяет сохранение результата попытки и постановку доставки в очередь вручную.
+func TestMemoryUpdateDelivery(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	now := time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	r := NewMemoryWebhookRepository()
+	r.now = func() time.Time { return now }
+
+	created, err := r.CreateEndpoint(ctx, EndpointInput{URL: "https://merchant.example/hook", Secret: "secret"})
+	assert.NoError(t, err)
+
+	_, err = r.CreateDeliveries(ctx, DeliveryInput{EventID: "evt_1", EventType: "payment.created", Payload: []byte(`{}`)})
+	assert.NoError(t, err)
+
+	data, err := r.ClaimDueDeliveries(ctx, 10, now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Len(t, data, 1)
+
+	value := data[0]
+	value.Status = DeliveryFailed
+	value.Attempts = 3
+	value.LastError = "unexpected response status 500"
+	value.ResponseCode = 500
+	assert.NoError(t, r.UpdateDelivery(ctx, value))
+
+	data, err = r.GetDeliveries(ctx, created.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, DeliveryFailed, data[0].Status)
+	assert.Equal(t, 3, data[0].Attempts)
+	assert.Equal(t, 500, data[0].ResponseCode)
+	assert.Empty(t, data[0].URL)
+
+	rows, err := r.RedeliverDelivery(ctx, value.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rows)
+
+	data, err = r.ClaimDueDeliveries(ctx, 10, now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Len(t, data, 1)
+
+	rows, err = r.RedeliverDelivery(ctx, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), rows)
+}
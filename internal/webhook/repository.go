@@ -0,0 +1,357 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	endpoints  = "webhook_endpoints"
+	deliveries = "webhook_deliveries"
+)
+
+// Репозиторий — это структура с полем db, которое является указателем на sql.DB.
+// @property db - Это указатель на объект sql.DB, который будет использоваться для подключения к базе
+// данных.
+type repository struct {
+	db *sql.DB
+}
+
+// Он создает новый экземпляр структуры репозитория и возвращает указатель на него.
+func NewWebhookRepository(db *sql.DB) *repository {
+	return &repository{
+		db: db,
+	}
+}
+
+// Регистрация нового эндпоинта.
+func (r *repository) CreateEndpoint(ctx context.Context, input EndpointInput) (endpoint, error) {
+	const format = `INSERT INTO %s (url, secret)
+						VALUES ($1, $2)
+					RETURNING id, enabled, created_at`
+
+	query := fmt.Sprintf(
+		format,
+		endpoints,
+	)
+
+	output := endpoint{
+		URL:    input.URL,
+		Secret: input.Secret,
+	}
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		input.URL,
+		input.Secret,
+	).Scan(&output.ID, &output.Enabled, &output.CreatedAt)
+	if err != nil {
+		return endpoint{}, fmt.Errorf("webhook-repository-CreateEndpoint, %s", err.Error())
+	}
+
+	return output, nil
+}
+
+// Функция, которая возвращает срез всех эндпоинтов без секретов.
+func (r *repository) GetEndpoints(ctx context.Context) ([]endpoint, error) {
+	const format = `SELECT id, url, enabled, created_at from %s
+					ORDER BY id`
+
+	query := fmt.Sprintf(
+		format,
+		endpoints,
+	)
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+	)
+	if err != nil {
+		return []endpoint{}, fmt.Errorf("webhook-repository-GetEndpoints, %s", err.Error())
+	}
+
+	defer rows.Close()
+
+	output := make([]endpoint, 0)
+	for rows.Next() {
+		value := endpoint{}
+
+		err := rows.Scan(
+			&value.ID,
+			&value.URL,
+			&value.Enabled,
+			&value.CreatedAt,
+		)
+		if err != nil {
+			return []endpoint{}, fmt.Errorf("webhook-repository-GetEndpoints, %s", err.Error())
+		}
+
+		output = append(output, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []endpoint{}, fmt.Errorf("webhook-repository-GetEndpoints, %s", err.Error())
+	}
+
+	return output, nil
+}
+
+// Удаление эндпоинта. Доставки удаляются каскадно.
+func (r *repository) DeleteEndpoint(ctx context.Context, EndpointID int64) (int64, error) {
+	const format = `DELETE FROM %s
+						WHERE id = $1`
+
+	query := fmt.Sprintf(
+		format,
+		endpoints,
+	)
+
+	rows, err := r.db.ExecContext(
+		ctx,
+		query,
+		EndpointID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("webhook-repository-DeleteEndpoint, %s", err.Error())
+	}
+
+	return rows.RowsAffected()
+}
+
+// Создание доставки события на каждый включенный эндпоинт одним запросом. Повторная доставка того же
+// события на эндпоинт не создается.
+func (r *repository) CreateDeliveries(ctx context.Context, input DeliveryInput) (int64, error) {
+	const format = `INSERT INTO %s (endpoint_id, event_id, event_type, payload)
+						SELECT id, $1, $2, $3 from %s
+						WHERE enabled
+					ON CONFLICT (endpoint_id, event_id) DO NOTHING`
+
+	query := fmt.Sprintf(
+		format,
+		deliveries,
+		endpoints,
+	)
+
+	rows, err := r.db.ExecContext(
+		ctx,
+		query,
+		input.EventID,
+		input.EventType,
+		string(input.Payload),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("webhook-repository-CreateDeliveries, %s", err.Error())
+	}
+
+	return rows.RowsAffected()
+}
+
+// Функция, которая возвращает журнал доставок эндпоинта.
+func (r *repository) GetDeliveries(ctx context.Context, EndpointID int64) ([]delivery, error) {
+	const format = `SELECT
+						id,
+						endpoint_id,
+						event_id,
+						event_type,
+						payload,
+						status,
+						attempts,
+						next_attempt_at,
+						last_error,
+						response_code,
+						created_at,
+						updated_at
+					from %s
+						WHERE endpoint_id = $1
+					ORDER BY id`
+
+	query := fmt.Sprintf(
+		format,
+		deliveries,
+	)
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		EndpointID,
+	)
+	if err != nil {
+		return []delivery{}, fmt.Errorf("webhook-repository-GetDeliveries, %s", err.Error())
+	}
+
+	defer rows.Close()
+
+	output := make([]delivery, 0)
+	for rows.Next() {
+		value := delivery{}
+		var payload string
+
+		err := rows.Scan(
+			&value.ID,
+			&value.EndpointID,
+			&value.EventID,
+			&value.EventType,
+			&payload,
+			&value.Status,
+			&value.Attempts,
+			&value.NextAttemptAt,
+			&value.LastError,
+			&value.ResponseCode,
+			&value.CreatedAt,
+			&value.UpdatedAt,
+		)
+		if err != nil {
+			return []delivery{}, fmt.Errorf("webhook-repository-GetDeliveries, %s", err.Error())
+		}
+
+		value.Payload = []byte(payload)
+		output = append(output, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []delivery{}, fmt.Errorf("webhook-repository-GetDeliveries, %s", err.Error())
+	}
+
+	return output, nil
+}
+
+// Функция, которая занимает ожидающие доставки, время попытки которых наступило, и возвращает их вместе
+// с адресом и секретом эндпоинта. Время попытки занятых доставок переносится на until, поэтому их не
+// получит другой диспетчер, а если диспетчер остановится, не сохранив результат, доставка будет
+// отправлена повторно после until. Строки, которые уже занимает другая транзакция, пропускаются.
+func (r *repository) ClaimDueDeliveries(ctx context.Context, limit int, until time.Time) ([]delivery, error) {
+	const format = `WITH due AS (
+						SELECT id from %s
+							WHERE status = $1
+							AND next_attempt_at <= NOW()
+						ORDER BY next_attempt_at, id
+						LIMIT $2
+						FOR UPDATE SKIP LOCKED
+					)
+					UPDATE %s d SET next_attempt_at = $3
+						FROM due, %s e
+						WHERE d.id = due.id
+						AND e.id = d.endpoint_id
+					RETURNING
+						d.id,
+						d.endpoint_id,
+						d.event_id,
+						d.event_type,
+						d.payload,
+						d.status,
+						d.attempts,
+						d.next_attempt_at,
+						e.url,
+						e.secret`
+
+	query := fmt.Sprintf(
+		format,
+		deliveries,
+		deliveries,
+		endpoints,
+	)
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		DeliveryPending,
+		limit,
+		until,
+	)
+	if err != nil {
+		return []delivery{}, fmt.Errorf("webhook-repository-ClaimDueDeliveries, %s", err.Error())
+	}
+
+	defer rows.Close()
+
+	output := make([]delivery, 0)
+	for rows.Next() {
+		value := delivery{}
+		var payload string
+
+		err := rows.Scan(
+			&value.ID,
+			&value.EndpointID,
+			&value.EventID,
+			&value.EventType,
+			&payload,
+			&value.Status,
+			&value.Attempts,
+			&value.NextAttemptAt,
+			&value.URL,
+			&value.Secret,
+		)
+		if err != nil {
+			return []delivery{}, fmt.Errorf("webhook-repository-ClaimDueDeliveries, %s", err.Error())
+		}
+
+		value.Payload = []byte(payload)
+		output = append(output, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []delivery{}, fmt.Errorf("webhook-repository-ClaimDueDeliveries, %s", err.Error())
+	}
+
+	return output, nil
+}
+
+// Сохранение результата попытки доставки.
+func (r *repository) UpdateDelivery(ctx context.Context, input delivery) error {
+	const format = `UPDATE %s SET
+						status = $1,
+						attempts = $2,
+						next_attempt_at = $3,
+						last_error = $4,
+						response_code = $5
+					WHERE id = $6`
+
+	query := fmt.Sprintf(
+		format,
+		deliveries,
+	)
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		input.Status,
+		input.Attempts,
+		input.NextAttemptAt,
+		input.LastError,
+		input.ResponseCode,
+		input.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("webhook-repository-UpdateDelivery, %s", err.Error())
+	}
+
+	return nil
+}
+
+// Постановка доставки в очередь на немедленную повторную отправку.
+func (r *repository) RedeliverDelivery(ctx context.Context, DeliveryID int64) (int64, error) {
+	const format = `UPDATE %s SET
+						status = $1,
+						next_attempt_at = NOW()
+					WHERE id = $2`
+
+	query := fmt.Sprintf(
+		format,
+		deliveries,
+	)
+
+	rows, err := r.db.ExecContext(
+		ctx,
+		query,
+		DeliveryPending,
+		DeliveryID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("webhook-repository-RedeliverDelivery, %s", err.Error())
+	}
+
+	return rows.RowsAffected()
+}
@@ -0,0 +1,312 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет, что событие доставляется на все включенные эндпоинты одним запросом, а повторное
+// событие не создает доставок.
+func TestCreateDeliveries(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	// Создание фиктивного подключения к базе данных.
+	defer db.Close()
+
+	r := NewWebhookRepository(db)
+
+	input := DeliveryInput{
+		EventID:   "evt_1",
+		EventType: "payment.created",
+		Payload:   []byte(`{"id":"evt_1"}`),
+	}
+
+	tests := []struct {
+		name   string
+		mock   func()
+		input  DeliveryInput
+		expect int64
+		err    error
+	}{
+		{
+			name: "Fan out to enabled endpoints",
+			mock: func() {
+				dbMock.ExpectExec("INSERT INTO webhook_deliveries (.+) SELECT (.+) from webhook_endpoints (.+) ON CONFLICT").
+					WithArgs("evt_1", "payment.created", `{"id":"evt_1"}`).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+			input:  input,
+			expect: 2,
+			err:    nil,
+		},
+		{
+			name: "Republished event",
+			mock: func() {
+				dbMock.ExpectExec("INSERT INTO webhook_deliveries").
+					WithArgs("evt_1", "payment.created", `{"id":"evt_1"}`).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			input:  input,
+			expect: 0,
+			err:    nil,
+		},
+		{
+			name: "Fail",
+			mock: func() {
+				dbMock.ExpectExec("INSERT INTO webhook_deliveries").
+					WithArgs("evt_1", "payment.created", `{"id":"evt_1"}`).
+					WillReturnError(errors.New("insert error"))
+			},
+			input: input,
+			err:   errors.New("insert error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.CreateDeliveries(
+				context.TODO(),
+				tt.input,
+			)
+
+			if tt.err != nil {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expect, got)
+			}
+
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+// Он проверяет, что доставки занимаются одним запросом с пропуском заблокированных строк и
+// возвращаются с адресом и секретом эндпоинта.
+func TestClaimDueDeliveries(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	// Создание фиктивного подключения к базе данных.
+	defer db.Close()
+
+	r := NewWebhookRepository(db)
+
+	until := time.Date(2022, 7, 1, 12, 1, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		mock   func()
+		expect []delivery
+		err    error
+	}{
+		{
+			name: "Claim due deliveries",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"id", "endpoint_id", "event_id", "event_type", "payload", "status", "attempts", "next_attempt_at", "url", "secret"}).
+					AddRow(1, 1, "evt_1", "payment.created", `{"id":"evt_1"}`, DeliveryPending, 0, until, "https://merchant.example/hook", "secret").
+					AddRow(2, 2, "evt_1", "payment.created", `{"id":"evt_1"}`, DeliveryPending, 3, until, "https://other.example/hook", "other")
+
+				dbMock.ExpectQuery("FOR UPDATE SKIP LOCKED (.+) UPDATE webhook_deliveries (.+) RETURNING").
+					WithArgs(DeliveryPending, 10, until).
+					WillReturnRows(rows)
+			},
+			expect: []delivery{
+				{ID: 1, EndpointID: 1, EventID: "evt_1", EventType: "payment.created", Payload: []byte(`{"id":"evt_1"}`), Status: DeliveryPending, Attempts: 0, NextAttemptAt: until, URL: "https://merchant.example/hook", Secret: "secret"},
+				{ID: 2, EndpointID: 2, EventID: "evt_1", EventType: "payment.created", Payload: []byte(`{"id":"evt_1"}`), Status: DeliveryPending, Attempts: 3, NextAttemptAt: until, URL: "https://other.example/hook", Secret: "other"},
+			},
+			err: nil,
+		},
+		{
+			name: "Fail",
+			mock: func() {
+				dbMock.ExpectQuery("FOR UPDATE SKIP LOCKED").
+					WithArgs(DeliveryPending, 10, until).
+					WillReturnError(errors.New("select error"))
+			},
+			err: errors.New("select error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.ClaimDueDeliveries(
+				context.TODO(),
+				10,
+				until,
+			)
+
+			if tt.err != nil {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expect, got)
+			}
+
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+// Он проверяет сохранение результата попытки доставки.
+func TestUpdateDelivery(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	// Создание фиктивного подключения к базе данных.
+	defer db.Close()
+
+	r := NewWebhookRepository(db)
+
+	next := time.Date(2022, 7, 1, 12, 0, 10, 0, time.UTC)
+	input := delivery{
+		ID:            1,
+		Status:        DeliveryPending,
+		Attempts:      1,
+		NextAttemptAt: next,
+		LastError:     "unexpected response status 500",
+		ResponseCode:  500,
+	}
+
+	tests := []struct {
+		name string
+		mock func()
+		err  error
+	}{
+		{
+			name: "Update delivery",
+			mock: func() {
+				dbMock.ExpectExec("UPDATE webhook_deliveries SET").
+					WithArgs(DeliveryPending, 1, next, "unexpected response status 500", 500, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			err: nil,
+		},
+		{
+			name: "Fail",
+			mock: func() {
+				dbMock.ExpectExec("UPDATE webhook_deliveries SET").
+					WithArgs(DeliveryPending, 1, next, "unexpected response status 500", 500, 1).
+					WillReturnError(errors.New("update error"))
+			},
+			err: errors.New("update error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			err := r.UpdateDelivery(
+				context.TODO(),
+				input,
+			)
+
+			if tt.err != nil {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+// Он проверяет постановку доставки в очередь и количество измененных строк для неизвестной доставки.
+func TestRedeliverDelivery(t *testing.T) {
+	t.Parallel()
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	// Создание фиктивного подключения к базе данных.
+	defer db.Close()
+
+	r := NewWebhookRepository(db)
+
+	tests := []struct {
+		name   string
+		mock   func()
+		input  int64
+		expect int64
+		err    error
+	}{
+		{
+			name: "Redeliver",
+			mock: func() {
+				dbMock.ExpectExec("UPDATE webhook_deliveries SET").
+					WithArgs(DeliveryPending, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			input:  1,
+			expect: 1,
+			err:    nil,
+		},
+		{
+			name: "Unknown delivery",
+			mock: func() {
+				dbMock.ExpectExec("UPDATE webhook_deliveries SET").
+					WithArgs(DeliveryPending, 100).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			input:  100,
+			expect: 0,
+			err:    nil,
+		},
+		{
+			name: "Fail",
+			mock: func() {
+				dbMock.ExpectExec("UPDATE webhook_deliveries SET").
+					WithArgs(DeliveryPending, 1).
+					WillReturnError(errors.New("update error"))
+			},
+			input: 1,
+			err:   errors.New("update error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.RedeliverDelivery(
+				context.TODO(),
+				tt.input,
+			)
+
+			if tt.err != nil {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expect, got)
+			}
+
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Возвращает подпись события: HMAC-SHA256 по секрету эндпоинта от строки «timestamp.payload» в
+// шестнадцатеричном виде. Мерчант проверяет ее по заголовкам X-Webhook-Timestamp и X-Webhook-Signature.
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Возвращает true, если подпись signature соответствует событию.
+func Verify(secret string, timestamp int64, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, payload)), []byte(signature))
+}
@@ -0,0 +1,142 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/onlycodergod/payment-api-emulator/internal/outbox"
+	"github.com/onlycodergod/payment-api-emulator/internal/payment"
+)
+
+// Ошибки, которые возвращает вариант использования webhook.
+var (
+	ErrEndpointNotFound = errors.New("webhook endpoint not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// UseCase — это структура с репозиторием эндпоинтов и доставок.
+// @property {WebhookRepository} repo - Это хранилище эндпоинтов и журнала доставок.
+type UseCase struct {
+	repo WebhookRepository
+}
+
+// > Эта функция создает новый экземпляр структуры UseCase и возвращает указатель на нее.
+func NewWebhookUseCase(repo WebhookRepository) *UseCase {
+	return &UseCase{
+		repo: repo,
+	}
+}
+
+// Возвращает случайную строку из n байт в шестнадцатеричном виде с префиксом.
+func randomID(prefix string, n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return prefix + hex.EncodeToString(b), nil
+}
+
+// Эта функция используется для регистрации эндпоинта. Если секрет не передан, он генерируется.
+func (u *UseCase) CreateEndpoint(ctx context.Context, input EndpointInput) (endpoint, error) {
+	if input.Secret == "" {
+		secret, err := randomID("whsec_", 24)
+		if err != nil {
+			return endpoint{}, fmt.Errorf("webhook-UseCase-CreateEndpoint, %s", err.Error())
+		}
+
+		input.Secret = secret
+	}
+
+	return u.repo.CreateEndpoint(
+		ctx,
+		input,
+	)
+}
+
+// Эта функция используется для получения всех эндпоинтов.
+func (u *UseCase) GetEndpoints(ctx context.Context) ([]endpoint, error) {
+	return u.repo.GetEndpoints(ctx)
+}
+
+// Эта функция используется для удаления эндпоинта.
+func (u *UseCase) DeleteEndpoint(ctx context.Context, EndpointID int64) error {
+	rows, err := u.repo.DeleteEndpoint(
+		ctx,
+		EndpointID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("webhook-UseCase-DeleteEndpoint, %w", ErrEndpointNotFound)
+	}
+
+	return nil
+}
+
+// Эта функция используется для получения журнала доставок эндпоинта.
+func (u *UseCase) GetDeliveries(ctx context.Context, EndpointID int64) ([]delivery, error) {
+	return u.repo.GetDeliveries(
+		ctx,
+		EndpointID,
+	)
+}
+
+// Эта функция используется для повторной отправки доставки вручную.
+func (u *UseCase) Redeliver(ctx context.Context, DeliveryID int64) error {
+	rows, err := u.repo.RedeliverDelivery(
+		ctx,
+		DeliveryID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("webhook-UseCase-Redeliver, %w", ErrDeliveryNotFound)
+	}
+
+	return nil
+}
+
+// Получает пачку событий outbox и создает их доставки на все включенные эндпоинты, реализуя
+// outbox.Sink. ID события webhook строится из ID записи outbox, поэтому повторно опубликованное
+// событие не создает вторую доставку. Сами запросы к эндпоинтам отправляет Dispatcher.
+func (u *UseCase) Publish(ctx context.Context, events []outbox.Event) error {
+	for _, value := range events {
+		paymentEvent := payment.EventFromOutbox(value)
+		id := fmt.Sprintf("evt_%d", value.ID)
+
+		payload, err := json.Marshal(
+			event{
+				ID:        id,
+				Type:      paymentEvent.Type,
+				CreatedAt: paymentEvent.OccurredAt,
+				Data:      paymentEvent,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("webhook-UseCase-Publish, %s", err.Error())
+		}
+
+		_, err = u.repo.CreateDeliveries(
+			ctx,
+			DeliveryInput{
+				EventID:   id,
+				EventType: paymentEvent.Type,
+				Payload:   payload,
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
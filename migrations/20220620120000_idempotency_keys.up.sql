@@ -0,0 +1,18 @@
+-- Creating a table called idempotency_keys that stores the first response of POST /payment
+-- for every Idempotency-Key header:
+-- - key: the value of the Idempotency-Key header
+-- - fingerprint: sha256 of the request method, path and body
+-- - status_code: the http status code of the stored response
+-- - response: the body of the stored response
+-- - created_at: when the key was first used
+-- - expires_at: after this moment the key can be reused
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key VARCHAR(255) PRIMARY KEY,
+    fingerprint CHAR(64) NOT NULL,
+    status_code INT NOT NULL,
+    response TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX ON idempotency_keys(expires_at);
@@ -0,0 +1,7 @@
+DROP TABLE refunds;
+
+ALTER TABLE payments
+    DROP CONSTRAINT payments_refunded_amount_check,
+    DROP COLUMN refunded_amount;
+
+DROP TYPE valid_refund_status;
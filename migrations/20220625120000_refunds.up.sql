@@ -0,0 +1,35 @@
+-- Creating a type called valid_refund_status that can only be one of the values in the list.
+CREATE TYPE valid_refund_status AS ENUM (
+    'pending',
+    'succeeded',
+    'failed'
+);
+
+-- Adding the refunded_amount column to payments. The constraint guarantees that the refunded total
+-- never exceeds the original amount of the payment.
+ALTER TABLE payments
+    ADD COLUMN refunded_amount decimal(12, 2) NOT NULL DEFAULT 0 CHECK(refunded_amount >= 0),
+    ADD CONSTRAINT payments_refunded_amount_check CHECK(refunded_amount <= amount);
+
+-- Creating a table called refunds with the following columns:
+-- - id: a serial primary key
+-- - payment_id: the refunded payment
+-- - amount: a decimal that cannot be null and must be greater than 0
+-- - status: a valid_refund_status that cannot be null and defaults to 'pending'
+-- - created_at: a timestamp with time zone that cannot be null and defaults to now
+-- - updated_at: a timestamp with time zone that cannot be null and defaults to now
+CREATE TABLE IF NOT EXISTS refunds (
+    id SERIAL PRIMARY KEY,
+    payment_id INT NOT NULL REFERENCES payments(id),
+    amount decimal(12, 2) NOT NULL CHECK(amount > 0),
+    status valid_refund_status NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON refunds(payment_id);
+
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON refunds
+    FOR EACH ROW
+    EXECUTE PROCEDURE trigger_set_timestamp();
@@ -0,0 +1,5 @@
+DROP TABLE webhook_deliveries;
+
+DROP TYPE valid_delivery_status;
+
+DROP TABLE webhook_endpoints;
@@ -0,0 +1,49 @@
+-- Creating a table called webhook_endpoints with the merchant endpoints that receive payment events:
+-- - id: a serial primary key
+-- - url: the address the events are posted to
+-- - secret: the key of the HMAC-SHA256 signature
+-- - enabled: disabled endpoints receive no new events
+-- - created_at: a timestamp with time zone that cannot be null and defaults to now
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id SERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Creating a type called valid_delivery_status that can only be one of the values in the list.
+CREATE TYPE valid_delivery_status AS ENUM (
+    'pending',
+    'succeeded',
+    'failed'
+);
+
+-- Creating a table called webhook_deliveries that is the delivery log of every event to every endpoint:
+-- - event_id, event_type, payload: the signed event
+-- - status: a valid_delivery_status that cannot be null and defaults to 'pending'
+-- - attempts: how many times the event was posted
+-- - next_attempt_at: when the dispatcher posts the event again
+-- - last_error, response_code: the result of the last attempt
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    endpoint_id INT NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+    event_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload TEXT NOT NULL,
+    status valid_delivery_status NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_error TEXT NOT NULL DEFAULT '',
+    response_code INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON webhook_deliveries(endpoint_id);
+CREATE INDEX ON webhook_deliveries(status, next_attempt_at);
+
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON webhook_deliveries
+    FOR EACH ROW
+    EXECUTE PROCEDURE trigger_set_timestamp();
@@ -0,0 +1 @@
+DROP TABLE payment_outbox;
\ No newline at end of file
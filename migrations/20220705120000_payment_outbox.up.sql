@@ -0,0 +1,19 @@
+-- Creating a table called payment_outbox. A row is written in the same transaction as every status
+-- mutation of a payment and is later drained by the relay worker:
+-- - payment_id: the changed payment
+-- - old_status: the status before the change, null for a created payment
+-- - new_status: the status after the change
+-- - actor: who made the change
+-- - created_at: when the change was made
+-- - published_at: when the relay delivered the event to the sink, null while pending
+CREATE TABLE IF NOT EXISTS payment_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    payment_id INT NOT NULL REFERENCES payments(id),
+    old_status valid_status,
+    new_status valid_status NOT NULL,
+    actor VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX ON payment_outbox(id) WHERE published_at IS NULL;
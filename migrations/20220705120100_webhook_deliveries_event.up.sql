@@ -0,0 +1,3 @@
+-- Webhook deliveries are created by the outbox relay, which may publish an event more than once:
+-- a unique event per endpoint, so a republished event does not create a second delivery.
+CREATE UNIQUE INDEX webhook_deliveries_endpoint_event ON webhook_deliveries(endpoint_id, event_id);
@@ -0,0 +1,4 @@
+ALTER TABLE payment_outbox
+    DROP COLUMN reason;
+
+DROP TABLE payment_status_history;
\ No newline at end of file
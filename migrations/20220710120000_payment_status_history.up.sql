@@ -0,0 +1,26 @@
+-- Creating a table called payment_status_history with one row for every transition of a payment:
+-- - payment_id: the changed payment
+-- - from_status: the status before the transition, null for a created payment
+-- - to_status: the status after the transition
+-- - reason: an optional reason given by the actor
+-- - actor: who made the transition
+-- - created_at: when the transition was made
+CREATE TABLE IF NOT EXISTS payment_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    payment_id INT NOT NULL REFERENCES payments(id),
+    from_status valid_status,
+    to_status valid_status NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    actor VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON payment_status_history(payment_id, id);
+
+-- Existing payments get the creation entry. Earlier transitions were not recorded.
+INSERT INTO payment_status_history (payment_id, to_status, actor, created_at)
+    SELECT id, 'new', 'system', created_at FROM payments;
+
+-- The reason of the status change is also copied into the outbox event.
+ALTER TABLE payment_outbox
+    ADD COLUMN reason TEXT NOT NULL DEFAULT '';
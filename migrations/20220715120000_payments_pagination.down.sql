@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS payments_user_email_id_idx;
+DROP INDEX IF EXISTS payments_user_id_id_idx;
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- Indexes for keyset pagination of user payment listings: the filter column followed by id.
+CREATE INDEX IF NOT EXISTS payments_user_id_id_idx ON payments(user_id, id);
+CREATE INDEX IF NOT EXISTS payments_user_email_id_idx ON payments(user_email, id);
@@ -0,0 +1,18 @@
+ALTER TABLE refunds
+    ALTER COLUMN amount TYPE decimal(12, 2);
+
+ALTER TABLE payments
+    ALTER COLUMN amount TYPE decimal(12, 2),
+    ALTER COLUMN refunded_amount TYPE decimal(12, 2);
+
+CREATE TYPE valid_currency AS ENUM (
+    'usd',
+    'eur',
+    'rub'
+);
+
+ALTER TABLE payments
+    DROP CONSTRAINT payments_currency_fkey,
+    ALTER COLUMN currency TYPE valid_currency USING currency::valid_currency;
+
+DROP TABLE currencies;
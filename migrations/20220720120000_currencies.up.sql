@@ -0,0 +1,41 @@
+-- Creating a table called currencies with the following columns:
+-- - code: the ISO 4217 alphabetic code in lower case
+-- - numeric_code: the ISO 4217 numeric code
+-- - minor_units: the number of digits after the decimal point
+-- - enabled: whether new payments are accepted in the currency
+-- - updated_at: a timestamp with time zone that cannot be null and defaults to now
+-- The application adds the rest of its ISO 4217 list on startup, disabled.
+CREATE TABLE IF NOT EXISTS currencies (
+    code VARCHAR(3) PRIMARY KEY,
+    numeric_code CHAR(3) NOT NULL,
+    minor_units SMALLINT NOT NULL CHECK(minor_units BETWEEN 0 AND 6),
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON currencies
+    FOR EACH ROW
+    EXECUTE PROCEDURE trigger_set_timestamp();
+
+-- The currencies of the former valid_currency enum stay enabled.
+INSERT INTO currencies (code, numeric_code, minor_units, enabled) VALUES
+    ('usd', '840', 2, TRUE),
+    ('eur', '978', 2, TRUE),
+    ('rub', '643', 2, TRUE)
+ON CONFLICT (code) DO NOTHING;
+
+-- Replacing the valid_currency enum with a foreign key to the registry.
+ALTER TABLE payments
+    ALTER COLUMN currency TYPE VARCHAR(3) USING currency::text,
+    ADD CONSTRAINT payments_currency_fkey FOREIGN KEY (currency) REFERENCES currencies(code);
+
+DROP TYPE valid_currency;
+
+-- Widening amounts for currencies with three minor units.
+ALTER TABLE payments
+    ALTER COLUMN amount TYPE decimal(15, 3),
+    ALTER COLUMN refunded_amount TYPE decimal(15, 3);
+
+ALTER TABLE refunds
+    ALTER COLUMN amount TYPE decimal(15, 3);
@@ -0,0 +1,6 @@
+ALTER TABLE payments
+    DROP COLUMN fx_rated_at,
+    DROP COLUMN fx_source,
+    DROP COLUMN fx_rate,
+    DROP COLUMN settlement_amount,
+    DROP COLUMN settlement_currency;
@@ -0,0 +1,27 @@
+-- Adding the settlement side of a payment:
+-- - settlement_currency: the currency the merchant is paid out in
+-- - settlement_amount: the amount converted into the settlement currency
+-- - fx_rate: the exchange rate applied to the payment
+-- - fx_source: where the rate came from (identity, file, admin or drift)
+-- - fx_rated_at: when the rate was taken
+ALTER TABLE payments
+    ADD COLUMN settlement_currency VARCHAR(3) REFERENCES currencies(code),
+    ADD COLUMN settlement_amount decimal(15, 3),
+    ADD COLUMN fx_rate decimal(18, 6),
+    ADD COLUMN fx_source VARCHAR(16),
+    ADD COLUMN fx_rated_at TIMESTAMP WITH TIME ZONE;
+
+-- Existing payments are settled in their own currency.
+UPDATE payments SET
+    settlement_currency = currency,
+    settlement_amount = amount,
+    fx_rate = 1,
+    fx_source = 'identity',
+    fx_rated_at = created_at;
+
+ALTER TABLE payments
+    ALTER COLUMN settlement_currency SET NOT NULL,
+    ALTER COLUMN settlement_amount SET NOT NULL,
+    ALTER COLUMN fx_rate SET NOT NULL,
+    ALTER COLUMN fx_source SET NOT NULL,
+    ALTER COLUMN fx_rated_at SET NOT NULL;
@@ -0,0 +1,30 @@
+-- Postgres cannot drop an enum value, so valid_status is recreated without it. Authorized payments are
+-- canceled and their transitions are removed from the outbox and the history.
+DELETE FROM payment_status_history WHERE from_status = 'authorized' OR to_status = 'authorized';
+DELETE FROM payment_outbox WHERE old_status = 'authorized' OR new_status = 'authorized';
+UPDATE payments SET status = 'canceled' WHERE status = 'authorized';
+
+ALTER TYPE valid_status RENAME TO valid_status_old;
+
+CREATE TYPE valid_status AS ENUM (
+    'new',
+    'success',
+    'failure',
+    'error',
+    'canceled'
+);
+
+ALTER TABLE payments
+    ALTER COLUMN status DROP DEFAULT,
+    ALTER COLUMN status TYPE valid_status USING status::text::valid_status,
+    ALTER COLUMN status SET DEFAULT 'new';
+
+ALTER TABLE payment_outbox
+    ALTER COLUMN old_status TYPE valid_status USING old_status::text::valid_status,
+    ALTER COLUMN new_status TYPE valid_status USING new_status::text::valid_status;
+
+ALTER TABLE payment_status_history
+    ALTER COLUMN from_status TYPE valid_status USING from_status::text::valid_status,
+    ALTER COLUMN to_status TYPE valid_status USING to_status::text::valid_status;
+
+DROP TYPE valid_status_old;
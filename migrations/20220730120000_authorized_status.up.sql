@@ -0,0 +1,3 @@
+-- Adding the authorized status for the two-step authorize/capture flow. A new enum value cannot be used in
+-- the transaction that adds it, so the columns that depend on it are added by the next migration.
+ALTER TYPE valid_status ADD VALUE IF NOT EXISTS 'authorized' AFTER 'new';
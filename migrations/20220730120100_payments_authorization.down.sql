@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS payments_authorized_at_idx;
+
+ALTER TABLE payments
+    DROP COLUMN authorized_at,
+    DROP COLUMN captured_amount;
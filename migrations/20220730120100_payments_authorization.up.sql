@@ -0,0 +1,13 @@
+-- Adding the authorization side of a payment:
+-- - captured_amount: the amount taken from the customer, refunds cannot exceed it
+-- - authorized_at: when the payment was authorized, null for a payment that was never authorized
+ALTER TABLE payments
+    ADD COLUMN captured_amount decimal(15, 3) NOT NULL DEFAULT 0,
+    ADD COLUMN authorized_at TIMESTAMP WITH TIME ZONE,
+    ADD CONSTRAINT payments_captured_amount_check CHECK(captured_amount >= 0 AND captured_amount <= amount);
+
+-- Successful payments were captured in full.
+UPDATE payments SET captured_amount = amount WHERE status = 'success';
+
+-- The expiry worker looks for authorizations older than the hold period.
+CREATE INDEX IF NOT EXISTS payments_authorized_at_idx ON payments(authorized_at) WHERE status = 'authorized';
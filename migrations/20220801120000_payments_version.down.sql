@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS increment_version ON payments;
+DROP FUNCTION IF EXISTS trigger_increment_version();
+
+ALTER TABLE payments
+    DROP COLUMN version;
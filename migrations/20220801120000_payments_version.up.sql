@@ -0,0 +1,17 @@
+-- Adding a version to payments for optimistic concurrency:
+-- - version: starts at 1 and is incremented by a trigger on every update, exposed as the payment ETag
+ALTER TABLE payments
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+CREATE FUNCTION trigger_increment_version()
+RETURNS TRIGGER AS $$
+    BEGIN
+    NEW.version = OLD.version + 1;
+    RETURN NEW;
+    END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER increment_version
+    BEFORE UPDATE ON payments
+    FOR EACH ROW
+    EXECUTE PROCEDURE trigger_increment_version();
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// Каталог миграций относительно рабочего каталога приложения.
+const MigrationsPath = "file://migrations"
+
+// Возвращает версию последней миграции из каталога path, например «file://migrations». До этой версии
+// InitMigrate обновляет базу данных при запуске.
+func LatestMigration(path string) (uint, error) {
+	driver, err := source.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer driver.Close()
+
+	version, err := driver.First()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		next, err := driver.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		version = next
+	}
+}
+
+// Возвращает проверку готовности, которая сравнивает версию примененных миграций в таблице
+// schema_migrations с ожидаемой версией expected. Незавершенная миграция тоже считается ошибкой.
+func CheckMigrations(db *sql.DB, expected uint) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var (
+			version uint
+			dirty   bool
+		)
+
+		err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("no migrations applied")
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if dirty {
+			return fmt.Errorf("migration %d is dirty", version)
+		}
+
+		if version != expected {
+			return fmt.Errorf("migration version is %d, expected %d", version, expected)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет, что последняя миграция находится по каталогу миграций.
+func TestLatestMigration(t *testing.T) {
+	t.Parallel()
+
+	files, err := filepath.Glob("../../../migrations/*.up.sql")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, files)
+
+	sort.Strings(files)
+	expect, err := strconv.ParseUint(strings.SplitN(filepath.Base(files[len(files)-1]), "_", 2)[0], 10, 64)
+	assert.NoError(t, err)
+
+	version, err := LatestMigration("file://../../../migrations")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(expect), version)
+
+	_, err = LatestMigration("file://missing")
+	assert.Error(t, err)
+}
+
+// Он проверяет проверку версии примененных миграций.
+func TestCheckMigrations(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name    string
+		version uint
+		dirty   bool
+		err     string
+	}{
+		{name: "Expected version", version: 3},
+		{name: "Old version", version: 2, err: "migration version is 2, expected 3"},
+		{name: "Dirty migration", version: 3, dirty: true, err: "migration 3 is dirty"},
+	}
+
+	check := CheckMigrations(db, 3)
+
+	for _, tt := range tests {
+		mock.ExpectQuery("SELECT version, dirty FROM schema_migrations").
+			WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(tt.version, tt.dirty))
+
+		err := check(context.TODO())
+		if tt.err == "" {
+			assert.NoError(t, err, tt.name)
+		} else {
+			assert.EqualError(t, err, tt.err, tt.name)
+		}
+	}
+
+	mock.ExpectQuery("SELECT version, dirty FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}))
+	assert.EqualError(t, check(context.TODO()), "no migrations applied")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
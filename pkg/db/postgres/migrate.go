@@ -15,7 +15,7 @@ func InitMigrate(logger loggin.ILogger, options DBOptions) {
 		logger.Fatal("migrate: environment variable not declared")
 	}
 
-	m, err := migrate.New("file://migrations", dsn)
+	m, err := migrate.New(MigrationsPath, dsn)
 	if err != nil {
 		logger.Fatal(err)
 	}
@@ -0,0 +1,208 @@
+// Package health отдает состояние приложения для проб оркестратора: /healthz - процесс запущен и
+// обслуживает запросы, /readyz - приложение готово принимать трафик.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Это константа, определяющая маршрут.
+const (
+	Liveness  = "/healthz"
+	Readiness = "/readyz"
+)
+
+// Состояния приложения и его компонентов.
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusDraining = "draining"
+)
+
+// Тайм-аут проверок готовности по умолчанию.
+const DefaultTimeout = 2 * time.Second
+
+// Компонент, который показывает, что процесс запущен.
+const ComponentProcess = "process"
+
+// Компонент, который показывает, что сервер не завершает работу.
+const ComponentServer = "server"
+
+// Check — это проверка компонента, например ping базы данных. Возвращает ошибку, если компонент не
+// готов.
+type Check func(ctx context.Context) error
+
+// Component — это состояние одного компонента.
+// @property {string} Status - Состояние: «ok», «fail» или «draining».
+// @property {string} Error - Описание ошибки проверки.
+type Component struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report — это ответ пробы.
+// @property {string} Status - Общее состояние: «ok», если все компоненты в порядке, «fail», если проверка
+// компонента не прошла, или «draining», если сервер завершает работу.
+// @property {map[string]Component} Components - Состояние компонентов по имени.
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+// Checker — это набор проверок готовности и признак завершения работы.
+// @property mu - Мьютекс, который защищает проверки.
+// @property checks - Проверки по имени компонента.
+// @property {time.Duration} timeout - Тайм-аут всех проверок одного запроса.
+// @property {int32} draining - 1, если приложение завершает работу.
+type Checker struct {
+	mu       sync.Mutex
+	checks   map[string]Check
+	timeout  time.Duration
+	draining int32
+}
+
+// > Эта функция создает новый набор проверок с тайм-аутом timeout и возвращает указатель на него.
+// Нулевой timeout заменяется на DefaultTimeout.
+func NewChecker(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Checker{
+		checks:  make(map[string]Check),
+		timeout: timeout,
+	}
+}
+
+// Добавляет проверку готовности компонента name.
+func (c *Checker) Add(name string, check Check) *Checker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks[name] = check
+
+	return c
+}
+
+// Отмечает, что приложение завершает работу. После этого проба готовности отвечает 503, чтобы
+// балансировщик перестал направлять запросы, а проба живости по-прежнему отвечает 200.
+func (c *Checker) Drain() {
+	atomic.StoreInt32(&c.draining, 1)
+}
+
+// Возвращает true, если приложение завершает работу.
+func (c *Checker) Draining() bool {
+	return atomic.LoadInt32(&c.draining) == 1
+}
+
+// Возвращает состояние живости: процесс запущен и обслуживает запросы.
+func (c *Checker) Live(ctx context.Context) Report {
+	return Report{
+		Status: StatusOK,
+		Components: map[string]Component{
+			ComponentProcess: {Status: StatusOK},
+		},
+	}
+}
+
+// Возвращает состояние готовности. Проверки выполняются параллельно с общим тайм-аутом.
+func (c *Checker) Ready(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.mu.Lock()
+	checks := make(map[string]Check, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.Unlock()
+
+	components := make(map[string]Component, len(checks)+1)
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			value := Component{Status: StatusOK}
+			if err := check(ctx); err != nil {
+				value = Component{Status: StatusFail, Error: err.Error()}
+			}
+
+			mu.Lock()
+			components[name] = value
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	components[ComponentServer] = Component{Status: StatusOK}
+	if c.Draining() {
+		components[ComponentServer] = Component{Status: StatusDraining}
+	}
+
+	return Report{
+		Status:     status(components),
+		Components: components,
+	}
+}
+
+// Возвращает общее состояние: «ok», если все компоненты в порядке, «draining», если сервер завершает
+// работу, иначе «fail».
+func status(components map[string]Component) string {
+	output := StatusOK
+	for _, value := range components {
+		switch value.Status {
+		case StatusFail:
+			return StatusFail
+		case StatusDraining:
+			output = StatusDraining
+		}
+	}
+
+	return output
+}
+
+// Эта функция регистрирует обработчики проб живости и готовности.
+func (c *Checker) Register(router *mux.Router) *mux.Router {
+	router.HandleFunc(Liveness, c.GetLiveness).Methods(http.MethodGet).Name("Liveness")
+	router.HandleFunc(Readiness, c.GetReadiness).Methods(http.MethodGet).Name("Readiness")
+	return router
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/healthz` методом `GET`.
+func (c *Checker) GetLiveness(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, c.Live(r.Context()))
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/readyz` методом `GET`. Неготовое приложение отвечает 503.
+func (c *Checker) GetReadiness(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, c.Ready(r.Context()))
+}
+
+// Записывает ответ пробы: 200 для состояния «ok», иначе 503.
+func writeReport(w http.ResponseWriter, report Report) {
+	code := http.StatusOK
+	if report.Status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,83 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет ответы проб живости и готовности с исправными и неисправными компонентами и во время
+// завершения работы.
+func TestChecker(t *testing.T) {
+	t.Parallel()
+
+	var databaseErr error
+
+	checker := NewChecker(0)
+	checker.Add("database", func(ctx context.Context) error { return databaseErr })
+	checker.Add("migrations", func(ctx context.Context) error { return nil })
+
+	router := checker.Register(mux.NewRouter())
+
+	get := func(target string) (int, Report) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+		var report Report
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&report), target)
+
+		return w.Code, report
+	}
+
+	code, report := get(Readiness)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, Report{
+		Status: StatusOK,
+		Components: map[string]Component{
+			"database":      {Status: StatusOK},
+			"migrations":    {Status: StatusOK},
+			ComponentServer: {Status: StatusOK},
+		},
+	}, report)
+
+	databaseErr = errors.New("connection refused")
+
+	code, report = get(Readiness)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, Component{Status: StatusFail, Error: "connection refused"}, report.Components["database"])
+
+	databaseErr = nil
+	checker.Drain()
+
+	code, report = get(Readiness)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusDraining, report.Status)
+	assert.Equal(t, Component{Status: StatusDraining}, report.Components[ComponentServer])
+
+	code, report = get(Liveness)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, Report{Status: StatusOK, Components: map[string]Component{ComponentProcess: {Status: StatusOK}}}, report)
+}
+
+// Он проверяет, что проверка, которая не укладывается в тайм-аут, получает отмененный контекст.
+func TestCheckerTimeout(t *testing.T) {
+	t.Parallel()
+
+	checker := NewChecker(10 * time.Millisecond)
+	checker.Add("database", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	report := checker.Ready(context.TODO())
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Components["database"].Error)
+}
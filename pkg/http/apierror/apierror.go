@@ -0,0 +1,49 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/requestid"
+)
+
+// Заголовок, в котором клиент может передать идентификатор запроса. Он же возвращается в ответе.
+const RequestIDHeader = requestid.Header
+
+// Body — это тело ответа с ошибкой.
+// @property {string} Code - Машиночитаемый код ошибки, например «payment_not_found».
+// @property {string} Message - Описание ошибки для человека.
+// @property Details - Подробности: поле запроса, текущий статус и т.д. Не передаются, если их нет.
+// @property {string} RequestID - Идентификатор запроса, по которому ошибку можно найти в журнале.
+type Body struct {
+	Code      string                 `json:"code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"request_id"`
+}
+
+// Отвечает клиенту ошибкой с кодом status в формате JSON. Идентификатор запроса берется из контекста
+// или заголовка X-Request-ID либо создается, если его нет, и возвращается в том же заголовке.
+func Write(w http.ResponseWriter, r *http.Request, status int, body Body) {
+	body.RequestID = RequestID(r)
+
+	w.Header().Set(RequestIDHeader, body.RequestID)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// Возвращает идентификатор запроса из контекста, куда его сохраняет requestid.Middleware, из заголовка
+// X-Request-ID или новый случайный идентификатор.
+func RequestID(r *http.Request) string {
+	if id := requestid.FromContext(r.Context()); id != "" {
+		return id
+	}
+
+	if id := r.Header.Get(RequestIDHeader); id != "" {
+		return id
+	}
+
+	return requestid.New()
+}
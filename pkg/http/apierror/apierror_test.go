@@ -0,0 +1,52 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет тело и заголовки ответа с ошибкой.
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		requestID string
+	}{
+		{
+			name:      "Request ID from header",
+			requestID: "abc",
+		},
+		{
+			name: "Generated request ID",
+		},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/payments/1", nil)
+		if tt.requestID != "" {
+			r.Header.Set(RequestIDHeader, tt.requestID)
+		}
+
+		w := httptest.NewRecorder()
+		Write(w, r, http.StatusNotFound, Body{Code: "payment_not_found", Message: "payment not found"})
+
+		var got Body
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&got), tt.name)
+
+		assert.Equal(t, http.StatusNotFound, w.Code, tt.name)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"), tt.name)
+		assert.Equal(t, "payment_not_found", got.Code, tt.name)
+		assert.Equal(t, "payment not found", got.Message, tt.name)
+		assert.NotEmpty(t, got.RequestID, tt.name)
+		assert.Equal(t, got.RequestID, w.Header().Get(RequestIDHeader), tt.name)
+
+		if tt.requestID != "" {
+			assert.Equal(t, tt.requestID, got.RequestID, tt.name)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package fault
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/apierror"
+)
+
+// Это константа, определяющая маршрут.
+const Faults = "/admin/faults"
+
+// Settings — это настройки внедрения сбоев, которые читает и меняет административный эндпоинт.
+// @property {int64} Seed - Начальное значение генератора случайных чисел. При изменении 0 оставляет
+// текущий генератор.
+// @property {map[string]Rule} Rules - Правила по имени маршрута, например «CreatePayment».
+type Settings struct {
+	Seed  int64           `json:"seed"`
+	Rules map[string]Rule `json:"rules"`
+}
+
+// Эта функция регистрирует административные обработчики внедрения сбоев. Маршруты не имеют имени,
+// поэтому правила к ним не применяются.
+func (i *Injector) Register(router *mux.Router) *mux.Router {
+	router.HandleFunc(Faults, i.GetSettings).Methods(http.MethodGet)
+	router.HandleFunc(Faults, i.PutSettings).Methods(http.MethodPut)
+	router.HandleFunc(Faults, i.DeleteSettings).Methods(http.MethodDelete)
+	return router
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/admin/faults` методом `GET`.
+func (i *Injector) GetSettings(w http.ResponseWriter, r *http.Request) {
+	seed, rules := i.Rules()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(
+		Settings{
+			Seed:  seed,
+			Rules: rules,
+		},
+	)
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/admin/faults` методом `PUT`. Заменяет все правила и, если передан seed, пересоздает генератор.
+func (i *Injector) PutSettings(w http.ResponseWriter, r *http.Request) {
+	var input Settings
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.Body{
+			Code:    CodeInvalidBody,
+			Message: InvalidBodyData,
+		})
+		return
+	}
+
+	if err := i.SetRules(input.Rules); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.Body{
+			Code:    CodeInvalidRules,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if input.Seed != 0 {
+		i.Reseed(input.Seed)
+	}
+
+	i.GetSettings(w, r)
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/admin/faults` методом `DELETE`. Удаляет все правила.
+func (i *Injector) DeleteSettings(w http.ResponseWriter, r *http.Request) {
+	_ = i.SetRules(nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,12 @@
+package fault
+
+const InjectedError = "injected fault"
+
+const InvalidBodyData = "invalid body data"
+
+// Машиночитаемые коды ошибок в поле «code» ответа.
+const (
+	CodeInvalidBody   = "invalid_body"
+	CodeInvalidRules  = "invalid_rules"
+	CodeFaultInjected = "fault_injected"
+)
@@ -0,0 +1,238 @@
+package fault
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/apierror"
+)
+
+// Код ответа по умолчанию для внедренной ошибки.
+const DefaultErrorStatus = http.StatusServiceUnavailable
+
+// Rule — это настройки внедрения сбоев для одного маршрута.
+// @property {int64} LatencyMS - Фиксированная задержка ответа в миллисекундах.
+// @property {int64} JitterMS - Случайная добавка к задержке от 0 до JitterMS миллисекунд.
+// @property {float64} ErrorRate - Доля запросов от 0 до 1, на которые возвращается ошибка.
+// @property {int} ErrorStatus - Код ответа ошибки, по умолчанию 503.
+// @property {int64} RetryAfter - Значение заголовка Retry-After в секундах для ответов 429 и 503.
+// @property {float64} DropRate - Доля запросов от 0 до 1, соединение которых разрывается без ответа.
+type Rule struct {
+	LatencyMS   int64   `json:"latency_ms,omitempty"`
+	JitterMS    int64   `json:"jitter_ms,omitempty"`
+	ErrorRate   float64 `json:"error_rate,omitempty"`
+	ErrorStatus int     `json:"error_status,omitempty"`
+	RetryAfter  int64   `json:"retry_after,omitempty"`
+	DropRate    float64 `json:"drop_rate,omitempty"`
+}
+
+// Проверяет, что задержки не отрицательные, доли лежат от 0 до 1, а код ответа является кодом ошибки.
+func (r Rule) Validate() error {
+	if r.LatencyMS < 0 || r.JitterMS < 0 {
+		return errors.New("latency must not be negative")
+	}
+
+	if r.ErrorRate < 0 || r.ErrorRate > 1 {
+		return errors.New("error_rate must be between 0 and 1")
+	}
+
+	if r.DropRate < 0 || r.DropRate > 1 {
+		return errors.New("drop_rate must be between 0 and 1")
+	}
+
+	if r.ErrorStatus != 0 && (r.ErrorStatus < http.StatusBadRequest || r.ErrorStatus > 599) {
+		return fmt.Errorf("error_status %d is not an error status", r.ErrorStatus)
+	}
+
+	if r.RetryAfter < 0 {
+		return errors.New("retry_after must not be negative")
+	}
+
+	return nil
+}
+
+// Injector — это потокобезопасный набор правил внедрения сбоев по имени маршрута. Маршрут без правила
+// обслуживается без изменений.
+// @property mu - Мьютекс, который защищает правила и генератор случайных чисел.
+// @property {int64} seed - Начальное значение генератора случайных чисел.
+// @property rules - Правила по имени маршрута.
+// @property random - Генератор случайных чисел для задержек и долей запросов.
+type Injector struct {
+	mu     sync.Mutex
+	seed   int64
+	rules  map[string]Rule
+	random *rand.Rand
+}
+
+// > Эта функция создает новый экземпляр структуры Injector без правил и возвращает указатель на нее.
+// Одинаковый seed дает одинаковую последовательность решений, 0 - случайный seed.
+func NewInjector(seed int64) *Injector {
+	i := &Injector{
+		rules: make(map[string]Rule),
+	}
+	i.Reseed(seed)
+
+	return i
+}
+
+// Пересоздает генератор случайных чисел с новым seed.
+func (i *Injector) Reseed(seed int64) {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.seed = seed
+	i.random = rand.New(rand.NewSource(seed))
+}
+
+// Возвращает текущий seed и копию правил.
+func (i *Injector) Rules() (int64, map[string]Rule) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	rules := make(map[string]Rule, len(i.rules))
+	for name, rule := range i.rules {
+		rules[name] = rule
+	}
+
+	return i.seed, rules
+}
+
+// Заменяет все правила. Правила проверяются до замены, при ошибке старые правила сохраняются.
+func (i *Injector) SetRules(rules map[string]Rule) error {
+	for name, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("%s: %s", name, err.Error())
+		}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.rules = make(map[string]Rule, len(rules))
+	for name, rule := range rules {
+		i.rules[name] = rule
+	}
+
+	return nil
+}
+
+// Решение по одному запросу.
+// @property {time.Duration} delay - Задержка перед обработкой.
+// @property {bool} drop - Разорвать соединение без ответа.
+// @property {int} status - Код ответа ошибки, 0 - обработать запрос.
+// @property {int64} retryAfter - Значение заголовка Retry-After.
+type decision struct {
+	delay      time.Duration
+	drop       bool
+	status     int
+	retryAfter int64
+}
+
+// Принимает решение по запросу к маршруту name. Все броски делаются под одной блокировкой, поэтому
+// при одинаковом seed и порядке запросов решения повторяются.
+func (i *Injector) decide(name string) (decision, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	rule, ok := i.rules[name]
+	if !ok {
+		return decision{}, false
+	}
+
+	output := decision{
+		delay: time.Duration(rule.LatencyMS) * time.Millisecond,
+	}
+
+	if rule.JitterMS > 0 {
+		output.delay += time.Duration(i.random.Int63n(rule.JitterMS+1)) * time.Millisecond
+	}
+
+	if rule.DropRate > 0 && i.random.Float64() < rule.DropRate {
+		output.drop = true
+		return output, true
+	}
+
+	if rule.ErrorRate > 0 && i.random.Float64() < rule.ErrorRate {
+		output.status = rule.ErrorStatus
+		if output.status == 0 {
+			output.status = DefaultErrorStatus
+		}
+
+		output.retryAfter = rule.RetryAfter
+	}
+
+	return output, true
+}
+
+// Middleware — это mux-middleware, которое применяет правило маршрута, найденного маршрутизатором, по его
+// имени (route.Name). Запрос ждет задержку, затем соединение разрывается, возвращается ошибка или запрос
+// передается дальше.
+func (i *Injector) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := mux.CurrentRoute(r)
+		if route == nil || route.GetName() == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		value, ok := i.decide(route.GetName())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if value.delay > 0 {
+			timer := time.NewTimer(value.delay)
+
+			select {
+			case <-r.Context().Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+
+		if value.drop {
+			drop(w)
+			return
+		}
+
+		if value.status != 0 {
+			if value.retryAfter > 0 && (value.status == http.StatusTooManyRequests || value.status == http.StatusServiceUnavailable) {
+				w.Header().Set("Retry-After", strconv.FormatInt(value.retryAfter, 10))
+			}
+
+			apierror.Write(w, r, value.status, apierror.Body{
+				Code:    CodeFaultInjected,
+				Message: InjectedError,
+				Details: map[string]interface{}{"route": route.GetName()},
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Разрывает соединение без ответа. Если соединение нельзя перехватить, обработчик прерывается через
+// http.ErrAbortHandler, и сервер закрывает соединение сам.
+func drop(w http.ResponseWriter) {
+	if hijacker, ok := w.(http.Hijacker); ok {
+		if conn, _, err := hijacker.Hijack(); err == nil {
+			conn.Close()
+			return
+		}
+	}
+
+	panic(http.ErrAbortHandler)
+}
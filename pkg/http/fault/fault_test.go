@@ -0,0 +1,146 @@
+package fault
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/apierror"
+	"github.com/stretchr/testify/assert"
+)
+
+// Он создает маршрутизатор с одним именованным маршрутом и подключенным Injector.
+func newRouter(i *Injector) *mux.Router {
+	router := mux.NewRouter()
+	router.Use(i.Middleware)
+	i.Register(router)
+	router.HandleFunc("/payment", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}).Methods(http.MethodPost).Name("CreatePayment")
+
+	return router
+}
+
+// Он проверяет ответы маршрута с разными правилами.
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		rules      map[string]Rule
+		expect     int
+		retryAfter string
+	}{
+		{
+			name:   "No rule",
+			rules:  nil,
+			expect: http.StatusCreated,
+		},
+		{
+			name:   "Rule for another route",
+			rules:  map[string]Rule{"GetStatusByID": {ErrorRate: 1}},
+			expect: http.StatusCreated,
+		},
+		{
+			name:       "Default error status",
+			rules:      map[string]Rule{"CreatePayment": {ErrorRate: 1, RetryAfter: 2}},
+			expect:     http.StatusServiceUnavailable,
+			retryAfter: "2",
+		},
+		{
+			name:       "Too many requests",
+			rules:      map[string]Rule{"CreatePayment": {ErrorRate: 1, ErrorStatus: http.StatusTooManyRequests, RetryAfter: 1}},
+			expect:     http.StatusTooManyRequests,
+			retryAfter: "1",
+		},
+		{
+			name:   "Latency only",
+			rules:  map[string]Rule{"CreatePayment": {LatencyMS: 1, JitterMS: 1}},
+			expect: http.StatusCreated,
+		},
+	}
+
+	for _, tt := range tests {
+		i := NewInjector(1)
+		assert.NoError(t, i.SetRules(tt.rules), tt.name)
+
+		w := httptest.NewRecorder()
+		newRouter(i).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/payment", nil))
+
+		assert.Equal(t, tt.expect, w.Code, tt.name)
+		assert.Equal(t, tt.retryAfter, w.Header().Get("Retry-After"), tt.name)
+
+		if tt.expect >= http.StatusBadRequest {
+			var got apierror.Body
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&got), tt.name)
+			assert.Equal(t, CodeFaultInjected, got.Code, tt.name)
+			assert.Equal(t, "CreatePayment", got.Details["route"], tt.name)
+			assert.NotEmpty(t, got.RequestID, tt.name)
+		}
+	}
+}
+
+// Он проверяет, что разорванное соединение не возвращает ответа клиенту.
+func TestMiddlewareDrop(t *testing.T) {
+	t.Parallel()
+
+	i := NewInjector(1)
+	assert.NoError(t, i.SetRules(map[string]Rule{"CreatePayment": {DropRate: 1}}))
+
+	srv := httptest.NewServer(newRouter(i))
+	defer srv.Close()
+
+	_, err := http.Post(srv.URL+"/payment", "application/json", nil)
+	assert.Error(t, err)
+}
+
+// Он проверяет, что одинаковый seed дает одинаковую последовательность решений.
+func TestInjectorSeed(t *testing.T) {
+	t.Parallel()
+
+	rules := map[string]Rule{"CreatePayment": {ErrorRate: 0.5, JitterMS: 100}}
+
+	sequence := func() []decision {
+		i := NewInjector(42)
+		assert.NoError(t, i.SetRules(rules))
+
+		output := make([]decision, 0, 20)
+		for n := 0; n < 20; n++ {
+			value, _ := i.decide("CreatePayment")
+			output = append(output, value)
+		}
+
+		return output
+	}
+
+	assert.Equal(t, sequence(), sequence())
+}
+
+// Он проверяет отклонение некорректных правил.
+func TestRuleValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input Rule
+		err   bool
+	}{
+		{name: "Valid rule", input: Rule{LatencyMS: 100, ErrorRate: 0.1, ErrorStatus: 502}},
+		{name: "Negative latency", input: Rule{LatencyMS: -1}, err: true},
+		{name: "Error rate above one", input: Rule{ErrorRate: 2}, err: true},
+		{name: "Drop rate below zero", input: Rule{DropRate: -0.1}, err: true},
+		{name: "Success status", input: Rule{ErrorRate: 1, ErrorStatus: http.StatusOK}, err: true},
+	}
+
+	for _, tt := range tests {
+		err := tt.input.Validate()
+
+		if tt.err {
+			assert.Error(t, err, tt.name)
+		} else {
+			assert.NoError(t, err, tt.name)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package loglevels
+
+const InvalidBodyData = "invalid body data"
+
+// Машиночитаемые коды ошибок в поле «code» ответа.
+const (
+	CodeInvalidBody   = "invalid_body"
+	CodeInvalidLevels = "invalid_levels"
+)
@@ -0,0 +1,66 @@
+package loglevels
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/apierror"
+	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
+)
+
+// > Тип контроллера — это структура с уровнями журнала, которые читает и меняет административный
+// эндпоинт.
+// @property levels - Это уровни журнала по умолчанию и по имени регистратора.
+type controller struct {
+	levels *loggin.Levels
+}
+
+// > Эта функция создает новый экземпляр структуры контроллера и возвращает указатель на него
+func NewLevelsController(levels *loggin.Levels) *controller {
+	return &controller{
+		levels: levels,
+	}
+}
+
+// Это константа, определяющая маршрут.
+const LogLevels = "/admin/log/levels"
+
+// Эта функция регистрирует административные обработчики уровней журнала.
+func (c *controller) Register(router *mux.Router) *mux.Router {
+	router.HandleFunc(LogLevels, c.GetLevels).Methods(http.MethodGet)
+	router.HandleFunc(LogLevels, c.PutLevels).Methods(http.MethodPut)
+	return router
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/admin/log/levels` методом `GET`.
+func (c *controller) GetLevels(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(c.levels.Settings())
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/admin/log/levels` методом `PUT`. Меняет уровень по умолчанию, если он передан, и заменяет все
+// уровни регистраторов без перезапуска.
+func (c *controller) PutLevels(w http.ResponseWriter, r *http.Request) {
+	var input loggin.LevelSettings
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.Body{
+			Code:    CodeInvalidBody,
+			Message: InvalidBodyData,
+		})
+		return
+	}
+
+	if err := c.levels.Set(input); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.Body{
+			Code:    CodeInvalidLevels,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.GetLevels(w, r)
+}
@@ -0,0 +1,87 @@
+package loglevels
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/apierror"
+	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет административный эндпоинт уровней журнала.
+func TestLevelsHandlers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		code   int
+		expect string
+		error  apierror.Body
+	}{
+		{
+			name:   "get",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			expect: `{"level":"info","packages":{}}`,
+		},
+		{
+			name:   "put",
+			method: http.MethodPut,
+			body:   `{"level":"debug","packages":{"payment":"error"}}`,
+			code:   http.StatusOK,
+			expect: `{"level":"debug","packages":{"payment":"error"}}`,
+		},
+		{
+			name:   "unknown level",
+			method: http.MethodPut,
+			body:   `{"level":"verbose"}`,
+			code:   http.StatusBadRequest,
+			error:  apierror.Body{Code: CodeInvalidLevels, Message: `unknown level "verbose"`},
+		},
+		{
+			name:   "invalid body",
+			method: http.MethodPut,
+			body:   `{`,
+			code:   http.StatusBadRequest,
+			error:  apierror.Body{Code: CodeInvalidBody, Message: InvalidBodyData},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			levels, err := loggin.NewLevels(loggin.LevelSettings{})
+			assert.NoError(t, err)
+
+			router := NewLevelsController(levels).Register(mux.NewRouter())
+
+			r := httptest.NewRequest(test.method, LogLevels, strings.NewReader(test.body))
+			r.Header.Set(apierror.RequestIDHeader, "abc")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			assert.Equal(t, test.code, w.Code)
+
+			if test.expect != "" {
+				assert.Equal(t, test.expect, strings.TrimSpace(w.Body.String()))
+				return
+			}
+
+			var got apierror.Body
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+
+			test.error.RequestID = "abc"
+			assert.Equal(t, test.error, got)
+		})
+	}
+}
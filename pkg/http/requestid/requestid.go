@@ -0,0 +1,83 @@
+// Package requestid принимает или создает идентификатор запроса X-Request-ID, сохраняет его в контексте
+// запроса и в полях журнала и возвращает его в ответе.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
+)
+
+// Заголовок, в котором клиент может передать идентификатор запроса. Он же возвращается в ответе.
+const Header = "X-Request-ID"
+
+// Поле записей журнала с идентификатором запроса.
+const Field = "request_id"
+
+// Максимальная длина идентификатора запроса, переданного клиентом.
+const MaxLength = 128
+
+// Ключ контекста, в котором хранится идентификатор запроса.
+type contextKey struct{}
+
+// Возвращает контекст с идентификатором запроса id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// Возвращает идентификатор запроса из контекста или пустую строку.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+
+	return id
+}
+
+// Возвращает новый случайный идентификатор запроса.
+func New() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// Возвращает true, если идентификатор клиента можно записать в журнал и заголовок: он не длиннее
+// MaxLength и состоит из видимых символов ASCII.
+func valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Возвращает middleware HTTP-сервера, которое берет идентификатор запроса из заголовка X-Request-ID или
+// создает новый, если заголовка нет или он некорректен. Идентификатор сохраняется в контексте запроса
+// вместе с регистратором logger и полем журнала request_id и возвращается в заголовке ответа.
+func Middleware(logger loggin.ILogger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(Header)
+			if !valid(id) {
+				id = New()
+			}
+
+			ctx := NewContext(r.Context(), id)
+			ctx = loggin.ContextWith(ctx, Field, id)
+			ctx = loggin.NewContext(ctx, logger)
+
+			w.Header().Set(Header, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет, что корректный идентификатор клиента сохраняется, а отсутствующий или некорректный
+// заменяется новым, и что идентификатор попадает в контекст, поля журнала и заголовок ответа.
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{name: "client id", header: "req-42", keep: true},
+		{name: "no header", header: ""},
+		{name: "with spaces", header: "req 42"},
+		{name: "too long", header: strings.Repeat("a", MaxLength+1)},
+		{name: "max length", header: strings.Repeat("a", MaxLength), keep: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var id string
+			var fields []interface{}
+			handler := Middleware(loggin.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				id = FromContext(r.Context())
+				fields = loggin.Fields(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/payments", nil)
+			if test.header != "" {
+				r.Header.Set(Header, test.header)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if test.keep {
+				assert.Equal(t, test.header, id)
+			} else {
+				assert.NotEqual(t, test.header, id)
+				assert.Len(t, id, 16)
+			}
+
+			assert.Equal(t, id, w.Header().Get(Header))
+			assert.Equal(t, []interface{}{Field, id}, fields)
+		})
+	}
+}
@@ -0,0 +1,131 @@
+// Package route хранит в контексте запроса маршрут, найденный маршрутизатором, и сведения об ответе,
+// чтобы middleware метрик и журнала доступа не искали маршрут и не оборачивали ответ каждое отдельно.
+package route
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Ключ контекста, в котором хранится запись запроса.
+type contextKey struct{}
+
+// Recorder — это http.ResponseWriter, который запоминает маршрут запроса, код ответа и размер тела.
+// Код 0 означает, что соединение разорвано без ответа.
+// @property {string} name - Имя маршрута, найденного маршрутизатором.
+// @property {string} template - Шаблон пути маршрута, например «/payments/{id}».
+// @property {int} status - Код ответа.
+// @property {int64} bytes - Размер записанного тела ответа.
+type Recorder struct {
+	http.ResponseWriter
+	name     string
+	template string
+	status   int
+	bytes    int64
+}
+
+// Возвращает запись запроса из контекста или nil, если ее нет.
+func FromContext(ctx context.Context) *Recorder {
+	recorder, _ := ctx.Value(contextKey{}).(*Recorder)
+
+	return recorder
+}
+
+// Возвращает запись запроса из контекста вместе с исходными w и r. Если записи нет, она создается и
+// сохраняется в контексте, а ответ и запрос, которые нужно передать дальше, заменяются на нее и запрос с
+// новым контекстом. Так первое middleware создает запись, а следующие используют ее же.
+func Wrap(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request, *Recorder) {
+	if recorder := FromContext(r.Context()); recorder != nil {
+		return w, r, recorder
+	}
+
+	recorder := &Recorder{ResponseWriter: w}
+
+	return recorder, r.WithContext(context.WithValue(r.Context(), contextKey{}, recorder)), recorder
+}
+
+// Match — это mux-middleware, которое сохраняет в записи запроса маршрут, найденный маршрутизатором. Оно
+// подключается через router.Use и вызывается только для запросов, которым нашелся маршрут, поэтому
+// маршрут ищется один раз - самим маршрутизатором.
+func Match(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := FromContext(r.Context())
+		current := mux.CurrentRoute(r)
+
+		if recorder != nil && current != nil {
+			recorder.name = current.GetName()
+			recorder.template, _ = current.GetPathTemplate()
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Возвращает имя маршрута. Для маршрута без имени возвращается шаблон пути, а для запроса без
+// маршрута - пустая строка.
+func (r *Recorder) Name() string {
+	if r.name != "" {
+		return r.name
+	}
+
+	return r.template
+}
+
+// Возвращает шаблон пути маршрута или пустую строку, если маршрут не найден.
+func (r *Recorder) Template() string {
+	return r.template
+}
+
+// Возвращает код ответа.
+func (r *Recorder) Status() int {
+	return r.status
+}
+
+// Возвращает размер записанного тела ответа.
+func (r *Recorder) Bytes() int64 {
+	return r.bytes
+}
+
+// Запоминает код ответа и передает его дальше.
+func (r *Recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Записывает тело ответа и считает его размер. Тело без WriteHeader отправляется с кодом 200.
+func (r *Recorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+
+	n, err := r.ResponseWriter.Write(data)
+	r.bytes += int64(n)
+
+	return n, err
+}
+
+// Отправляет буферизованные данные клиенту, если это поддерживает исходный http.ResponseWriter.
+func (r *Recorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Перехватывает соединение, если это поддерживает исходный http.ResponseWriter. Нужен для разрыва
+// соединения при внедрении сбоев.
+func (r *Recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("route: response writer does not support hijacking")
+	}
+
+	return hijacker.Hijack()
+}
@@ -0,0 +1,57 @@
+package route
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет, что вложенные middleware получают одну запись запроса, а маршрут в нее сохраняет
+// route.Match только для запросов, которым нашелся маршрут.
+func TestWrapAndMatch(t *testing.T) {
+	t.Parallel()
+
+	router := mux.NewRouter()
+	router.Use(Match)
+	router.HandleFunc("/payments/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}).Name("GetPaymentByID")
+	router.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name     string
+		target   string
+		route    string
+		template string
+		status   int
+		bytes    int64
+	}{
+		{name: "Named route", target: "/payments/1", route: "GetPaymentByID", template: "/payments/{id}", status: http.StatusOK, bytes: 2},
+		{name: "Route without name", target: "/healthz", route: "/healthz", template: "/healthz"},
+		{name: "Unmatched", target: "/unknown", status: http.StatusNotFound, bytes: 19},
+	}
+
+	for _, tt := range tests {
+		var outer, inner *Recorder
+
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w, r, outer = Wrap(w, r)
+
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w, r, inner = Wrap(w, r)
+				router.ServeHTTP(w, r)
+			}).ServeHTTP(w, r)
+		})
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+		assert.Same(t, outer, inner, tt.name)
+		assert.Equal(t, tt.route, outer.Name(), tt.name)
+		assert.Equal(t, tt.template, outer.Template(), tt.name)
+		assert.Equal(t, tt.status, outer.Status(), tt.name)
+		assert.Equal(t, tt.bytes, outer.Bytes(), tt.name)
+	}
+}
@@ -0,0 +1,295 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/requestid"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/route"
+)
+
+// Форматы журнала доступа.
+const (
+	AccessFormatJSON     = "json"
+	AccessFormatLogfmt   = "logfmt"
+	AccessFormatCombined = "combined"
+)
+
+// Формат времени записи журнала доступа в формате Apache combined.
+const combinedTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLogOptions — это настройки журнала доступа.
+// @property {string} Format - Формат записей: «json», «logfmt» или «combined».
+// @property {float64} SampleRate - Доля записываемых запросов от 0 до 1. Ответы с кодом 5xx
+// записываются всегда.
+// @property {[]string} Exclude - Пути запросов, которые не записываются, например /healthz.
+type AccessLogOptions struct {
+	Format     string
+	SampleRate float64
+	Exclude    []string
+}
+
+// AccessEntry — это запись журнала доступа об одном запросе.
+// @property {time.Time} Time - Время начала обработки запроса.
+// @property {string} Method - Метод запроса.
+// @property {string} Route - Шаблон пути маршрута, например «/payments/{id}», или «-» без маршрута.
+// @property {string} Path - Путь запроса.
+// @property {string} Proto - Версия протокола запроса.
+// @property {int} Status - Код ответа. 0 означает, что соединение разорвано без ответа.
+// @property {int64} Bytes - Размер тела ответа в байтах.
+// @property {time.Duration} Latency - Время обработки запроса.
+// @property {string} RemoteAddr - Адрес клиента без порта.
+// @property {string} RequestID - Идентификатор запроса из X-Request-ID.
+// @property {string} Referer - Заголовок Referer.
+// @property {string} UserAgent - Заголовок User-Agent.
+type AccessEntry struct {
+	Time       time.Time
+	Method     string
+	Route      string
+	Path       string
+	Proto      string
+	Status     int
+	Bytes      int64
+	Latency    time.Duration
+	RemoteAddr string
+	RequestID  string
+	Referer    string
+	UserAgent  string
+}
+
+// AccessLog — это журнал доступа: по одной записи на запрос в выбранном формате.
+// @property mu - Мьютекс, который защищает вывод и генератор случайных чисел.
+// @property {io.Writer} out - Вывод записей, например os.Stdout.
+// @property format - Функция, которая форматирует запись в строку.
+// @property {float64} sampleRate - Доля записываемых запросов.
+// @property exclude - Пути запросов, которые не записываются.
+// @property random - Генератор случайных чисел для выборки запросов.
+type AccessLog struct {
+	mu         sync.Mutex
+	out        io.Writer
+	format     func(entry AccessEntry) string
+	sampleRate float64
+	exclude    map[string]struct{}
+	random     *rand.Rand
+}
+
+// > Эта функция создает новый журнал доступа, который пишет в out, и возвращает указатель на него.
+// Возвращает ошибку, если формат неизвестен или доля выборки не в диапазоне от 0 до 1.
+func NewAccessLog(out io.Writer, options AccessLogOptions) (*AccessLog, error) {
+	var format func(entry AccessEntry) string
+	switch options.Format {
+	case AccessFormatJSON, "":
+		format = formatJSON
+	case AccessFormatLogfmt:
+		format = formatLogfmt
+	case AccessFormatCombined:
+		format = formatCombined
+	default:
+		return nil, fmt.Errorf("server-NewAccessLog, unknown format %q", options.Format)
+	}
+
+	if options.SampleRate < 0 || options.SampleRate > 1 {
+		return nil, fmt.Errorf("server-NewAccessLog, sample rate %v is out of range [0, 1]", options.SampleRate)
+	}
+
+	exclude := make(map[string]struct{}, len(options.Exclude))
+	for _, path := range options.Exclude {
+		exclude[path] = struct{}{}
+	}
+
+	return &AccessLog{
+		out:        out,
+		format:     format,
+		sampleRate: options.SampleRate,
+		exclude:    exclude,
+		random:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// Middleware — это middleware HTTP-сервера, которое записывает запрос в журнал доступа после ответа.
+// Идентификатор запроса берется из контекста, поэтому middleware подключается после
+// requestid.Middleware. Шаблон пути сохраняет в записи запроса route.Match, подключенное к
+// маршрутизатору.
+func (a *AccessLog) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := a.exclude[r.URL.Path]; ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w, r, recorder := route.Wrap(w, r)
+		start := time.Now()
+
+		// Запись делается и тогда, когда обработчик прерван паникой, например при разрыве соединения.
+		defer func() {
+			if !a.sample(recorder.Status()) {
+				return
+			}
+
+			a.write(AccessEntry{
+				Time:       start,
+				Method:     r.Method,
+				Route:      dash(recorder.Template()),
+				Path:       r.URL.RequestURI(),
+				Proto:      r.Proto,
+				Status:     recorder.Status(),
+				Bytes:      recorder.Bytes(),
+				Latency:    time.Since(start),
+				RemoteAddr: remoteHost(r.RemoteAddr),
+				RequestID:  requestid.FromContext(r.Context()),
+				Referer:    r.Referer(),
+				UserAgent:  r.UserAgent(),
+			})
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Возвращает true, если запрос попадает в выборку. Ответы с кодом 5xx записываются всегда.
+func (a *AccessLog) sample(status int) bool {
+	if status >= http.StatusInternalServerError || a.sampleRate >= 1 {
+		return true
+	}
+
+	if a.sampleRate <= 0 {
+		return false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.random.Float64() < a.sampleRate
+}
+
+// Записывает запись одной строкой.
+func (a *AccessLog) write(entry AccessEntry) {
+	line := a.format(entry) + "\n"
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	io.WriteString(a.out, line)
+}
+
+// Возвращает адрес клиента без порта.
+func remoteHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
+
+// Форматирует запись в JSON.
+func formatJSON(entry AccessEntry) string {
+	data, _ := json.Marshal(struct {
+		Time       string  `json:"time"`
+		Method     string  `json:"method"`
+		Route      string  `json:"route"`
+		Path       string  `json:"path"`
+		Status     int     `json:"status"`
+		Bytes      int64   `json:"bytes"`
+		LatencyMS  float64 `json:"latency_ms"`
+		RemoteAddr string  `json:"remote_addr"`
+		RequestID  string  `json:"request_id"`
+	}{
+		Time:       entry.Time.Format(time.RFC3339),
+		Method:     entry.Method,
+		Route:      entry.Route,
+		Path:       entry.Path,
+		Status:     entry.Status,
+		Bytes:      entry.Bytes,
+		LatencyMS:  latencyMS(entry.Latency),
+		RemoteAddr: entry.RemoteAddr,
+		RequestID:  entry.RequestID,
+	})
+
+	return string(data)
+}
+
+// Форматирует запись в logfmt: пары key=value через пробел.
+func formatLogfmt(entry AccessEntry) string {
+	pairs := []struct {
+		key   string
+		value string
+	}{
+		{"time", entry.Time.Format(time.RFC3339)},
+		{"method", entry.Method},
+		{"route", entry.Route},
+		{"path", entry.Path},
+		{"status", strconv.Itoa(entry.Status)},
+		{"bytes", strconv.FormatInt(entry.Bytes, 10)},
+		{"latency_ms", strconv.FormatFloat(latencyMS(entry.Latency), 'f', -1, 64)},
+		{"remote_addr", entry.RemoteAddr},
+		{"request_id", entry.RequestID},
+	}
+
+	var builder strings.Builder
+	for i, pair := range pairs {
+		if i > 0 {
+			builder.WriteByte(' ')
+		}
+
+		builder.WriteString(pair.key)
+		builder.WriteByte('=')
+		builder.WriteString(logfmtValue(pair.value))
+	}
+
+	return builder.String()
+}
+
+// Возвращает значение logfmt, заключенное в кавычки, если оно пустое или содержит пробелы, кавычки или
+// знак равенства.
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\"=\\") || strings.IndexFunc(value, func(r rune) bool { return r < ' ' }) >= 0 {
+		return strconv.Quote(value)
+	}
+
+	return value
+}
+
+// Форматирует запись в формате Apache combined, за которым следуют время обработки в миллисекундах,
+// шаблон пути маршрута и идентификатор запроса.
+func formatCombined(entry AccessEntry) string {
+	bytes := "-"
+	if entry.Bytes > 0 {
+		bytes = strconv.FormatInt(entry.Bytes, 10)
+	}
+
+	return fmt.Sprintf(`%s - - [%s] %s %d %s %s %s %s %s %s`,
+		dash(entry.RemoteAddr),
+		entry.Time.Format(combinedTimeLayout),
+		strconv.Quote(entry.Method+" "+entry.Path+" "+entry.Proto),
+		entry.Status,
+		bytes,
+		strconv.Quote(dash(entry.Referer)),
+		strconv.Quote(dash(entry.UserAgent)),
+		strconv.FormatFloat(latencyMS(entry.Latency), 'f', -1, 64),
+		strconv.Quote(entry.Route),
+		dash(entry.RequestID),
+	)
+}
+
+// Возвращает «-» вместо пустого значения.
+func dash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
+
+// Возвращает время обработки в миллисекундах с точностью до микросекунды.
+func latencyMS(latency time.Duration) float64 {
+	return float64(latency.Microseconds()) / 1000
+}
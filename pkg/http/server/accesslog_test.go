@@ -0,0 +1,151 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/requestid"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/route"
+	"github.com/onlycodergod/payment-api-emulator/pkg/loggin"
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет записи журнала доступа в каждом формате.
+func TestAccessLogFormats(t *testing.T) {
+	t.Parallel()
+
+	entry := AccessEntry{
+		Time:       time.Date(2022, 8, 1, 12, 0, 0, 0, time.UTC),
+		Method:     http.MethodGet,
+		Route:      "/payments/{id}",
+		Path:       "/payments/1?expand=refunds",
+		Proto:      "HTTP/1.1",
+		Status:     http.StatusOK,
+		Bytes:      42,
+		Latency:    1500 * time.Microsecond,
+		RemoteAddr: "192.0.2.1",
+		RequestID:  "req-42",
+		UserAgent:  "curl/7.81.0",
+	}
+
+	tests := []struct {
+		name   string
+		format func(entry AccessEntry) string
+		expect string
+	}{
+		{
+			name:   "json",
+			format: formatJSON,
+			expect: `{"time":"2022-08-01T12:00:00Z","method":"GET","route":"/payments/{id}","path":"/payments/1?expand=refunds","status":200,"bytes":42,"latency_ms":1.5,"remote_addr":"192.0.2.1","request_id":"req-42"}`,
+		},
+		{
+			name:   "logfmt",
+			format: formatLogfmt,
+			expect: `time=2022-08-01T12:00:00Z method=GET route=/payments/{id} path="/payments/1?expand=refunds" status=200 bytes=42 latency_ms=1.5 remote_addr=192.0.2.1 request_id=req-42`,
+		},
+		{
+			name:   "combined",
+			format: formatCombined,
+			expect: `192.0.2.1 - - [01/Aug/2022:12:00:00 +0000] "GET /payments/1?expand=refunds HTTP/1.1" 200 42 "-" "curl/7.81.0" 1.5 "/payments/{id}" req-42`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expect, test.format(entry))
+		})
+	}
+}
+
+// Он проверяет, что запрос записывается с шаблоном пути, кодом, размером ответа и идентификатором
+// запроса, исключенные пути не записываются, а при нулевой доле выборки записываются только ответы 5xx.
+func TestAccessLogMiddleware(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		target     string
+		sampleRate float64
+		expect     []string
+	}{
+		{
+			name:       "logged",
+			target:     "/payments/7",
+			sampleRate: 1,
+			expect:     []string{`method=GET route=/payments/{id} path=/payments/7 status=200 bytes=2 `, `remote_addr=192.0.2.1 request_id=req-42`},
+		},
+		{
+			name:       "unmatched",
+			target:     "/unknown",
+			sampleRate: 1,
+			expect:     []string{`route=- path=/unknown status=404 bytes=19 `},
+		},
+		{name: "excluded", target: "/healthz", sampleRate: 1},
+		{name: "not sampled", target: "/payments/7"},
+		{
+			name:   "server error always logged",
+			target: "/fail",
+			expect: []string{`route=/fail path=/fail status=500 bytes=0 `},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			router := mux.NewRouter()
+			router.Use(route.Match)
+			router.HandleFunc("/payments/{id}", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("{}"))
+			})
+			router.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {})
+			router.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+
+			var out bytes.Buffer
+			accessLog, err := NewAccessLog(&out, AccessLogOptions{
+				Format:     AccessFormatLogfmt,
+				SampleRate: test.sampleRate,
+				Exclude:    []string{"/healthz"},
+			})
+			assert.NoError(t, err)
+
+			handler := requestid.Middleware(loggin.NewNop())(accessLog.Middleware(router))
+
+			r := httptest.NewRequest(http.MethodGet, test.target, nil)
+			r.Header.Set(requestid.Header, "req-42")
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			if len(test.expect) == 0 {
+				assert.Empty(t, out.String())
+				return
+			}
+
+			assert.Equal(t, 1, strings.Count(out.String(), "\n"))
+			for _, part := range test.expect {
+				assert.Contains(t, out.String(), part)
+			}
+		})
+	}
+}
+
+// Он проверяет, что неизвестный формат и доля выборки вне диапазона отклоняются.
+func TestNewAccessLogOptions(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewAccessLog(&bytes.Buffer{}, AccessLogOptions{Format: "xml", SampleRate: 1})
+	assert.Error(t, err)
+
+	_, err = NewAccessLog(&bytes.Buffer{}, AccessLogOptions{Format: AccessFormatCombined, SampleRate: 1.5})
+	assert.Error(t, err)
+}
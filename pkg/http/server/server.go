@@ -11,15 +11,27 @@ import (
 // @property notify - Это канал, который будет использоваться для уведомления основной горутины об
 // остановке сервера.
 // @property shutdownTimeout - Время ожидания завершения работы сервера перед возвратом ошибки.
+// @property drainDelay - Время между началом завершения работы и закрытием соединений.
+// @property onShutdown - Функции, которые вызываются в начале завершения работы.
 type server struct {
 	server          *http.Server
 	notify          chan error
 	shutdownTimeout time.Duration
+	drainDelay      time.Duration
+	onShutdown      []func()
 }
 
+// Middleware — это обертка обработчика HTTP-сервера, например сбор метрик запросов.
+type Middleware func(next http.Handler) http.Handler
+
 // > Эта функция создает новый HTTP-сервер с заданным обработчиком, портом, тайм-аутом чтения,
-// тайм-аутом записи и временем завершения работы.
-func NewHttpServer(handler http.Handler, port string, readTimeout, writeTimeout, shutdownTime time.Duration) *server {
+// тайм-аутом записи и временем завершения работы. Обработчик оборачивается в middlewares: первое из
+// них выполняется первым, и все они видят каждый запрос, в том числе запрос без маршрута.
+func NewHttpServer(handler http.Handler, port string, readTimeout, writeTimeout, shutdownTime time.Duration, middlewares ...Middleware) *server {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
 	httpServer := &http.Server{
 		Addr:         ":" + port,
 		Handler:      handler,
@@ -51,8 +63,26 @@ func (s *server) Notify() <-chan error {
 	return s.notify
 }
 
+// Регистрирует функцию, которая вызывается в начале Shutdown, пока сервер еще принимает запросы,
+// например отметку о завершении работы для пробы готовности.
+func (s *server) OnShutdown(hook func()) {
+	s.onShutdown = append(s.onShutdown, hook)
+}
+
+// Задает, сколько секунд сервер продолжает принимать запросы после вызова функций OnShutdown, чтобы
+// балансировщик успел увидеть, что приложение завершает работу.
+func (s *server) SetDrainDelay(drainDelay time.Duration) {
+	s.drainDelay = drainDelay * time.Second
+}
+
 // Выключение сервера.
 func (s *server) Shutdown() error {
+	for _, hook := range s.onShutdown {
+		hook()
+	}
+
+	time.Sleep(s.drainDelay)
+
 	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
 	defer cancel()
 
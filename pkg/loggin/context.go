@@ -0,0 +1,41 @@
+package loggin
+
+import "context"
+
+// Ключ контекста, в котором хранятся поля записей журнала.
+type fieldsKey struct{}
+
+// Ключ контекста, в котором хранится регистратор.
+type loggerKey struct{}
+
+// Возвращает контекст, в котором к полям записей журнала добавлены пары ключ-значение args, например
+// ContextWith(ctx, "request_id", id). Поля добавляет ILogger.WithContext.
+func ContextWith(ctx context.Context, args ...interface{}) context.Context {
+	fields := append(append([]interface{}(nil), Fields(ctx)...), args...)
+
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// Возвращает поля записей журнала из контекста.
+func Fields(ctx context.Context) []interface{} {
+	fields, _ := ctx.Value(fieldsKey{}).([]interface{})
+
+	return fields
+}
+
+// Возвращает контекст с регистратором l. Его получают слои, которым регистратор не передается при
+// создании, например варианты использования и репозитории.
+func NewContext(ctx context.Context, l ILogger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// Возвращает регистратор из контекста с полями контекста или регистратор, который ничего не
+// записывает, если контекст его не содержит.
+func FromContext(ctx context.Context) ILogger {
+	l, ok := ctx.Value(loggerKey{}).(ILogger)
+	if !ok {
+		l = NewNop()
+	}
+
+	return l.WithContext(ctx)
+}
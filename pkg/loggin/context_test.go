@@ -0,0 +1,33 @@
+package loggin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// Он проверяет, что регистратор из контекста добавляет к записям поля контекста и собственные поля, а
+// без регистратора в контексте ничего не записывается.
+func TestFromContext(t *testing.T) {
+	t.Parallel()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := &logger{loggin: zap.New(core).Sugar()}
+
+	ctx := ContextWith(context.Background(), "request_id", "req-42")
+	ctx = NewContext(ctx, l)
+
+	FromContext(ctx).With("payment_id", 7).Info("captured")
+	l.Info("plain")
+	FromContext(context.Background()).Info("dropped")
+
+	entries := logs.AllUntimed()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "captured", entries[0].Message)
+	assert.Equal(t, map[string]interface{}{"request_id": "req-42", "payment_id": int64(7)}, entries[0].ContextMap())
+	assert.Empty(t, entries[1].Context)
+}
@@ -0,0 +1,172 @@
+package loggin
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelSettings — это уровни журнала, которые читает и меняет административный эндпоинт.
+// @property {string} Level - Уровень по умолчанию: «debug», «info», «warn» или «error». При изменении
+// пустая строка оставляет текущий уровень.
+// @property {map[string]string} Packages - Уровни по имени регистратора, например «payment». Имя
+// «payment.worker» без собственного уровня получает уровень «payment».
+type LevelSettings struct {
+	Level    string            `json:"level"`
+	Packages map[string]string `json:"packages"`
+}
+
+// Levels — это уровни журнала по умолчанию и по имени регистратора, которые можно менять без
+// перезапуска.
+// @property mu - Мьютекс, который защищает уровни.
+// @property {zapcore.Level} level - Уровень по умолчанию.
+// @property packages - Уровни по имени регистратора.
+type Levels struct {
+	mu       sync.RWMutex
+	level    zapcore.Level
+	packages map[string]zapcore.Level
+}
+
+// > Эта функция создает уровни журнала из settings и возвращает указатель на них. Пустой уровень по
+// умолчанию заменяется на «info».
+func NewLevels(settings LevelSettings) (*Levels, error) {
+	l := &Levels{
+		level:    zapcore.InfoLevel,
+		packages: make(map[string]zapcore.Level),
+	}
+
+	if err := l.Set(settings); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Возвращает текущие уровни журнала.
+func (l *Levels) Settings() LevelSettings {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	packages := make(map[string]string, len(l.packages))
+	for name, level := range l.packages {
+		packages[name] = level.String()
+	}
+
+	return LevelSettings{
+		Level:    l.level.String(),
+		Packages: packages,
+	}
+}
+
+// Меняет уровень по умолчанию, если он передан, и заменяет все уровни регистраторов. Если хотя бы один
+// уровень неизвестен, уровни не меняются.
+func (l *Levels) Set(settings LevelSettings) error {
+	level, err := parseLevel(settings.Level)
+	if settings.Level != "" && err != nil {
+		return err
+	}
+
+	packages := make(map[string]zapcore.Level, len(settings.Packages))
+	for name, value := range settings.Packages {
+		if name == "" {
+			return fmt.Errorf("loggin-Levels-Set, empty logger name")
+		}
+
+		parsed, err := parseLevel(value)
+		if err != nil {
+			return fmt.Errorf("loggin-Levels-Set, %s: %w", name, err)
+		}
+
+		packages[name] = parsed
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if settings.Level != "" {
+		l.level = level
+	}
+	l.packages = packages
+
+	return nil
+}
+
+// Возвращает true, если запись уровня level регистратора name записывается. Уровень ищется по имени,
+// затем по именам родителей, например «payment» для «payment.worker», затем берется уровень по
+// умолчанию.
+func (l *Levels) Enabled(name string, level zapcore.Level) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for name != "" {
+		if value, ok := l.packages[name]; ok {
+			return value.Enabled(level)
+		}
+
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+
+		name = name[:i]
+	}
+
+	return l.level.Enabled(level)
+}
+
+// Возвращает наименьший из уровней: запись ниже него не записывается ни одним регистратором.
+func (l *Levels) min() zapcore.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	output := l.level
+	for _, level := range l.packages {
+		if level < output {
+			output = level
+		}
+	}
+
+	return output
+}
+
+// Разбирает название уровня журнала.
+func parseLevel(value string) (zapcore.Level, error) {
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return level, fmt.Errorf("unknown level %q", value)
+	}
+
+	return level, nil
+}
+
+// levelCore — это ядро zap, которое пропускает запись, если ее уровень включен для имени регистратора.
+// @property {zapcore.Core} Core - Ядро, в которое передаются записи.
+// @property {*Levels} levels - Уровни журнала.
+type levelCore struct {
+	zapcore.Core
+	levels *Levels
+}
+
+// Возвращает true, если уровень включен хотя бы для одного регистратора.
+func (c *levelCore) Enabled(level zapcore.Level) bool {
+	return c.levels.min().Enabled(level)
+}
+
+// Возвращает ядро с полями fields.
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{
+		Core:   c.Core.With(fields),
+		levels: c.levels,
+	}
+}
+
+// Добавляет ядро к записи, если ее уровень включен для имени регистратора.
+func (c *levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.levels.Enabled(entry.LoggerName, entry.Level) {
+		return checked
+	}
+
+	return c.Core.Check(entry, checked)
+}
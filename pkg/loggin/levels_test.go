@@ -0,0 +1,43 @@
+package loggin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// Он проверяет, что запись проходит по уровню своего регистратора, его родителя или уровню по
+// умолчанию, а изменение уровней действует без пересоздания регистратора.
+func TestLevels(t *testing.T) {
+	t.Parallel()
+
+	levels, err := NewLevels(LevelSettings{Level: "warn", Packages: map[string]string{"payment": "debug"}})
+	assert.NoError(t, err)
+
+	observed, logs := observer.New(zapcore.DebugLevel)
+	l := &logger{loggin: zap.New(&levelCore{Core: observed, levels: levels}).Sugar()}
+
+	l.Named("payment").Named("worker").Debug("payment debug")
+	l.Named("webhook").Info("webhook info")
+	l.Named("webhook").Warn("webhook warn")
+
+	assert.NoError(t, levels.Set(LevelSettings{Packages: map[string]string{"webhook": "info"}}))
+
+	l.Named("payment").Debug("payment debug after")
+	l.Named("webhook").Info("webhook info after")
+
+	var messages []string
+	for _, entry := range logs.AllUntimed() {
+		messages = append(messages, entry.Message)
+	}
+	assert.Equal(t, []string{"payment debug", "webhook warn", "webhook info after"}, messages)
+
+	assert.Equal(t, LevelSettings{Level: "warn", Packages: map[string]string{"webhook": "info"}}, levels.Settings())
+
+	assert.Error(t, levels.Set(LevelSettings{Level: "verbose"}))
+	assert.Error(t, levels.Set(LevelSettings{Packages: map[string]string{"payment": "loud"}}))
+	assert.Equal(t, "warn", levels.Settings().Level)
+}
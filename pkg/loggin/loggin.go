@@ -1,8 +1,12 @@
 package loggin
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log"
 	"os"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -20,6 +24,10 @@ import (
 // @property Error - Записывает сообщение на уровне ошибки.
 // @property Fatalf - Fatal эквивалентен l.Critical(fmt.Sprint()), за которым следует вызов os.Exit(1).
 // @property Fatal - Fatal регистрирует сообщение уровня Fatal в стандартном регистраторе.
+// @property With - Возвращает регистратор, который добавляет к каждой записи поля из пар ключ-значение.
+// @property WithContext - Возвращает регистратор, который добавляет к каждой записи поля из контекста,
+// например идентификатор запроса.
+// @property Named - Возвращает регистратор с именем, по которому для него выбирается уровень журнала.
 type ILogger interface {
 	Debugf(message string, args ...interface{})
 	Debug(args ...interface{})
@@ -31,47 +39,166 @@ type ILogger interface {
 	Error(args ...interface{})
 	Fatalf(message string, args ...interface{})
 	Fatal(args ...interface{})
+	With(args ...interface{}) ILogger
+	WithContext(ctx context.Context) ILogger
+	Named(name string) ILogger
 }
 
-// Тип регистратора — это структура, которая имеет одно поле с именем loggin типа ILogger.
-// @property {ILogger} loggin - Это интерфейс, который мы будем использовать для регистрации сообщений.
+// Тип регистратора — это структура с регистратором zap и выводами, в которые он пишет.
+// @property loggin - Это регистратор zap, который мы будем использовать для регистрации сообщений.
+// @property writer - Это выводы журнала, в которые можно писать готовые строки, например журнал доступа.
 type logger struct {
-	loggin ILogger
+	loggin *zap.SugaredLogger
+	writer zapcore.WriteSyncer
+}
+
+// Кодировщики записей журнала.
+const (
+	EncodingConsole = "console"
+	EncodingJSON    = "json"
+)
+
+// Выводы журнала.
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+	OutputFile   = "file"
+)
+
+// Options — это настройки регистратора.
+// @property {string} Encoding - Кодировщик записей: «console» или «json».
+// @property {[]string} Outputs - Выводы: «stdout», «stderr» и «file».
+// @property {string} File - Путь к файлу журнала для вывода «file».
+// @property {int64} MaxSize - Наибольший размер файла журнала в байтах, после которого он
+// переименовывается в архивный, 0 - без ограничения.
+// @property {time.Duration} MaxAge - Наибольший возраст архивного файла журнала, 0 - без ограничения.
+type Options struct {
+	Encoding string
+	Outputs  []string
+	File     string
+	MaxSize  int64
+	MaxAge   time.Duration
 }
 
 // `NewLogger` возвращает указатель на структуру `logger`, которая содержит структуру `logging`
 func NewLogger(debug bool) *logger {
+	level := zapcore.InfoLevel
+	if debug {
+		level = zapcore.DebugLevel
+	}
+
+	levels, err := NewLevels(LevelSettings{Level: level.String()})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	writer, err := NewWriter(Options{})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	zapLogger, err := InitZap(Options{}, writer, levels)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	return &logger{
+		loggin: zapLogger,
+		writer: writer,
+	}
+}
+
+// `New` возвращает регистратор с настройками options, уровни которого можно менять через levels.
+func New(options Options, levels *Levels) (*logger, error) {
+	writer, err := NewWriter(options)
+	if err != nil {
+		return nil, err
+	}
+
+	zapLogger, err := InitZap(options, writer, levels)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logger{
+		loggin: zapLogger,
+		writer: writer,
+	}, nil
+}
+
+// `NewNop` возвращает регистратор, который ничего не записывает.
+func NewNop() ILogger {
 	return &logger{
-		loggin: InitZap(debug),
+		loggin: zap.NewNop().Sugar(),
+		writer: zapcore.AddSync(io.Discard),
 	}
 }
 
-// Он создает новый каталог с именем logs.
-func InitZap(debug bool) ILogger {
+// Возвращает выводы журнала. Строки, записанные в них, попадают туда же, куда записи регистратора, и
+// в тот же файл с ротацией.
+func (l *logger) Writer() io.Writer {
+	return l.writer
+}
+
+// Он создает регистратор zap с кодировщиком из options, который пишет в writer. Запись пропускается,
+// если ее уровень не включен в levels для имени регистратора.
+func InitZap(options Options, writer zapcore.WriteSyncer, levels *Levels) (*zap.SugaredLogger, error) {
 	config := zap.NewProductionEncoderConfig()
 	config.TimeKey = "time"
 	config.MessageKey = "message"
+	config.NameKey = "logger"
 	config.EncodeTime = zapcore.TimeEncoderOfLayout("02-01-2006,15:04:05")
 	config.EncodeCaller = zapcore.ShortCallerEncoder
-	encoderConsole := zapcore.NewConsoleEncoder(config)
 
-	err := os.MkdirAll("logs", 0o755)
-	if err != nil {
-		log.Fatalln(err.Error())
+	var encoder zapcore.Encoder
+	switch options.Encoding {
+	case EncodingConsole, "":
+		encoder = zapcore.NewConsoleEncoder(config)
+	case EncodingJSON:
+		encoder = zapcore.NewJSONEncoder(config)
+	default:
+		return nil, fmt.Errorf("loggin-InitZap, unknown encoding %q", options.Encoding)
 	}
 
-	var defaultLogLevel zapcore.Level
-	if debug {
-		defaultLogLevel = zapcore.DebugLevel
-	} else {
-		defaultLogLevel = zapcore.InfoLevel
+	core := &levelCore{
+		Core:   zapcore.NewCore(encoder, writer, zapcore.DebugLevel),
+		levels: levels,
 	}
 
-	core := zapcore.NewTee(
-		zapcore.NewCore(encoderConsole, zapcore.AddSync(os.Stdout), defaultLogLevel),
-	)
+	return zap.New(core, zap.AddCallerSkip(1), zap.AddCaller()).Sugar(), nil
+}
+
+// Он создает выводы журнала из options. Для вывода «file» создается каталог файла журнала.
+func NewWriter(options Options) (zapcore.WriteSyncer, error) {
+	outputs := options.Outputs
+	if len(outputs) == 0 {
+		outputs = []string{OutputStdout}
+	}
+
+	syncers := make([]zapcore.WriteSyncer, 0, len(outputs))
+	for _, output := range outputs {
+		switch output {
+		case OutputStdout:
+			syncers = append(syncers, zapcore.Lock(os.Stdout))
+		case OutputStderr:
+			syncers = append(syncers, zapcore.Lock(os.Stderr))
+		case OutputFile:
+			if options.File == "" {
+				return nil, fmt.Errorf("loggin-NewWriter, %s", "file output requires file")
+			}
+
+			file, err := NewRotatingFile(options.File, options.MaxSize, options.MaxAge)
+			if err != nil {
+				return nil, err
+			}
 
-	return zap.New(core, zap.AddCallerSkip(1), zap.AddCaller()).Sugar()
+			syncers = append(syncers, file)
+		default:
+			return nil, fmt.Errorf("loggin-NewWriter, unknown output %q", output)
+		}
+	}
+
+	return zapcore.NewMultiWriteSyncer(syncers...), nil
 }
 
 // Метод, определенный в структуре logger. Он принимает сообщение и переменную
@@ -133,3 +260,32 @@ func (l *logger) Fatalf(message string, args ...interface{}) {
 func (l *logger) Fatal(args ...interface{}) {
 	l.loggin.Fatal(args...)
 }
+
+// Возвращает регистратор, который добавляет к каждой записи поля из пар ключ-значение, например
+// With("payment_id", 1).
+func (l *logger) With(args ...interface{}) ILogger {
+	return &logger{
+		loggin: l.loggin.With(args...),
+		writer: l.writer,
+	}
+}
+
+// Возвращает регистратор, который добавляет к каждой записи поля, сохраненные в контексте через
+// ContextWith.
+func (l *logger) WithContext(ctx context.Context) ILogger {
+	fields := Fields(ctx)
+	if len(fields) == 0 {
+		return l
+	}
+
+	return l.With(fields...)
+}
+
+// Возвращает регистратор с именем name, например «payment». Имя добавляется к имени текущего
+// регистратора через точку, и по нему выбирается уровень журнала.
+func (l *logger) Named(name string) ILogger {
+	return &logger{
+		loggin: l.loggin.Named(name),
+		writer: l.writer,
+	}
+}
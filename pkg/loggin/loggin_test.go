@@ -0,0 +1,43 @@
+package loggin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет, что строки, записанные в Writer, и записи регистратора попадают в один файл, а
+// неверные выводы отклоняются.
+func TestWriter(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+
+	levels, err := NewLevels(LevelSettings{Level: "info"})
+	assert.NoError(t, err)
+
+	l, err := New(Options{Outputs: []string{OutputFile}, File: path, MaxSize: 1 << 20}, levels)
+	assert.NoError(t, err)
+
+	l.Named("payment").Info("payment created")
+
+	_, err = l.Writer().Write([]byte("GET /payments/1 200\n"))
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "payment created")
+	assert.Equal(t, "GET /payments/1 200", lines[1])
+
+	_, err = NewWriter(Options{Outputs: []string{OutputFile}})
+	assert.Error(t, err)
+
+	_, err = NewWriter(Options{Outputs: []string{"syslog"}})
+	assert.Error(t, err)
+}
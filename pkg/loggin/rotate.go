@@ -0,0 +1,164 @@
+package loggin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Формат времени в имени архивного файла журнала.
+const backupTimeLayout = "20060102T150405.000"
+
+// RotatingFile — это файл журнала, который переименовывается в архивный, когда его размер превышает
+// предел, например «logs/app.log» в «logs/app-20220801T120000.000.log». Архивные файлы старше
+// предельного возраста удаляются.
+// @property mu - Мьютекс, который защищает файл.
+// @property {string} path - Путь к файлу журнала.
+// @property {int64} maxSize - Наибольший размер файла в байтах, 0 - без ограничения.
+// @property {time.Duration} maxAge - Наибольший возраст архивного файла, 0 - без ограничения.
+// @property {*os.File} file - Открытый файл журнала.
+// @property {int64} size - Текущий размер файла.
+// @property now - Источник текущего времени.
+type RotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	maxAge  time.Duration
+	file    *os.File
+	size    int64
+	now     func() time.Time
+}
+
+// > Эта функция открывает файл журнала path, создавая его каталог, и возвращает указатель на него.
+func NewRotatingFile(path string, maxSize int64, maxAge time.Duration) (*RotatingFile, error) {
+	f := &RotatingFile{
+		path:    path,
+		maxSize: maxSize,
+		maxAge:  maxAge,
+		now:     time.Now,
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("loggin-NewRotatingFile, %s", err.Error())
+	}
+
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	f.cleanup()
+
+	return f, nil
+}
+
+// Записывает data в файл. Если запись превысит наибольший размер, файл сначала переименовывается в
+// архивный.
+func (f *RotatingFile) Write(data []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(data)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(data)
+	f.size += int64(n)
+
+	return n, err
+}
+
+// Сбрасывает данные файла на диск.
+func (f *RotatingFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.file.Sync()
+}
+
+// Закрывает файл.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.file.Close()
+}
+
+// Открывает файл журнала на дозапись и запоминает его размер.
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("loggin-RotatingFile-open, %s", err.Error())
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("loggin-RotatingFile-open, %s", err.Error())
+	}
+
+	f.file = file
+	f.size = info.Size()
+
+	return nil
+}
+
+// Переименовывает файл в архивный, открывает новый и удаляет устаревшие архивные файлы.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("loggin-RotatingFile-rotate, %s", err.Error())
+	}
+
+	if err := os.Rename(f.path, f.backupName(f.now())); err != nil {
+		return fmt.Errorf("loggin-RotatingFile-rotate, %s", err.Error())
+	}
+
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	f.cleanup()
+
+	return nil
+}
+
+// Возвращает имя архивного файла для времени t.
+func (f *RotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(f.path)
+
+	return strings.TrimSuffix(f.path, ext) + "-" + t.Format(backupTimeLayout) + ext
+}
+
+// Удаляет архивные файлы, которые изменялись раньше наибольшего возраста. Файлы, имя которых не
+// содержит время архивации, не удаляются.
+func (f *RotatingFile) cleanup() {
+	if f.maxAge <= 0 {
+		return
+	}
+
+	ext := filepath.Ext(f.path)
+	backups, err := filepath.Glob(strings.TrimSuffix(f.path, ext) + "-*" + ext)
+	if err != nil {
+		return
+	}
+
+	prefix := strings.TrimSuffix(f.path, ext) + "-"
+	cutoff := f.now().Add(-f.maxAge)
+	for _, backup := range backups {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(backup, prefix), ext)
+		if _, err := time.Parse(backupTimeLayout, stamp); err != nil {
+			continue
+		}
+
+		info, err := os.Stat(backup)
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+
+		os.Remove(backup)
+	}
+}
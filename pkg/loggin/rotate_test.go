@@ -0,0 +1,52 @@
+package loggin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет, что файл переименовывается в архивный при превышении размера, а архивные файлы старше
+// предельного возраста удаляются.
+func TestRotatingFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs", "app.log")
+	now := time.Date(2022, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	f, err := NewRotatingFile(path, 10, 24*time.Hour)
+	assert.NoError(t, err)
+	defer f.Close()
+	f.now = func() time.Time { return now }
+
+	stale := f.backupName(now.Add(-48 * time.Hour))
+	assert.NoError(t, os.WriteFile(stale, []byte("old\n"), 0o644))
+	assert.NoError(t, os.Chtimes(stale, now.Add(-48*time.Hour), now.Add(-48*time.Hour)))
+
+	other := filepath.Join(dir, "logs", "app-access.log")
+	assert.NoError(t, os.WriteFile(other, []byte("other\n"), 0o644))
+	assert.NoError(t, os.Chtimes(other, now.Add(-48*time.Hour), now.Add(-48*time.Hour)))
+
+	for _, line := range []string{"first\n", "second\n"} {
+		_, err := f.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+
+	backup, err := os.ReadFile(f.backupName(now))
+	assert.NoError(t, err)
+	assert.Equal(t, "first\n", string(backup))
+
+	current, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "second\n", string(current))
+
+	_, err = os.Stat(stale)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = os.Stat(other)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,35 @@
+package metrics
+
+import "database/sql"
+
+// Эта функция регистрирует в registry метрики пула соединений db из sql.DBStats. Значения читаются при
+// каждом запросе метрик.
+func NewDBStats(registry *Registry, db *sql.DB) {
+	registry.NewGaugeFunc("db_connections_max_open", "Maximum number of open connections to the database.", func() float64 {
+		return float64(db.Stats().MaxOpenConnections)
+	})
+	registry.NewGaugeFunc("db_connections_open", "Number of established connections, both in use and idle.", func() float64 {
+		return float64(db.Stats().OpenConnections)
+	})
+	registry.NewGaugeFunc("db_connections_in_use", "Number of connections currently in use.", func() float64 {
+		return float64(db.Stats().InUse)
+	})
+	registry.NewGaugeFunc("db_connections_idle", "Number of idle connections.", func() float64 {
+		return float64(db.Stats().Idle)
+	})
+	registry.NewCounterFunc("db_wait_count_total", "Total number of connections waited for.", func() float64 {
+		return float64(db.Stats().WaitCount)
+	})
+	registry.NewCounterFunc("db_wait_duration_seconds_total", "Total time blocked waiting for a new connection.", func() float64 {
+		return db.Stats().WaitDuration.Seconds()
+	})
+	registry.NewCounterFunc("db_max_idle_closed_total", "Total number of connections closed due to SetMaxIdleConns.", func() float64 {
+		return float64(db.Stats().MaxIdleClosed)
+	})
+	registry.NewCounterFunc("db_max_idle_time_closed_total", "Total number of connections closed due to SetConnMaxIdleTime.", func() float64 {
+		return float64(db.Stats().MaxIdleTimeClosed)
+	})
+	registry.NewCounterFunc("db_max_lifetime_closed_total", "Total number of connections closed due to SetConnMaxLifetime.", func() float64 {
+		return float64(db.Stats().MaxLifetimeClosed)
+	})
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/route"
+)
+
+// Значение метки route для запроса, которому не нашелся маршрут.
+const RouteUnmatched = "unmatched"
+
+// HTTPMetrics — это метрики HTTP-сервера: количество и длительность запросов по маршрутам и количество
+// запросов в обработке.
+// @property {*Counter} requests - Количество запросов по маршруту, методу и коду ответа.
+// @property {*Histogram} duration - Длительность запросов по маршруту и методу.
+// @property {*Gauge} inFlight - Количество запросов в обработке.
+type HTTPMetrics struct {
+	requests *Counter
+	duration *Histogram
+	inFlight *Gauge
+}
+
+// > Эта функция регистрирует метрики HTTP-сервера в registry и возвращает указатель на них.
+func NewHTTPMetrics(registry *Registry) *HTTPMetrics {
+	return &HTTPMetrics{
+		requests: registry.NewCounter("http_requests_total", "Total number of HTTP requests.", "route", "method", "code"),
+		duration: registry.NewHistogram("http_request_duration_seconds", "HTTP request latency in seconds.", DefBuckets, "route", "method"),
+		inFlight: registry.NewGauge("http_requests_in_flight", "Number of HTTP requests being served."),
+	}
+}
+
+// Middleware — это middleware HTTP-сервера, которое считает запрос и время его обработки. Запрос
+// попадает в ряд маршрута по его имени, то есть по имени константы маршрута. Маршрут сохраняет в записи
+// запроса route.Match, подключенное к маршрутизатору.
+func (m *HTTPMetrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w, r, recorder := route.Wrap(w, r)
+		start := time.Now()
+
+		m.inFlight.Inc()
+
+		// Метрики записываются и тогда, когда обработчик прерван паникой, например при разрыве
+		// соединения.
+		defer func() {
+			name := recorder.Name()
+			if name == "" {
+				name = RouteUnmatched
+			}
+
+			m.inFlight.Dec()
+			m.requests.Inc(name, r.Method, strconv.Itoa(recorder.Status()))
+			m.duration.Observe(time.Since(start).Seconds(), name, r.Method)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
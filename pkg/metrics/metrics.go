@@ -0,0 +1,394 @@
+// Package metrics собирает счетчики, значения и гистограммы приложения и отдает их в текстовом формате
+// Prometheus по GET /metrics.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+// Это константа, определяющая маршрут.
+const Metrics = "/metrics"
+
+// Тип содержимого текстового формата Prometheus.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Границы гистограммы по умолчанию в секундах, как в клиентской библиотеке Prometheus.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Типы метрик в строке «# TYPE».
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// family — это метрика с одним именем, которая записывает свои значения в текстовом формате.
+type family interface {
+	write(w *bufio.Writer)
+}
+
+// Registry — это потокобезопасный набор метрик в порядке регистрации.
+// @property mu - Мьютекс, который защищает список метрик.
+// @property families - Метрики в порядке регистрации.
+// @property names - Имена зарегистрированных метрик.
+type Registry struct {
+	mu       sync.Mutex
+	families []family
+	names    map[string]bool
+}
+
+// > Эта функция создает пустой набор метрик и возвращает указатель на него.
+func NewRegistry() *Registry {
+	return &Registry{
+		names: make(map[string]bool),
+	}
+}
+
+// Добавляет метрику. Повторное имя - ошибка программы, поэтому вызывает панику.
+func (r *Registry) add(name string, value family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[name] {
+		panic(fmt.Sprintf("metrics: duplicate metric %q", name))
+	}
+
+	r.names[name] = true
+	r.families = append(r.families, value)
+}
+
+// Регистрирует счетчик name с метками labels и возвращает его.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{vec: newVec(name, help, typeCounter, labels)}
+	r.add(name, c)
+
+	return c
+}
+
+// Регистрирует значение name с метками labels и возвращает его.
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{vec: newVec(name, help, typeGauge, labels)}
+	r.add(name, g)
+
+	return g
+}
+
+// Регистрирует гистограмму name с границами buckets по возрастанию и метками labels и возвращает ее.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{
+		vec:     newVec(name, help, typeHistogram, labels),
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+	r.add(name, h)
+
+	return h
+}
+
+// Регистрирует значение name, которое вычисляется функцией fn при каждом чтении метрик.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.add(name, &funcFamily{name: name, help: help, kind: typeGauge, fn: fn})
+}
+
+// Регистрирует счетчик name, который вычисляется функцией fn при каждом чтении метрик.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.add(name, &funcFamily{name: name, help: help, kind: typeCounter, fn: fn})
+}
+
+// Записывает все метрики в текстовом формате Prometheus.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	families := append([]family(nil), r.families...)
+	r.mu.Unlock()
+
+	buffer := bufio.NewWriter(w)
+	for _, value := range families {
+		value.write(buffer)
+	}
+
+	return buffer.Flush()
+}
+
+// Эта функция регистрирует обработчик метрик.
+func (r *Registry) Register(router *mux.Router) *mux.Router {
+	router.HandleFunc(Metrics, r.GetMetrics).Methods(http.MethodGet).Name("Metrics")
+	return router
+}
+
+// Обработчик, который будет вызываться при запросе маршрута
+// // `/metrics` методом `GET`.
+func (r *Registry) GetMetrics(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(http.StatusOK)
+	r.Write(w)
+}
+
+// vec — это общая часть метрик с метками: имя, описание и значения меток рядов.
+// @property mu - Мьютекс, который защищает значения рядов.
+// @property {string} name - Имя метрики.
+// @property {string} help - Описание метрики.
+// @property {string} kind - Тип метрики.
+// @property labels - Имена меток.
+// @property values - Значения рядов по ключу из значений меток.
+// @property keys - Значения меток рядов по ключу.
+type vec struct {
+	mu     sync.Mutex
+	name   string
+	help   string
+	kind   string
+	labels []string
+	values map[string]float64
+	keys   map[string][]string
+}
+
+// Возвращает метрику без рядов.
+func newVec(name, help, kind string, labels []string) vec {
+	return vec{
+		name:   name,
+		help:   help,
+		kind:   kind,
+		labels: labels,
+		values: make(map[string]float64),
+		keys:   make(map[string][]string),
+	}
+}
+
+// Возвращает ключ ряда по значениям меток. Неверное количество значений - ошибка программы, поэтому
+// вызывает панику. Вызывается под мьютексом.
+func (v *vec) key(values []string) string {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+
+	key := strings.Join(values, "\xff")
+	if _, ok := v.keys[key]; !ok {
+		v.keys[key] = append([]string(nil), values...)
+	}
+
+	return key
+}
+
+// Прибавляет delta к ряду со значениями меток values.
+func (v *vec) add(delta float64, values []string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.values[v.key(values)] += delta
+}
+
+// Возвращает ключи рядов по возрастанию. Вызывается под мьютексом.
+func (v *vec) sorted() []string {
+	keys := make([]string, 0, len(v.keys))
+	for key := range v.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Записывает строки «# HELP» и «# TYPE».
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+// Записывает строку ряда.
+func writeSample(w *bufio.Writer, name string, labels, values []string, value float64) {
+	w.WriteString(name)
+
+	if len(labels) != 0 {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i != 0 {
+				w.WriteByte(',')
+			}
+
+			fmt.Fprintf(w, "%s=\"%s\"", label, escapeLabel(values[i]))
+		}
+		w.WriteByte('}')
+	}
+
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+// Записывает метрику с рядами по возрастанию значений меток.
+func (v *vec) write(w *bufio.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	writeHeader(w, v.name, v.help, v.kind)
+	for _, key := range v.sorted() {
+		writeSample(w, v.name, v.labels, v.keys[key], v.values[key])
+	}
+}
+
+// Counter — это счетчик, который только увеличивается.
+type Counter struct {
+	vec
+}
+
+// Увеличивает счетчик ряда со значениями меток values на единицу.
+func (c *Counter) Inc(values ...string) {
+	c.add(1, values)
+}
+
+// Увеличивает счетчик ряда со значениями меток values на delta. Отрицательное delta - ошибка
+// программы, поэтому вызывает панику.
+func (c *Counter) Add(delta float64, values ...string) {
+	if delta < 0 {
+		panic(fmt.Sprintf("metrics: counter %s cannot decrease", c.name))
+	}
+
+	c.add(delta, values)
+}
+
+// Gauge — это значение, которое может увеличиваться и уменьшаться.
+type Gauge struct {
+	vec
+}
+
+// Устанавливает значение ряда со значениями меток values.
+func (g *Gauge) Set(value float64, values ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.values[g.key(values)] = value
+}
+
+// Прибавляет delta к значению ряда со значениями меток values.
+func (g *Gauge) Add(delta float64, values ...string) {
+	g.add(delta, values)
+}
+
+// Увеличивает значение ряда на единицу.
+func (g *Gauge) Inc(values ...string) {
+	g.add(1, values)
+}
+
+// Уменьшает значение ряда на единицу.
+func (g *Gauge) Dec(values ...string) {
+	g.add(-1, values)
+}
+
+// Histogram — это гистограмма наблюдений с накопительными корзинами, суммой и количеством.
+// @property buckets - Верхние границы корзин по возрастанию.
+// @property series - Ряды по ключу из значений меток.
+type Histogram struct {
+	vec
+	buckets []float64
+	series  map[string]*histogramSeries
+}
+
+// histogramSeries — это ряд гистограммы.
+// @property counts - Количество наблюдений в каждой корзине, не накопительное.
+// @property {float64} sum - Сумма наблюдений.
+// @property {uint64} count - Количество наблюдений.
+type histogramSeries struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Добавляет наблюдение value в ряд со значениями меток values.
+func (h *Histogram) Observe(value float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := h.key(values)
+
+	series, ok := h.series[key]
+	if !ok {
+		series = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = series
+	}
+
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		series.counts[i]++
+	}
+
+	series.sum += value
+	series.count++
+}
+
+// Записывает гистограмму: накопительные корзины с меткой le, сумму и количество наблюдений.
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	writeHeader(w, h.name, h.help, h.kind)
+
+	labels := append(append([]string(nil), h.labels...), "le")
+	for _, key := range h.sorted() {
+		series := h.series[key]
+		values := append(append([]string(nil), h.keys[key]...), "")
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += series.counts[i]
+			values[len(values)-1] = formatFloat(bound)
+			writeSample(w, h.name+"_bucket", labels, values, float64(cumulative))
+		}
+
+		values[len(values)-1] = "+Inf"
+		writeSample(w, h.name+"_bucket", labels, values, float64(series.count))
+		writeSample(w, h.name+"_sum", h.labels, h.keys[key], series.sum)
+		writeSample(w, h.name+"_count", h.labels, h.keys[key], float64(series.count))
+	}
+}
+
+// funcFamily — это метрика без меток, значение которой вычисляется при чтении.
+type funcFamily struct {
+	name string
+	help string
+	kind string
+	fn   func() float64
+}
+
+// Записывает метрику с текущим значением функции.
+func (f *funcFamily) write(w *bufio.Writer) {
+	writeHeader(w, f.name, f.help, f.kind)
+	writeSample(w, f.name, nil, nil, f.fn())
+}
+
+// Возвращает число в записи Prometheus.
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// Экранирует описание метрики.
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// Экранирует значение метки.
+var labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// Возвращает описание метрики с экранированными «\» и переводами строк.
+func escapeHelp(value string) string {
+	return helpReplacer.Replace(value)
+}
+
+// Возвращает значение метки с экранированными «\», «"» и переводами строк.
+func escapeLabel(value string) string {
+	return labelReplacer.Replace(value)
+}
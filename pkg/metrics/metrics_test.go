@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/onlycodergod/payment-api-emulator/pkg/http/route"
+	"github.com/stretchr/testify/assert"
+)
+
+// Он проверяет текстовый формат счетчиков, значений, гистограмм и вычисляемых метрик.
+func TestRegistryWrite(t *testing.T) {
+	t.Parallel()
+
+	registry := NewRegistry()
+
+	counter := registry.NewCounter("requests_total", "Total requests.", "route", "code")
+	counter.Inc("B", "200")
+	counter.Add(2, "A", "500")
+	counter.Inc("A", "500")
+	counter.Inc(`quote"\`, "200")
+
+	gauge := registry.NewGauge("in_flight", "Requests in flight.\nSecond line.")
+	gauge.Inc()
+	gauge.Inc()
+	gauge.Dec()
+
+	histogram := registry.NewHistogram("duration_seconds", "Latency.", []float64{0.1, 1}, "route")
+	histogram.Observe(0.05, "A")
+	histogram.Observe(0.1, "A")
+	histogram.Observe(3, "A")
+
+	registry.NewGaugeFunc("temperature", "Computed value.", func() float64 { return 1.5 })
+
+	var buffer bytes.Buffer
+	assert.NoError(t, registry.Write(&buffer))
+
+	expect := `# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{route="A",code="500"} 3
+requests_total{route="B",code="200"} 1
+requests_total{route="quote\"\\",code="200"} 1
+# HELP in_flight Requests in flight.\nSecond line.
+# TYPE in_flight gauge
+in_flight 1
+# HELP duration_seconds Latency.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{route="A",le="0.1"} 2
+duration_seconds_bucket{route="A",le="1"} 2
+duration_seconds_bucket{route="A",le="+Inf"} 3
+duration_seconds_sum{route="A"} 3.15
+duration_seconds_count{route="A"} 3
+# HELP temperature Computed value.
+# TYPE temperature gauge
+temperature 1.5
+`
+	assert.Equal(t, expect, buffer.String())
+
+	assert.Panics(t, func() { registry.NewGauge("in_flight", "Duplicate.") })
+	assert.Panics(t, func() { counter.Inc("A") })
+	assert.Panics(t, func() { counter.Add(-1, "A", "200") })
+}
+
+// Он проверяет, что запросы считаются по имени маршрута, шаблону пути маршрута без имени и отдельно
+// без маршрута, а /metrics отдает их в текстовом формате.
+func TestHTTPMetrics(t *testing.T) {
+	t.Parallel()
+
+	registry := NewRegistry()
+	router := mux.NewRouter()
+	router.Use(route.Match)
+
+	router.HandleFunc("/payments/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}).Methods(http.MethodGet).Name("GetPaymentByID")
+	router.HandleFunc("/admin/faults", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}).Methods(http.MethodGet)
+	registry.Register(router)
+
+	handler := NewHTTPMetrics(registry).Middleware(router)
+
+	for _, target := range []string{"/payments/1", "/payments/2", "/admin/faults", "/unknown"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, Metrics, nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+
+	body := w.Body.String()
+	for _, line := range []string{
+		`http_requests_total{route="GetPaymentByID",method="GET",code="404"} 2`,
+		`http_requests_total{route="/admin/faults",method="GET",code="200"} 1`,
+		`http_requests_total{route="unmatched",method="GET",code="404"} 1`,
+		`http_request_duration_seconds_count{route="GetPaymentByID",method="GET"} 2`,
+		`http_requests_in_flight 1`,
+	} {
+		assert.True(t, strings.Contains(body, line+"\n"), line)
+	}
+}
+
+// Он проверяет, что метрики пула соединений читаются из sql.DBStats.
+func TestNewDBStats(t *testing.T) {
+	t.Parallel()
+
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	db.SetMaxOpenConns(7)
+
+	registry := NewRegistry()
+	NewDBStats(registry, db)
+
+	var buffer bytes.Buffer
+	assert.NoError(t, registry.Write(&buffer))
+
+	assert.Contains(t, buffer.String(), "# TYPE db_connections_max_open gauge\ndb_connections_max_open 7\n")
+	assert.Contains(t, buffer.String(), "# TYPE db_wait_count_total counter\ndb_wait_count_total 0\n")
+}
@@ -0,0 +1,297 @@
+// Package validator проверяет структуры по правилам, объявленным в теге validate, и возвращает ошибки
+// по полям.
+//
+//	type Input struct {
+//		UserID int64  `json:"user_id" validate:"gt=0"`
+//		Email  string `json:"email" validate:"required,max=20,email"`
+//		Sort   string `json:"sort" validate:"omitempty,oneof=asc desc"`
+//	}
+//
+// Имя поля в ошибке берется из тега json. Правило omitempty пропускает остальные правила для нулевого
+// значения, правило required требует ненулевое значение.
+package validator
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Тег, в котором объявляются правила поля.
+const Tag = "validate"
+
+// FieldError — это ошибка проверки одного поля.
+// @property {string} Field - Имя поля из тега json.
+// @property {string} Message - Описание ошибки, например «must be > 0».
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Возвращает ошибку в виде «поле: описание».
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Errors — это ошибки проверки всех полей структуры в порядке объявления полей.
+type Errors []FieldError
+
+// Возвращает ошибки через «; », например «amount: must be > 0; status: unknown value».
+func (e Errors) Error() string {
+	list := make([]string, 0, len(e))
+	for _, value := range e {
+		list = append(list, value.Error())
+	}
+
+	return strings.Join(list, "; ")
+}
+
+// Rule — это правило проверки значения поля с параметром из тега, например «0» для «gt=0». Возвращает
+// описание ошибки или пустую строку, если значение корректно.
+type Rule func(value reflect.Value, param string) string
+
+// Comparable — это значение, которое сравнивается с параметром правил gt, gte, lt и lte, например точное
+// десятичное число. CompareString возвращает -1, 0 или 1 либо ошибку, если параметр некорректен.
+type Comparable interface {
+	CompareString(param string) (int, error)
+}
+
+// Validator — это набор правил по имени.
+// @property rules - Правила по имени из тега validate.
+type Validator struct {
+	rules map[string]Rule
+}
+
+// > Эта функция создает проверку со встроенными правилами required, gt, gte, lt, lte, min, max, email и
+// oneof и возвращает указатель на нее.
+func New() *Validator {
+	v := &Validator{
+		rules: make(map[string]Rule),
+	}
+
+	v.Register("required", required)
+	v.Register("gt", compare(">", func(cmp int) bool { return cmp > 0 }))
+	v.Register("gte", compare(">=", func(cmp int) bool { return cmp >= 0 }))
+	v.Register("lt", compare("<", func(cmp int) bool { return cmp < 0 }))
+	v.Register("lte", compare("<=", func(cmp int) bool { return cmp <= 0 }))
+	v.Register("min", length("at least", func(n, limit int) bool { return n >= limit }))
+	v.Register("max", length("at most", func(n, limit int) bool { return n <= limit }))
+	v.Register("email", email)
+	v.Register("oneof", oneOf)
+
+	return v
+}
+
+// Регистрирует правило name. Правило с тем же именем заменяется.
+func (v *Validator) Register(name string, rule Rule) *Validator {
+	v.rules[name] = rule
+	return v
+}
+
+// Проверяет экспортируемые поля структуры или указателя на нее по тегу validate. Возвращает Errors с
+// первой ошибкой каждого поля или nil. Неизвестное правило в теге - ошибка программы, поэтому
+// вызывает панику.
+func (v *Validator) Struct(input interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(input))
+	if value.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validator: %T is not a struct", input))
+	}
+
+	var errs Errors
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+
+		tag, ok := field.Tag.Lookup(Tag)
+		if !ok || field.PkgPath != "" {
+			continue
+		}
+
+		if message := v.field(value.Field(i), tag); message != "" {
+			errs = append(errs, FieldError{
+				Field:   fieldName(field),
+				Message: message,
+			})
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// Проверяет одно значение по правилам tag, например параметр запроса. Возвращает Errors с ошибкой
+// поля name или nil.
+func (v *Validator) Var(name string, value interface{}, tag string) error {
+	if message := v.field(reflect.ValueOf(value), tag); message != "" {
+		return Errors{{Field: name, Message: message}}
+	}
+
+	return nil
+}
+
+// Проверяет значение по правилам тега и возвращает описание первой ошибки.
+func (v *Validator) field(value reflect.Value, tag string) string {
+	for _, item := range strings.Split(tag, ",") {
+		name, param := item, ""
+		if i := strings.IndexByte(item, '='); i >= 0 {
+			name, param = item[:i], item[i+1:]
+		}
+
+		if name == "omitempty" {
+			if value.IsZero() {
+				return ""
+			}
+
+			continue
+		}
+
+		rule, ok := v.rules[name]
+		if !ok {
+			panic(fmt.Sprintf("validator: unknown rule %q", name))
+		}
+
+		if message := rule(value, param); message != "" {
+			return message
+		}
+	}
+
+	return ""
+}
+
+// Возвращает имя поля из тега json или имя поля структуры, если тега нет.
+func fieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return field.Name
+	}
+
+	return name
+}
+
+// Правило required: значение не нулевое.
+func required(value reflect.Value, param string) string {
+	if value.IsZero() {
+		return "is required"
+	}
+
+	return ""
+}
+
+// Возвращает правило сравнения значения с параметром. Поддерживаются целые и дробные числа и
+// Comparable.
+func compare(operator string, ok func(cmp int) bool) Rule {
+	return func(value reflect.Value, param string) string {
+		cmp, err := compareValue(value, param)
+		if err != nil {
+			panic(fmt.Sprintf("validator: %s", err.Error()))
+		}
+
+		if !ok(cmp) {
+			return "must be " + operator + " " + param
+		}
+
+		return ""
+	}
+}
+
+// Сравнивает значение с параметром и возвращает -1, 0 или 1.
+func compareValue(value reflect.Value, param string) (int, error) {
+	if value.CanInterface() {
+		if comparable, ok := value.Interface().(Comparable); ok {
+			return comparable.CompareString(param)
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		limit, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+
+		return sign(float64(value.Int()) - float64(limit)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		limit, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+
+		return sign(float64(value.Uint()) - float64(limit)), nil
+	case reflect.Float32, reflect.Float64:
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return 0, err
+		}
+
+		return sign(value.Float() - limit), nil
+	default:
+		return 0, fmt.Errorf("cannot compare %s", value.Type())
+	}
+}
+
+// Возвращает знак числа.
+func sign(value float64) int {
+	switch {
+	case value > 0:
+		return 1
+	case value < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// Возвращает правило ограничения длины строки, среза или карты либо значения целого числа.
+func length(word string, ok func(n, limit int) bool) Rule {
+	return func(value reflect.Value, param string) string {
+		limit, err := strconv.Atoi(param)
+		if err != nil {
+			panic(fmt.Sprintf("validator: %s", err.Error()))
+		}
+
+		switch value.Kind() {
+		case reflect.String:
+			if !ok(len([]rune(value.String())), limit) {
+				return fmt.Sprintf("must be %s %d characters", word, limit)
+			}
+		case reflect.Slice, reflect.Map, reflect.Array:
+			if !ok(value.Len(), limit) {
+				return fmt.Sprintf("must contain %s %d items", word, limit)
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if !ok(int(value.Int()), limit) {
+				return fmt.Sprintf("must be %s %d", word, limit)
+			}
+		default:
+			panic(fmt.Sprintf("validator: cannot measure %s", value.Type()))
+		}
+
+		return ""
+	}
+}
+
+// Правило email: строка является адресом электронной почты без имени.
+func email(value reflect.Value, param string) string {
+	address, err := mail.ParseAddress(value.String())
+	if err != nil || address.Address != value.String() {
+		return "must be a valid email"
+	}
+
+	return ""
+}
+
+// Правило oneof: строка совпадает с одним из значений параметра через пробел.
+func oneOf(value reflect.Value, param string) string {
+	for _, item := range strings.Fields(param) {
+		if value.String() == item {
+			return ""
+		}
+	}
+
+	return "must be one of: " + strings.Join(strings.Fields(param), ", ")
+}
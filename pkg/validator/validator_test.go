@@ -0,0 +1,100 @@
+package validator
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// amount — это сумма, которая сравнивается с параметром правила через Comparable.
+type amount int64
+
+// Сравнивает сумму с целым параметром.
+func (a amount) CompareString(param string) (int, error) {
+	value, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return sign(float64(a) - float64(value)), nil
+}
+
+// input — это проверяемая структура со всеми встроенными правилами.
+type input struct {
+	UserID  int64   `json:"user_id" validate:"gt=0"`
+	Amount  amount  `json:"amount" validate:"gte=0,lt=100"`
+	Rate    float64 `json:"rate,omitempty" validate:"lte=1.5"`
+	Email   string  `json:"email" validate:"required,max=20,email"`
+	Sort    string  `json:"sort,omitempty" validate:"omitempty,oneof=asc desc"`
+	Tags    []string
+	Limit   int    `validate:"min=1"`
+	Color   string `json:"color" validate:"omitempty,color"`
+	ignored string `validate:"required"`
+}
+
+// Он проверяет встроенные и зарегистрированные правила и имена полей в ошибках.
+func TestStruct(t *testing.T) {
+	t.Parallel()
+
+	v := New().Register("color", func(value reflect.Value, param string) string {
+		if value.String() != "red" {
+			return "unknown value"
+		}
+
+		return ""
+	})
+
+	valid := input{UserID: 1, Amount: 10, Rate: 1.5, Email: "user@mail.ru", Limit: 1}
+
+	tests := []struct {
+		name  string
+		apply func(in *input)
+		err   string
+	}{
+		{name: "Valid", apply: func(in *input) {}},
+		{name: "Optional values", apply: func(in *input) { in.Sort = "desc"; in.Color = "red" }},
+		{name: "Greater than", apply: func(in *input) { in.UserID = 0 }, err: "user_id: must be > 0"},
+		{name: "Comparable", apply: func(in *input) { in.Amount = -1 }, err: "amount: must be >= 0"},
+		{name: "Second rule", apply: func(in *input) { in.Amount = 100 }, err: "amount: must be < 100"},
+		{name: "Float", apply: func(in *input) { in.Rate = 1.6 }, err: "rate: must be <= 1.5"},
+		{name: "Required", apply: func(in *input) { in.Email = "" }, err: "email: is required"},
+		{name: "Length", apply: func(in *input) { in.Email = "long.user.name@mail.ru" }, err: "email: must be at most 20 characters"},
+		{name: "Email", apply: func(in *input) { in.Email = "User <u@mail.ru>" }, err: "email: must be a valid email"},
+		{name: "One of", apply: func(in *input) { in.Sort = "amount" }, err: "sort: must be one of: asc, desc"},
+		{name: "Field without json tag", apply: func(in *input) { in.Limit = 0 }, err: "Limit: must be at least 1"},
+		{name: "Registered rule", apply: func(in *input) { in.Color = "blue" }, err: "color: unknown value"},
+		{
+			name:  "All fields",
+			apply: func(in *input) { in.UserID = -1; in.Sort = "amount" },
+			err:   "user_id: must be > 0; sort: must be one of: asc, desc",
+		},
+	}
+
+	for _, tt := range tests {
+		in := valid
+		tt.apply(&in)
+
+		err := v.Struct(&in)
+		if tt.err == "" {
+			assert.NoError(t, err, tt.name)
+			continue
+		}
+
+		assert.EqualError(t, err, tt.err, tt.name)
+		assert.IsType(t, Errors{}, err, tt.name)
+	}
+}
+
+// Он проверяет одно значение и панику на неизвестном правиле.
+func TestVar(t *testing.T) {
+	t.Parallel()
+
+	v := New()
+
+	assert.NoError(t, v.Var("email", "user@mail.ru", "required,email"))
+	assert.Equal(t, Errors{{Field: "email", Message: "is required"}}, v.Var("email", "", "required,email"))
+	assert.Panics(t, func() { v.Var("email", "user@mail.ru", "unknown") })
+	assert.Panics(t, func() { v.Struct("not a struct") })
+}